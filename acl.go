@@ -0,0 +1,83 @@
+package slackbot
+
+// CommandACL restricts the users and the channels running the Command. Empty allows all.
+type CommandACL struct {
+	Users    []string `json:"users"`
+	Channels []string `json:"channels"`
+}
+
+var (
+	// enabledCommands only if not nil.
+	enabledCommands  map[string]bool
+	disabledCommands = map[string]bool{}
+	commandACLs      = map[string]CommandACL{}
+
+	// allowedChannels only if not nil.
+	allowedChannels map[string]bool
+)
+
+// EnableCommands only of the names. All the commands are enabled if no names.
+func EnableCommands(names ...string) {
+	enabledCommands = stringSet(names)
+}
+
+// DisableCommands of the names. Disabled commands are neither run nor listed by help.
+func DisableCommands(names ...string) {
+	disabledCommands = stringSet(names)
+	if disabledCommands == nil {
+		disabledCommands = map[string]bool{}
+	}
+}
+
+// SetCommandACL of the command name. The other users and channels are told that the command is not allowed.
+func SetCommandACL(name string, acl CommandACL) {
+	commandACLs[name] = acl
+}
+
+// SetAllowedChannels where the bot handles events. Events in the other channels are ignored.
+// All the channels are allowed if no channels.
+func SetAllowedChannels(channels ...string) {
+	allowedChannels = stringSet(channels)
+}
+
+// stringSet of the values. Returns nil if no values.
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func commandEnabled(name string) bool {
+	return !disabledCommands[name] && (enabledCommands == nil || enabledCommands[name])
+}
+
+// commandAllowed for the user in the channel of the Event.
+func commandAllowed(c *Command, e Event) bool {
+	acl, ok := commandACLs[c.Name]
+	if !ok {
+		return true
+	}
+	return (len(acl.Users) == 0 || containsString(acl.Users, e.User())) &&
+		(len(acl.Channels) == 0 || containsString(acl.Channels, e.Channel()))
+}
+
+func channelAllowed(channel string) bool {
+	return allowedChannels == nil || allowedChannels[channel]
+}
+
+// taskChannel where the Task occurred.
+func taskChannel(typeName string, e Event, p Payload) string {
+	switch typeName {
+	case TaskReactionAdded, TaskReactionRemoved:
+		return Payload(e).Object("item").String("channel")
+	case TaskInteraction:
+		return p.Object("channel").String("id")
+	default:
+		return e.Channel()
+	}
+}
@@ -0,0 +1,143 @@
+package slackbot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ToolsClearACL() {
+	EnableCommands()
+	DisableCommands()
+	commandACLs = map[string]CommandACL{}
+	SetAllowedChannels()
+}
+
+func TestEnableCommands(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	called := map[string]bool{}
+	clear := func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+		fake.Reset()
+		Setup("bot", "", "")
+		called = map[string]bool{}
+		for _, name := range []string{"deploy", "status"} {
+			name := name
+			AddCommand(&Command{Name: name, Execute: func(e Event, opt interface{}) {
+				called[name] = true
+			}})
+		}
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+	})
+
+	testRun(t, "enable test", func(t *testing.T) {
+		EnableCommands("help", "status")
+
+		assert.False(t, executeCommand(Event{"channel": "C1"}, []string{"deploy"}))
+		assert.True(t, executeCommand(Event{"channel": "C1"}, []string{"status"}))
+		assert.True(t, called["status"])
+
+		help := helpMessages(false, defaultLocale)[0]
+		assert.Contains(t, help, "status")
+		assert.NotContains(t, help, "deploy")
+		assert.NotContains(t, help, "ping")
+	})
+
+	testRun(t, "disable test", func(t *testing.T) {
+		DisableCommands("deploy")
+
+		assert.False(t, executeCommand(Event{"channel": "C1"}, []string{"deploy"}))
+		assert.True(t, executeCommand(Event{"channel": "C1"}, []string{"status"}))
+		assert.NotContains(t, helpMessages(false, defaultLocale)[0], "deploy")
+	})
+}
+
+func TestSetCommandACL(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	called := false
+	clear := func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+		fake.Reset()
+		Setup("bot", "", "")
+		called = false
+		AddCommand(&Command{Name: "deploy", Execute: func(e Event, opt interface{}) {
+			called = true
+		}})
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetCommandACL("deploy", CommandACL{Users: []string{"U1"}, Channels: []string{"C1"}})
+
+		assert.True(t, executeCommand(Event{"channel": "C1", "user": "U1"}, []string{"deploy"}))
+		assert.True(t, called)
+		assert.Empty(t, fake.CallsFor("chat.postEphemeral"))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetCommandACL("deploy", CommandACL{Users: []string{"U1"}, Channels: []string{"C1"}})
+
+		assert.True(t, executeCommand(Event{"channel": "C1", "user": "U2"}, []string{"deploy"}))
+		assert.True(t, executeCommand(Event{"channel": "C2", "user": "U1"}, []string{"deploy"}))
+		assert.False(t, called)
+
+		calls := fake.CallsFor("chat.postEphemeral")
+		assert.Len(t, calls, 2)
+		assert.Equal(t, "You are not allowed to run `deploy` here.", calls[0].Param("text"))
+	})
+}
+
+func TestSetAllowedChannels(t *testing.T) {
+	var texts []string
+	clear := func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+		Setup("bot", "", "")
+		texts = []string{}
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			texts = append(texts, e.Channel())
+		}})
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetAllowedChannels("C1")
+
+		assert.True(t, runTask(TaskAppMention, Event{"channel": "C1", "text": "<@bot> test"}, nil))
+		assert.True(t, runTask(TaskAppMention, Event{"channel": "C2", "text": "<@bot> test"}, nil))
+		assert.True(t, runTask(TaskSlashCommand, Event{"channel": "C2", "text": "test"}, nil))
+		assert.Equal(t, []string{"C1"}, texts)
+	})
+
+	testRun(t, "reaction test", func(t *testing.T) {
+		SetAllowedChannels("C1")
+		handler := &TestReactionHandler{}
+		SetReactionHandler(handler)
+		defer SetReactionHandler(nil)
+
+		runTask(TaskReactionAdded, Event{"reaction": "eyes", "item": map[string]interface{}{"channel": "C2"}}, nil)
+		assert.Empty(t, handler.Reaction)
+		runTask(TaskReactionAdded, Event{"reaction": "eyes", "item": map[string]interface{}{"channel": "C1"}}, nil)
+		assert.Equal(t, "eyes", handler.Reaction)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetAllowedChannels("C1")
+		assert.False(t, runTask("unknown", Event{"channel": "C1"}, nil))
+	})
+}
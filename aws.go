@@ -2,10 +2,20 @@ package slackbot
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/apex/gateway"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AWSLambdaHandler is handler when a slack event is received via aws lambda.
@@ -15,13 +25,266 @@ func AWSLambdaHandler(ctx context.Context, e events.APIGatewayProxyRequest) (eve
 		return events.APIGatewayProxyResponse{}, err
 	}
 
+	w := serveLambda(ctx, r)
+	return w.End(), nil
+}
+
+// AWSLambdaHTTPAPIHandler is handler when a slack event is received via API Gateway HTTP API (payload format 2.0).
+func AWSLambdaHTTPAPIHandler(ctx context.Context, e events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	r, err := newLambdaRequest(ctx, e.RequestContext.HTTP.Method, e.RawPath, e.RawQueryString, lambdaHeader(e.Headers, nil, e.Cookies), e.Body, e.IsBase64Encoded)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if r.Header.Get(requestIDHeader) == "" {
+		r.Header.Set(requestIDHeader, e.RequestContext.RequestID)
+	}
+
+	w := serveLambda(ctx, r)
+	headers, cookies := lambdaResponseHeader(w.Header())
+	res := w.End()
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:      res.StatusCode,
+		Headers:         headers,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+		Cookies:         cookies,
+	}, nil
+}
+
+// AWSLambdaFunctionURLHandler is handler when a slack event is received via Lambda Function URLs.
+func AWSLambdaFunctionURLHandler(ctx context.Context, e events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	r, err := newLambdaRequest(ctx, e.RequestContext.HTTP.Method, e.RawPath, e.RawQueryString, lambdaHeader(e.Headers, nil, e.Cookies), e.Body, e.IsBase64Encoded)
+	if err != nil {
+		return events.LambdaFunctionURLResponse{}, err
+	}
+	if r.Header.Get(requestIDHeader) == "" {
+		r.Header.Set(requestIDHeader, e.RequestContext.RequestID)
+	}
+
+	w := serveLambda(ctx, r)
+	headers, cookies := lambdaResponseHeader(w.Header())
+	res := w.End()
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      res.StatusCode,
+		Headers:         headers,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+		Cookies:         cookies,
+	}, nil
+}
+
+// AWSLambdaALBHandler is handler when a slack event is received via Application Load Balancer.
+// Multi-value headers are returned if they are enabled for the target group.
+func AWSLambdaALBHandler(ctx context.Context, e events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	// the query parameters are passed by ALB as they are sent, without decoding
+	query := []string{}
+	if e.MultiValueQueryStringParameters != nil {
+		for k, values := range e.MultiValueQueryStringParameters {
+			for _, v := range values {
+				query = append(query, k+"="+v)
+			}
+		}
+	} else {
+		for k, v := range e.QueryStringParameters {
+			query = append(query, k+"="+v)
+		}
+	}
+	sort.Strings(query)
+
+	r, err := newLambdaRequest(ctx, e.HTTPMethod, e.Path, strings.Join(query, "&"), lambdaHeader(e.Headers, e.MultiValueHeaders, nil), e.Body, e.IsBase64Encoded)
+	if err != nil {
+		return events.ALBTargetGroupResponse{}, err
+	}
+
+	w := serveLambda(ctx, r)
+	header := w.Header()
+	res := w.End()
+	out := events.ALBTargetGroupResponse{
+		StatusCode:        res.StatusCode,
+		StatusDescription: strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode),
+		Body:              res.Body,
+		IsBase64Encoded:   res.IsBase64Encoded,
+	}
+	if e.MultiValueHeaders != nil {
+		out.MultiValueHeaders = header
+	} else {
+		out.Headers = res.Headers
+	}
+	return out, nil
+}
+
+// newLambdaRequest from the HTTP request of a Lambda event.
+// The base64 encoded body is decoded so that the signature is verified with the raw bytes.
+func newLambdaRequest(ctx context.Context, method, path, rawQuery string, header http.Header, body string, isBase64Encoded bool) (*http.Request, error) {
+	if isBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+
+	target := path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	r, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header
+	r.Host = r.Header.Get("Host")
+	return r.WithContext(ctx), nil
+}
+
+// lambdaHeader of the request from the headers and the cookies of a Lambda event.
+func lambdaHeader(headers map[string]string, multiValueHeaders map[string][]string, cookies []string) http.Header {
+	header := http.Header{}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	for k, values := range multiValueHeaders {
+		header.Del(k)
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	if len(cookies) > 0 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	return header
+}
+
+// lambdaResponseHeader of the payload format 2.0, where Set-Cookie is separated as the cookies.
+func lambdaResponseHeader(header http.Header) (map[string]string, []string) {
+	var cookies []string
+	headers := map[string]string{}
+	for k, values := range header {
+		if k == "Set-Cookie" {
+			cookies = append(cookies, values...)
+			continue
+		}
+		headers[k] = strings.Join(values, ",")
+	}
+	return headers, cookies
+}
+
+// serveLambda the request converted from a Lambda event.
+func serveLambda(ctx context.Context, r *http.Request) *gateway.ResponseWriter {
+	if lc, ok := lambdacontext.FromContext(ctx); ok && r.Header.Get(requestIDHeader) == "" {
+		r.Header.Set(requestIDHeader, lc.AwsRequestID)
+	}
+
 	w := gateway.NewResponse()
 	OnCall(w, r)
+	return w
+}
 
-	return w.End(), nil
+// AWSLambdaScheduleHandler is handler when a scheduled event is received via aws lambda.
+// The schedule whose ID is the name of the EventBridge rule is run.
+// If not found, the schedules due at the event time are run.
+// Returns ConfigError if the config of SLACKBOT_CONFIG is invalid.
+func AWSLambdaScheduleHandler(ctx context.Context, e events.CloudWatchEvent) error {
+	if err := setupFromEnv(); err != nil {
+		return err
+	}
+	for _, resource := range e.Resources {
+		if i := strings.LastIndex(resource, "rule/"); i >= 0 {
+			if RunSchedule(resource[i+len("rule/"):]) {
+				return nil
+			}
+		}
+	}
+
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	RunDueSchedules(t)
+	return nil
+}
+
+// awsLambdaHandler dispatches the event by its source.
+// API Gateway REST API is assumed if the source is not detected.
+// The metrics and the CloudEvents are flushed after each invocation.
+func awsLambdaHandler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	ctx, span := startSpan(ctx, "slackbot.lambda", trace.SpanKindServer)
+	defer span.End()
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		span.SetAttributes(attribute.String("faas.invocation_id", lc.AwsRequestID))
+	}
+	defer func() {
+		if err := FlushCloudEvents(ctx); err != nil {
+			logError(nil, "cloudevents error", "error", err)
+		}
+		if err := FlushMetrics(); err != nil {
+			logError(nil, "metrics error", "error", err)
+		}
+	}()
+
+	var source struct {
+		Task    *Task `json:"slackbot_task"`
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+		Source         string `json:"source"`
+		DetailType     string `json:"detail-type"`
+		Version        string `json:"version"`
+		RequestContext struct {
+			ELB        *json.RawMessage `json:"elb"`
+			DomainName string           `json:"domainName"`
+		} `json:"requestContext"`
+	}
+	json.Unmarshal(payload, &source)
+
+	switch {
+	case source.Task != nil:
+		return nil, RunTask(ctx, source.Task)
+
+	case len(source.Records) > 0 && source.Records[0].EventSource == "aws:sqs":
+		var e events.SQSEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return AWSLambdaSQSHandler(ctx, e)
+
+	case source.Source == "aws.events" && source.DetailType == "Scheduled Event":
+		var e events.CloudWatchEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return nil, AWSLambdaScheduleHandler(ctx, e)
+
+	case source.RequestContext.ELB != nil:
+		var e events.ALBTargetGroupRequest
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return AWSLambdaALBHandler(ctx, e)
+
+	case source.Version == "2.0" && strings.Contains(source.RequestContext.DomainName, ".lambda-url."):
+		var e events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return AWSLambdaFunctionURLHandler(ctx, e)
+
+	case source.Version == "2.0":
+		var e events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return AWSLambdaHTTPAPIHandler(ctx, e)
+	}
+
+	var e events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	return AWSLambdaHandler(ctx, e)
 }
 
 // AWSLambdaStart is start execution of aws lambda.
 func AWSLambdaStart() {
-	lambda.Start(AWSLambdaHandler)
+	lambda.Start(awsLambdaHandler)
 }
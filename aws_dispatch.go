@@ -0,0 +1,230 @@
+package slackbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// awsTaskKey of the payload invoking the Lambda function with a Task.
+const awsTaskKey = "slackbot_task"
+
+// LambdaDispatcher invokes the Lambda function asynchronously with the Task.
+// By default, the function itself is invoked with the credentials of the Lambda environment,
+// and the Task is run by AWSLambdaStart.
+type LambdaDispatcher struct {
+	FunctionName string
+	Region       string
+
+	// Endpoint of the Lambda API, such as "https://lambda.us-east-1.amazonaws.com".
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewLambdaDispatcher invoking the function itself.
+func NewLambdaDispatcher() *LambdaDispatcher {
+	return &LambdaDispatcher{
+		FunctionName: os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+		Region:       os.Getenv("AWS_REGION"),
+	}
+}
+
+// Dispatch the Task by the asynchronous invocation.
+func (d *LambdaDispatcher) Dispatch(ctx context.Context, task *Task) error {
+	body, err := json.Marshal(map[string]*Task{awsTaskKey: task})
+	if err != nil {
+		return err
+	}
+
+	endpoint := d.Endpoint
+	if endpoint == "" {
+		endpoint = "https://lambda." + d.Region + ".amazonaws.com"
+	}
+	r, err := http.NewRequest("POST", endpoint+"/2015-03-31/functions/"+awsEscape(d.FunctionName)+"/invocations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Amz-Invocation-Type", "Event")
+
+	return sendAWSRequest(ctx, d.Client, r, body, "lambda", d.Region, http.StatusAccepted)
+}
+
+// SQSDispatcher sends the Task to the SQS queue, and AWSLambdaSQSHandler runs it.
+type SQSDispatcher struct {
+	QueueURL string
+	Region   string
+	Client   *http.Client
+}
+
+// NewSQSDispatcher sending to the queue URL. The region is taken from the URL or AWS_REGION.
+func NewSQSDispatcher(queueURL string) *SQSDispatcher {
+	region := os.Getenv("AWS_REGION")
+	if u, err := url.Parse(queueURL); err == nil {
+		if parts := strings.Split(u.Hostname(), "."); len(parts) >= 4 && parts[0] == "sqs" {
+			region = parts[1]
+		}
+	}
+	return &SQSDispatcher{QueueURL: queueURL, Region: region}
+}
+
+// Dispatch the Task by SendMessage.
+func (d *SQSDispatcher) Dispatch(ctx context.Context, task *Task) error {
+	message, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	body := []byte(url.Values{
+		"Action":      {"SendMessage"},
+		"MessageBody": {string(message)},
+		"Version":     {"2012-11-05"},
+	}.Encode())
+	r, err := http.NewRequest("POST", d.QueueURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	return sendAWSRequest(ctx, d.Client, r, body, "sqs", d.Region, http.StatusOK)
+}
+
+// AWSLambdaSQSHandler is handler when Tasks are received via SQS.
+// Failed messages are reported as batch item failures to be retried.
+func AWSLambdaSQSHandler(ctx context.Context, e events.SQSEvent) (events.SQSEventResponse, error) {
+	res := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	for _, message := range e.Records {
+		task, err := DecodeTask([]byte(message.Body))
+		if err == nil {
+			err = RunTask(ctx, task)
+		}
+		if err != nil {
+			logError(nil, "task error", "message_id", message.MessageId, "error", err)
+			res.BatchItemFailures = append(res.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+		}
+	}
+	return res, nil
+}
+
+// sendAWSRequest signed with the credentials in the environment variables.
+func sendAWSRequest(ctx context.Context, client *http.Client, r *http.Request, body []byte, service, region string, status int) error {
+	signAWSRequest(r, body, service, region, awsCredentialsFromEnv(), time.Now())
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(r.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != status {
+		message, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%s error: %s %s", service, res.Status, message)
+	}
+	return nil
+}
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func awsCredentialsFromEnv() awsCredentials {
+	return awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// signAWSRequest by Signature Version 4.
+func signAWSRequest(r *http.Request, body []byte, service, region string, creds awsCredentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	r.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": r.URL.Host}
+	for key, values := range r.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	keys := []string{}
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	canonicalHeaders := ""
+	for _, key := range keys {
+		canonicalHeaders += key + ":" + headers[key] + "\n"
+	}
+	signedHeaders := strings.Join(keys, ";")
+
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		strings.Join(segments, "/"),
+		strings.Replace(r.URL.Query().Encode(), "+", "%20", -1),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape the string except the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	escaped := ""
+	for _, b := range []byte(s) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || strings.IndexByte("-_.~", b) >= 0 {
+			escaped += string(b)
+		} else {
+			escaped += fmt.Sprintf("%%%02X", b)
+		}
+	}
+	return escaped
+}
@@ -0,0 +1,183 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	// get-vanilla of the Signature Version 4 test suite
+	r, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signAWSRequest(r, nil, "service", "us-east-1", creds, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", r.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		r.Header.Get("Authorization"))
+}
+
+func TestAWSEscape(t *testing.T) {
+	assert.Equal(t, "func-name_1.~", awsEscape("func-name_1.~"))
+	assert.Equal(t, "arn%3Aaws%3Alambda%20%2F", awsEscape("arn:aws:lambda /"))
+}
+
+func TestLambdaDispatcher(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	os.Setenv("AWS_SESSION_TOKEN", "session")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SESSION_TOKEN")
+	os.Setenv("AWS_LAMBDA_FUNCTION_NAME", "slackbot")
+	os.Setenv("AWS_REGION", "ap-northeast-1")
+	defer os.Unsetenv("AWS_LAMBDA_FUNCTION_NAME")
+	defer os.Unsetenv("AWS_REGION")
+
+	testRun := ToolsCreateTestRun(func() {
+		status = http.StatusAccepted
+	}, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		d := NewLambdaDispatcher()
+		assert.Equal(t, "slackbot", d.FunctionName)
+		assert.Equal(t, "ap-northeast-1", d.Region)
+		d.Endpoint = server.URL
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{"text": "test"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/2015-03-31/functions/slackbot/invocations", got.URL.Path)
+		assert.Equal(t, "Event", got.Header.Get("X-Amz-Invocation-Type"))
+		assert.Equal(t, "session", got.Header.Get("X-Amz-Security-Token"))
+		assert.Contains(t, got.Header.Get("Authorization"), "Credential=AKID/")
+		assert.Contains(t, got.Header.Get("Authorization"), "/ap-northeast-1/lambda/aws4_request")
+		assert.JSONEq(t, `{"slackbot_task":{"type":"message","event":{"text":"test"}}}`, string(body))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		status = http.StatusForbidden
+		d := &LambdaDispatcher{FunctionName: "slackbot", Endpoint: server.URL}
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{}})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSQSDispatcher(t *testing.T) {
+	var got *http.Request
+	var form url.Values
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	testRun := ToolsCreateTestRun(func() {
+		status = http.StatusOK
+	}, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		d := NewSQSDispatcher(server.URL + "/123456789012/slackbot")
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{"text": "test"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/123456789012/slackbot", got.URL.Path)
+		assert.Equal(t, "SendMessage", form.Get("Action"))
+		assert.JSONEq(t, `{"type":"message","event":{"text":"test"}}`, form.Get("MessageBody"))
+		assert.Contains(t, got.Header.Get("Authorization"), "/sqs/aws4_request")
+	})
+
+	testRun(t, "region test", func(t *testing.T) {
+		d := NewSQSDispatcher("https://sqs.us-west-2.amazonaws.com/123456789012/slackbot")
+		assert.Equal(t, "us-west-2", d.Region)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		status = http.StatusBadRequest
+		d := NewSQSDispatcher(server.URL + "/123456789012/slackbot")
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{}})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAWSLambdaSQSHandler(t *testing.T) {
+	var texts []string
+	clear := func() {
+		ToolsInitCommand()
+		texts = []string{}
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				texts = append(texts, e.Text())
+			},
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, ToolsInitCommand)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		e := events.SQSEvent{Records: []events.SQSMessage{
+			{MessageId: "1", Body: `{"type":"app_mention","event":{"text":"test 1"}}`},
+			{MessageId: "2", Body: `{"type":"app_mention","event":{"text":"test 2"}}`},
+		}}
+
+		res, err := AWSLambdaSQSHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Empty(t, res.BatchItemFailures)
+		assert.Equal(t, []string{"test 1", "test 2"}, texts)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		e := events.SQSEvent{Records: []events.SQSMessage{
+			{MessageId: "1", Body: `invalid`},
+			{MessageId: "2", Body: `{"type":"unknown","event":{}}`},
+			{MessageId: "3", Body: `{"type":"app_mention","event":{"text":"test"}}`},
+		}}
+
+		res, err := AWSLambdaSQSHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "1"}, {ItemIdentifier: "2"}}, res.BatchItemFailures)
+		assert.Equal(t, []string{"test"}, texts)
+	})
+
+	testRun(t, "dispatch handler test", func(t *testing.T) {
+		res, err := awsLambdaHandler(context.Background(), json.RawMessage(`{"Records":[{"messageId":"1","eventSource":"aws:sqs","body":"{\"type\":\"app_mention\",\"event\":{\"text\":\"test\"}}"}]}`))
+
+		assert.NoError(t, err)
+		assert.Empty(t, res.(events.SQSEventResponse).BatchItemFailures)
+
+		res, err = awsLambdaHandler(context.Background(), json.RawMessage(`{"slackbot_task":{"type":"app_mention","event":{"text":"test self"}}}`))
+
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+		assert.Equal(t, []string{"test", "test self"}, texts)
+	})
+}
@@ -2,7 +2,18 @@ package slackbot
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +43,338 @@ func TestAWSLambdaHandler(t *testing.T) {
 	})
 }
 
+func ToolsSignLambdaHeaders(body, secret string) map[string]string {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	return map[string]string{
+		"x-slack-request-timestamp": timestamp,
+		"x-slack-signature":         "v0=" + hex.EncodeToString(mac.Sum(nil)),
+	}
+}
+
+func TestAWSLambdaHTTPAPIHandler(t *testing.T) {
+	testRun := ToolsCreateTestRun(func() {
+		ClearCommand()
+		SetSigningSecret("secret")
+	}, func() {
+		SetSigningSecret("")
+	})
+	body := `{"type":"url_verification", "challenge":"test"}`
+
+	testRun(t, "normal test", func(t *testing.T) {
+		e := events.APIGatewayV2HTTPRequest{
+			RawPath:         "/",
+			Headers:         ToolsSignLambdaHeaders(body, "secret"),
+			Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+			IsBase64Encoded: true,
+		}
+		e.RequestContext.HTTP.Method = "POST"
+
+		res, err := AWSLambdaHTTPAPIHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "test", res.Body)
+		assert.Equal(t, "text/plain", res.Headers["Content-Type"])
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		e := events.APIGatewayV2HTTPRequest{
+			RawPath: "/",
+			Headers: ToolsSignLambdaHeaders(body, "invalid"),
+			Body:    body,
+		}
+		e.RequestContext.HTTP.Method = "POST"
+
+		res, err := AWSLambdaHTTPAPIHandler(context.Background(), e)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		e.Body = "invalid base64"
+		e.IsBase64Encoded = true
+		_, err = AWSLambdaHTTPAPIHandler(context.Background(), e)
+		assert.Error(t, err)
+	})
+}
+
+func TestAWSLambdaFunctionURLHandler(t *testing.T) {
+	testRun := ToolsCreateTestRun(func() {
+		ClearCommand()
+		SetSigningSecret("secret")
+	}, func() {
+		SetSigningSecret("")
+	})
+	body := `{"type":"url_verification", "challenge":"test"}`
+
+	testRun(t, "normal test", func(t *testing.T) {
+		e := events.LambdaFunctionURLRequest{
+			RawPath:         "/",
+			Headers:         ToolsSignLambdaHeaders(body, "secret"),
+			Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+			IsBase64Encoded: true,
+		}
+		e.RequestContext.HTTP.Method = "POST"
+
+		res, err := AWSLambdaFunctionURLHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "test", res.Body)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		e := events.LambdaFunctionURLRequest{
+			RawPath: "/",
+			Headers: ToolsSignLambdaHeaders(body, "invalid"),
+			Body:    body,
+		}
+		e.RequestContext.HTTP.Method = "POST"
+
+		res, err := AWSLambdaFunctionURLHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func TestAWSLambdaALBHandler(t *testing.T) {
+	testRun := ToolsCreateTestRun(func() {
+		ClearCommand()
+		SetSigningSecret("secret")
+	}, func() {
+		SetSigningSecret("")
+	})
+	body := `{"type":"url_verification", "challenge":"test"}`
+
+	testRun(t, "normal test", func(t *testing.T) {
+		e := events.ALBTargetGroupRequest{
+			HTTPMethod:      "POST",
+			Path:            "/",
+			Headers:         ToolsSignLambdaHeaders(body, "secret"),
+			Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+			IsBase64Encoded: true,
+		}
+
+		res, err := AWSLambdaALBHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "200 OK", res.StatusDescription)
+		assert.Equal(t, "test", res.Body)
+		assert.Equal(t, "text/plain", res.Headers["Content-Type"])
+		assert.Nil(t, res.MultiValueHeaders)
+	})
+
+	testRun(t, "multi value headers test", func(t *testing.T) {
+		e := events.ALBTargetGroupRequest{
+			HTTPMethod:        "POST",
+			Path:              "/",
+			MultiValueHeaders: map[string][]string{},
+			Body:              body,
+		}
+		for k, v := range ToolsSignLambdaHeaders(body, "secret") {
+			e.MultiValueHeaders[k] = []string{v}
+		}
+
+		res, err := AWSLambdaALBHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test", res.Body)
+		assert.Equal(t, []string{"text/plain"}, res.MultiValueHeaders["Content-Type"])
+		assert.Nil(t, res.Headers)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		e := events.ALBTargetGroupRequest{
+			HTTPMethod: "POST",
+			Path:       "/",
+			Headers:    ToolsSignLambdaHeaders(body, "invalid"),
+			Body:       body,
+		}
+
+		res, err := AWSLambdaALBHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func TestNewLambdaRequest(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		header := lambdaHeader(
+			map[string]string{"host": "example.com", "x-test": "a"},
+			map[string][]string{"x-test": {"b", "c"}},
+			[]string{"a=1", "b=2"},
+		)
+
+		r, err := newLambdaRequest(context.Background(), "POST", "/slack", "a=%20b&c=d", header, "body", false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/slack", r.URL.Path)
+		assert.Equal(t, " b", r.URL.Query().Get("a"))
+		assert.Equal(t, "example.com", r.Host)
+		assert.Equal(t, []string{"b", "c"}, r.Header["X-Test"])
+		assert.Equal(t, "a=1; b=2", r.Header.Get("Cookie"))
+		assert.Equal(t, int64(4), r.ContentLength)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		_, err := newLambdaRequest(context.Background(), "POST", "/", "", http.Header{}, "%", true)
+		assert.Error(t, err)
+
+		_, err = newLambdaRequest(context.Background(), "POST", ":foo", "", http.Header{}, "", false)
+		assert.Error(t, err)
+	})
+}
+
+func TestLambdaResponseHeader(t *testing.T) {
+	headers, cookies := lambdaResponseHeader(http.Header{
+		"Content-Type": {"text/plain"},
+		"Vary":         {"a", "b"},
+		"Set-Cookie":   {"a=1", "b=2"},
+	})
+
+	assert.Equal(t, map[string]string{"Content-Type": "text/plain", "Vary": "a,b"}, headers)
+	assert.Equal(t, []string{"a=1", "b=2"}, cookies)
+}
+
+func TestAWSLambdaScheduleHandler(t *testing.T) {
+	var called []string
+	clear := func() {
+		ToolsClearSchedule()
+		called = []string{}
+		job := func(ctx context.Context, e Event) {
+			called = append(called, e.String("schedule"))
+		}
+		AddScheduleJob(&Schedule{ID: "daily-report", Spec: "0 9 * * *", Job: job})
+		AddScheduleJob(&Schedule{ID: "every-minute", Spec: "* * * * *", Job: job})
+	}
+	testRun := ToolsCreateTestRun(clear, ToolsClearSchedule)
+
+	testRun(t, "rule test", func(t *testing.T) {
+		e := events.CloudWatchEvent{Resources: []string{"arn:aws:events:ap-northeast-1:123456789012:rule/daily-report"}}
+
+		err := AWSLambdaScheduleHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"daily-report"}, called)
+	})
+
+	testRun(t, "due test", func(t *testing.T) {
+		e := events.CloudWatchEvent{
+			Resources: []string{"arn:aws:events:ap-northeast-1:123456789012:rule/every-minute-tick"},
+			Time:      time.Date(2020, 1, 1, 9, 0, 0, 0, time.Local),
+		}
+
+		err := AWSLambdaScheduleHandler(context.Background(), e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"daily-report", "every-minute"}, called)
+	})
+
+	testRun(t, "setup test", func(t *testing.T) {
+		ToolsInitCommand()
+		defer ToolsInitCommand()
+		api = nil
+		os.Setenv("SLACK_BOT_USER_ID", "worker")
+		defer os.Unsetenv("SLACK_BOT_USER_ID")
+
+		err := AWSLambdaScheduleHandler(context.Background(), events.CloudWatchEvent{Resources: []string{"arn:aws:events:ap-northeast-1:123456789012:rule/daily-report"}})
+
+		// set up from the environment variables on a cold start
+		assert.NoError(t, err)
+		assert.NotNil(t, api)
+		_, ok := findCommand("ping")
+		assert.True(t, ok)
+	})
+
+	testRun(t, "config error test", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "slackbot-schedule.yaml")
+		ioutil.WriteFile(path, []byte("log:\n  level: verbose\n"), 0600)
+		defer os.Remove(path)
+		os.Setenv(ConfigEnv, path)
+		defer os.Unsetenv(ConfigEnv)
+		api = nil
+
+		err := AWSLambdaScheduleHandler(context.Background(), events.CloudWatchEvent{Resources: []string{"arn:aws:events:ap-northeast-1:123456789012:rule/daily-report"}})
+
+		assert.IsType(t, &ConfigError{}, err)
+		assert.Empty(t, called)
+	})
+
+	testRun(t, "now test", func(t *testing.T) {
+		err := AWSLambdaScheduleHandler(context.Background(), events.CloudWatchEvent{})
+
+		assert.NoError(t, err)
+		assert.Contains(t, called, "every-minute")
+	})
+}
+
+func TestAWSLambdaDispatchHandler(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	testRun(t, "schedule test", func(t *testing.T) {
+		called := false
+		AddScheduleJob(&Schedule{ID: "test", Spec: "@daily", Job: func(ctx context.Context, e Event) {
+			called = true
+		}})
+		payload := `{"source":"aws.events","detail-type":"Scheduled Event","resources":["arn:aws:events:ap-northeast-1:123456789012:rule/test"]}`
+
+		res, err := awsLambdaHandler(context.Background(), json.RawMessage(payload))
+
+		assert.NoError(t, err)
+		assert.Nil(t, res)
+		assert.True(t, called)
+	})
+
+	testRun(t, "api gateway test", func(t *testing.T) {
+		payload := `{"httpMethod":"POST","path":"/","body":"{\"type\":\"url_verification\",\"challenge\":\"test\"}"}`
+
+		res, err := awsLambdaHandler(context.Background(), json.RawMessage(payload))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test", res.(events.APIGatewayProxyResponse).Body)
+	})
+
+	testRun(t, "http api test", func(t *testing.T) {
+		payload := `{"version":"2.0","rawPath":"/","requestContext":{"domainName":"abc.execute-api.ap-northeast-1.amazonaws.com","http":{"method":"POST"}},"body":"{\"type\":\"url_verification\",\"challenge\":\"test\"}"}`
+
+		res, err := awsLambdaHandler(context.Background(), json.RawMessage(payload))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test", res.(events.APIGatewayV2HTTPResponse).Body)
+	})
+
+	testRun(t, "function url test", func(t *testing.T) {
+		payload := `{"version":"2.0","rawPath":"/","requestContext":{"domainName":"abc.lambda-url.ap-northeast-1.on.aws","http":{"method":"POST"}},"body":"{\"type\":\"url_verification\",\"challenge\":\"test\"}"}`
+
+		res, err := awsLambdaHandler(context.Background(), json.RawMessage(payload))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test", res.(events.LambdaFunctionURLResponse).Body)
+	})
+
+	testRun(t, "alb test", func(t *testing.T) {
+		payload := `{"httpMethod":"POST","path":"/","requestContext":{"elb":{"targetGroupArn":"arn"}},"body":"{\"type\":\"url_verification\",\"challenge\":\"test\"}"}`
+
+		res, err := awsLambdaHandler(context.Background(), json.RawMessage(payload))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "test", res.(events.ALBTargetGroupResponse).Body)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		_, err := awsLambdaHandler(context.Background(), json.RawMessage(`[]`))
+
+		assert.Error(t, err)
+	})
+}
+
 func TestAWSLambdaStart(t *testing.T) {
+	// listen on a free port as the go1.x runtime
+	os.Setenv("_LAMBDA_SERVER_PORT", "0")
 	go AWSLambdaStart()
 }
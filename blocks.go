@@ -0,0 +1,307 @@
+package slackbot
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nlopes/slack"
+)
+
+// Limits of Block Kit validated by Blocks.
+const (
+	MaxBlocks          = 50
+	MaxSectionText     = 3000
+	MaxSectionFields   = 10
+	MaxFieldText       = 2000
+	MaxContextElements = 10
+	MaxActionElements  = 25
+	MaxButtonText      = 75
+	MaxSelectOptions   = 100
+	MaxOverflowOptions = 5
+	MaxImageURL        = 3000
+	MaxAltText         = 2000
+)
+
+// Blocks builds a Block Kit message. Texts of the blocks are mrkdwn, and not escaped.
+//
+//	slackbot.ReplyBlocks(e, slackbot.NewBlocks().
+//		Section("*Deploy* finished").
+//		Fields("*Service*\napi", "*Env*\nprod").
+//		Actions(slackbot.NewButton("rollback", "api", "Rollback")).
+//		Text("Deploy finished"))
+type Blocks struct {
+	blocks []slack.Block
+	text   string
+}
+
+// BlocksError has all the violations of the limits of Block Kit.
+type BlocksError struct {
+	Errors []string
+}
+
+func (e *BlocksError) Error() string {
+	return "invalid blocks: " + strings.Join(e.Errors, "; ")
+}
+
+// NewBlocks of a Block Kit message.
+func NewBlocks() *Blocks {
+	return &Blocks{}
+}
+
+// Text fallback shown in notifications. It is escaped like the text of ReplyMessage.
+// The texts of the sections are used if not set.
+func (b *Blocks) Text(text string) *Blocks {
+	b.text = text
+	return b
+}
+
+// Section of the text, with the accessory such as a button if any.
+func (b *Blocks) Section(text string, accessory ...slack.BlockElement) *Blocks {
+	return b.Add(slack.NewSectionBlock(markdownText(text), nil, sectionAccessory(accessory)))
+}
+
+// Fields of a section shown in two columns.
+func (b *Blocks) Fields(fields ...string) *Blocks {
+	texts := make([]*slack.TextBlockObject, len(fields))
+	for i, field := range fields {
+		texts[i] = markdownText(field)
+	}
+	return b.Add(slack.NewSectionBlock(nil, texts, nil))
+}
+
+// Context of the texts shown in small letters.
+func (b *Blocks) Context(texts ...string) *Blocks {
+	elements := make([]slack.MixedElement, len(texts))
+	for i, text := range texts {
+		elements[i] = markdownText(text)
+	}
+	return b.Add(slack.NewContextBlock("", elements...))
+}
+
+// Divider between the blocks.
+func (b *Blocks) Divider() *Blocks {
+	return b.Add(slack.NewDividerBlock())
+}
+
+// Image of the URL.
+func (b *Blocks) Image(url, altText string) *Blocks {
+	return b.Add(slack.NewImageBlock(url, altText, "", nil))
+}
+
+// Actions of the interactive elements such as buttons, selects and overflow menus.
+func (b *Blocks) Actions(elements ...slack.BlockElement) *Blocks {
+	return b.Add(slack.NewActionBlock("", elements...))
+}
+
+// Add the blocks built by slack.
+func (b *Blocks) Add(blocks ...slack.Block) *Blocks {
+	b.blocks = append(b.blocks, blocks...)
+	return b
+}
+
+// Len of the blocks.
+func (b *Blocks) Len() int {
+	return len(b.blocks)
+}
+
+// Build the blocks. Returns BlocksError if the blocks exceed the limits of Block Kit.
+func (b *Blocks) Build() ([]slack.Block, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return append([]slack.Block{}, b.blocks...), nil
+}
+
+// Validate the limits of Block Kit.
+func (b *Blocks) Validate() error {
+	errs := []string{}
+	if len(b.blocks) == 0 {
+		errs = append(errs, "no blocks")
+	}
+	if len(b.blocks) > MaxBlocks {
+		errs = append(errs, fmt.Sprintf("%d blocks exceed %d", len(b.blocks), MaxBlocks))
+	}
+	for i, block := range b.blocks {
+		for _, err := range validateBlock(block) {
+			errs = append(errs, fmt.Sprintf("block %d: %s", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return &BlocksError{Errors: errs}
+	}
+	return nil
+}
+
+// msgOptions of the blocks with the text fallback.
+func (b *Blocks) msgOptions() ([]slack.MsgOption, error) {
+	blocks, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	text := slack.MsgOptionText(b.text, true)
+	if b.text == "" {
+		text = slack.MsgOptionText(b.sectionText(), false)
+	}
+	return []slack.MsgOption{text, slack.MsgOptionBlocks(blocks...)}, nil
+}
+
+func (b *Blocks) sectionText() string {
+	texts := []string{}
+	for _, block := range b.blocks {
+		if s, ok := block.(*slack.SectionBlock); ok && s.Text != nil {
+			texts = append(texts, s.Text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func validateBlock(block slack.Block) []string {
+	errs := []string{}
+	exceed := func(name string, n, max int) {
+		if n > max {
+			errs = append(errs, fmt.Sprintf("%s of %d exceeds %d", name, n, max))
+		}
+	}
+	empty := func(name string, n int) {
+		if n == 0 {
+			errs = append(errs, name+" is empty")
+		}
+	}
+
+	switch b := block.(type) {
+	case *slack.SectionBlock:
+		if b.Text == nil && len(b.Fields) == 0 {
+			errs = append(errs, "section has neither text nor fields")
+		}
+		if b.Text != nil {
+			empty("section text", textLength(b.Text))
+			exceed("section text", textLength(b.Text), MaxSectionText)
+		}
+		exceed("fields", len(b.Fields), MaxSectionFields)
+		for _, field := range b.Fields {
+			empty("field text", textLength(field))
+			exceed("field text", textLength(field), MaxFieldText)
+		}
+		if b.Accessory != nil {
+			errs = append(errs, validateAccessory(b.Accessory)...)
+		}
+	case *slack.ContextBlock:
+		if len(b.ContextElements.Elements) == 0 {
+			errs = append(errs, "context has no elements")
+		}
+		exceed("context elements", len(b.ContextElements.Elements), MaxContextElements)
+		for _, element := range b.ContextElements.Elements {
+			if t, ok := element.(*slack.TextBlockObject); ok {
+				empty("context text", textLength(t))
+			}
+		}
+	case *slack.ImageBlock:
+		exceed("image URL", utf8.RuneCountInString(b.ImageURL), MaxImageURL)
+		exceed("alt text", utf8.RuneCountInString(b.AltText), MaxAltText)
+	case *slack.ActionBlock:
+		if len(b.Elements.ElementSet) == 0 {
+			errs = append(errs, "actions has no elements")
+		}
+		exceed("action elements", len(b.Elements.ElementSet), MaxActionElements)
+		for _, element := range b.Elements.ElementSet {
+			errs = append(errs, validateElement(element)...)
+		}
+	}
+	return errs
+}
+
+func validateAccessory(a *slack.Accessory) []string {
+	switch {
+	case a.ButtonElement != nil:
+		return validateElement(a.ButtonElement)
+	case a.SelectElement != nil:
+		return validateElement(a.SelectElement)
+	case a.OverflowElement != nil:
+		return validateElement(a.OverflowElement)
+	}
+	return nil
+}
+
+func validateElement(element slack.BlockElement) []string {
+	errs := []string{}
+	switch e := element.(type) {
+	case *slack.ButtonBlockElement:
+		if n := textLength(e.Text); n > MaxButtonText {
+			errs = append(errs, fmt.Sprintf("button text of %d exceeds %d", n, MaxButtonText))
+		}
+	case *slack.SelectBlockElement:
+		if n := len(e.Options); n > MaxSelectOptions {
+			errs = append(errs, fmt.Sprintf("select options of %d exceed %d", n, MaxSelectOptions))
+		}
+	case *slack.OverflowBlockElement:
+		if n := len(e.Options); n < 2 || n > MaxOverflowOptions {
+			errs = append(errs, fmt.Sprintf("overflow options of %d are not between 2 and %d", n, MaxOverflowOptions))
+		}
+	}
+	return errs
+}
+
+func textLength(t *slack.TextBlockObject) int {
+	if t == nil {
+		return 0
+	}
+	return utf8.RuneCountInString(t.Text)
+}
+
+func markdownText(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+}
+
+func plainText(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.PlainTextType, text, true, false)
+}
+
+func sectionAccessory(elements []slack.BlockElement) *slack.Accessory {
+	if len(elements) == 0 {
+		return nil
+	}
+	return slack.NewAccessory(elements[0])
+}
+
+// NewButton of the action. The value is passed to the interaction.
+func NewButton(actionID, value, text string) *slack.ButtonBlockElement {
+	return slack.NewButtonBlockElement(actionID, value, plainText(text))
+}
+
+// NewLinkButton opening the URL.
+func NewLinkButton(actionID, text, url string) *slack.ButtonBlockElement {
+	button := slack.NewButtonBlockElement(actionID, "", plainText(text))
+	button.URL = url
+	return button
+}
+
+// NewOption of selects and overflow menus.
+func NewOption(value, text string) *slack.OptionBlockObject {
+	return slack.NewOptionBlockObject(value, plainText(text))
+}
+
+// NewSelect of the static options.
+func NewSelect(actionID, placeholder string, options ...*slack.OptionBlockObject) *slack.SelectBlockElement {
+	return slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, plainText(placeholder), actionID, options...)
+}
+
+// NewUserSelect of the users in the workspace.
+func NewUserSelect(actionID, placeholder string) *slack.SelectBlockElement {
+	return slack.NewOptionsSelectBlockElement(slack.OptTypeUser, plainText(placeholder), actionID)
+}
+
+// NewChannelSelect of the public channels in the workspace.
+func NewChannelSelect(actionID, placeholder string) *slack.SelectBlockElement {
+	return slack.NewOptionsSelectBlockElement(slack.OptTypeChannels, plainText(placeholder), actionID)
+}
+
+// NewOverflow menu of 2 to 5 options.
+func NewOverflow(actionID string, options ...*slack.OptionBlockObject) *slack.OverflowBlockElement {
+	return slack.NewOverflowBlockElement(actionID, options...)
+}
+
+// NewImageElement for the accessory of a section.
+func NewImageElement(url, altText string) *slack.ImageBlockElement {
+	return slack.NewImageBlockElement(url, altText)
+}
@@ -0,0 +1,119 @@
+package slackbot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocks(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		blocks, err := NewBlocks().
+			Section("*deploy*", NewButton("rollback", "api", "Rollback")).
+			Fields("*Service*\napi", "*Env*\nprod").
+			Context("by <@U1>").
+			Divider().
+			Image("https://example.com/a.png", "graph").
+			Actions(
+				NewLinkButton("open", "Open", "https://example.com"),
+				NewSelect("env", "Environment", NewOption("dev", "Dev"), NewOption("prod", "Prod")),
+				NewUserSelect("user", "User"),
+				NewChannelSelect("channel", "Channel"),
+				NewOverflow("more", NewOption("logs", "Logs"), NewOption("stop", "Stop")),
+			).
+			Build()
+		assert.NoError(t, err)
+		assert.Len(t, blocks, 6)
+
+		b, err := json.Marshal(slack.Blocks{BlockSet: blocks})
+		assert.NoError(t, err)
+		s := string(b)
+		assert.Contains(t, s, `{"type":"section","text":{"type":"mrkdwn","text":"*deploy*"},"accessory":{"type":"button","text":{"type":"plain_text","text":"Rollback","emoji":true},"action_id":"rollback","value":"api"}}`)
+		assert.Contains(t, s, `"fields":[{"type":"mrkdwn","text":"*Service*\napi"},{"type":"mrkdwn","text":"*Env*\nprod"}]`)
+		assert.Contains(t, s, `{"type":"context","elements":[{"type":"mrkdwn","text":"by \u003c@U1\u003e"}]}`)
+		assert.Contains(t, s, `{"type":"divider"}`)
+		assert.Contains(t, s, `{"type":"image","image_url":"https://example.com/a.png","alt_text":"graph"`)
+		assert.Contains(t, s, `"url":"https://example.com"`)
+		assert.Contains(t, s, `"type":"static_select"`)
+		assert.Contains(t, s, `"type":"users_select"`)
+		assert.Contains(t, s, `"type":"channels_select"`)
+		assert.Contains(t, s, `"type":"overflow"`)
+	})
+
+	testRun(t, "text test", func(t *testing.T) {
+		_, values, err := slack.UnsafeApplyMsgOptions("", "C1", "", mustMsgOptions(t, NewBlocks().Section("a <@U1>").Divider().Section("b"))...)
+		assert.NoError(t, err)
+		assert.Equal(t, "a <@U1>\nb", values.Get("text"))
+
+		_, values, err = slack.UnsafeApplyMsgOptions("", "C1", "", mustMsgOptions(t, NewBlocks().Section("a").Text("a & b"))...)
+		assert.NoError(t, err)
+		assert.Equal(t, "a &amp; b", values.Get("text"))
+	})
+
+	testRun(t, "add test", func(t *testing.T) {
+		b := NewBlocks().Add(slack.NewDividerBlock(), slack.NewDividerBlock())
+		assert.Equal(t, 2, b.Len())
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		_, err := NewBlocks().Build()
+		assert.EqualError(t, err, "invalid blocks: no blocks")
+
+		b := NewBlocks()
+		for i := 0; i < MaxBlocks+1; i++ {
+			b.Divider()
+		}
+		_, err = b.Build()
+		assert.EqualError(t, err, "invalid blocks: 51 blocks exceed 50")
+
+		long := strings.Repeat("あ", MaxSectionText+1)
+		texts := strings.Split(strings.Repeat("a", MaxContextElements+1), "")
+		_, err = NewBlocks().
+			Section(long).
+			Add(slack.NewSectionBlock(nil, nil, nil)).
+			Fields(texts...).
+			Fields(strings.Repeat("a", MaxFieldText+1)).
+			Context(texts...).
+			Image(strings.Repeat("a", MaxImageURL+1), strings.Repeat("a", MaxAltText+1)).
+			Actions(NewButton("a", "a", strings.Repeat("a", MaxButtonText+1)), NewOverflow("b", NewOption("a", "a"))).
+			Section("a", NewSelect("c", "c", make([]*slack.OptionBlockObject, MaxSelectOptions+1)...)).
+			Build()
+		if assert.IsType(t, &BlocksError{}, err) {
+			assert.Equal(t, []string{
+				"block 0: section text of 3001 exceeds 3000",
+				"block 1: section has neither text nor fields",
+				"block 2: fields of 11 exceeds 10",
+				"block 3: field text of 2001 exceeds 2000",
+				"block 4: context elements of 11 exceeds 10",
+				"block 5: image URL of 3001 exceeds 3000",
+				"block 5: alt text of 2001 exceeds 2000",
+				"block 6: button text of 76 exceeds 75",
+				"block 6: overflow options of 1 are not between 2 and 5",
+				"block 7: select options of 101 exceed 100",
+			}, err.(*BlocksError).Errors)
+		}
+
+		// rejected by Slack
+		_, err = NewBlocks().Section("").Fields("a", "").Context().Context("").Actions().Build()
+		if assert.IsType(t, &BlocksError{}, err) {
+			assert.Equal(t, []string{
+				"block 0: section text is empty",
+				"block 1: field text is empty",
+				"block 2: context has no elements",
+				"block 3: context text is empty",
+				"block 4: actions has no elements",
+			}, err.(*BlocksError).Errors)
+		}
+	})
+}
+
+func mustMsgOptions(t *testing.T, b *Blocks) []slack.MsgOption {
+	options, err := b.msgOptions()
+	assert.NoError(t, err)
+	return options
+}
@@ -0,0 +1,137 @@
+// Package boltstore is a slackbot.Store saved in a BoltDB file.
+package boltstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store saves the bot state in a BoltDB file. Each namespace is a bucket.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open the BoltDB file as Store.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
+// New Store with the opened BoltDB.
+func New(db *bolt.DB) *Store {
+	return &Store{db: db}
+}
+
+// Close the BoltDB.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// encode the value with the expiry time in unix nano. Zero means no expiry.
+func encode(value []byte, ttl time.Duration) []byte {
+	data := make([]byte, 8, 8+len(value))
+	if ttl != 0 {
+		binary.BigEndian.PutUint64(data, uint64(time.Now().Add(ttl).UnixNano()))
+	}
+	return append(data, value...)
+}
+
+// decode the value. Returns false if expired.
+func decode(data []byte) ([]byte, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	expiresAt := int64(binary.BigEndian.Uint64(data[:8]))
+	if expiresAt != 0 && time.Now().UnixNano() > expiresAt {
+		return nil, false
+	}
+	return append([]byte{}, data[8:]...), true
+}
+
+// Get the value.
+func (s *Store) Get(namespace, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return slackbot.ErrNotFound
+		}
+		v, ok := decode(b.Get([]byte(key)))
+		if !ok {
+			return slackbot.ErrNotFound
+		}
+		value = v
+		return nil
+	})
+	return value, err
+}
+
+// Set the value.
+func (s *Store) Set(namespace, key string, value []byte, ttl time.Duration) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), encode(value, ttl))
+	})
+}
+
+// Delete the value.
+func (s *Store) Delete(namespace, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// List keys in the namespace in order.
+func (s *Store) List(namespace string) ([]string, error) {
+	keys := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if _, ok := decode(v); ok {
+				keys = append(keys, string(k))
+			}
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// CompareAndSwap the value.
+func (s *Store) CompareAndSwap(namespace, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	swapped := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+
+		current, ok := decode(b.Get([]byte(key)))
+		if old == nil && ok {
+			return nil
+		}
+		if old != nil && (!ok || !bytes.Equal(current, old)) {
+			return nil
+		}
+
+		swapped = true
+		return b.Put([]byte(key), encode(value, ttl))
+	})
+	return swapped, err
+}
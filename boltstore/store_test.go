@@ -0,0 +1,34 @@
+package boltstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/peto-tn/slackbot-go/slackbottest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	slackbottest.RunStoreTests(t, store)
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("error test", func(t *testing.T) {
+		_, err := Open(filepath.Join("not", "found", "test.db"))
+		assert.Error(t, err)
+	})
+}
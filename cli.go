@@ -0,0 +1,151 @@
+package slackbot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// CLI runs commands in the terminal without Slack.
+// Each line is handled as a mention to the bot, and messages posted by the bot are printed.
+type CLI struct {
+	User    string
+	Channel string
+	Prompt  string
+	In      io.Reader
+	Out     io.Writer
+}
+
+// RunCLI on stdin and stdout.
+func RunCLI() error {
+	cli := &CLI{
+		User:    "UCLIUSER",
+		Channel: "CCLICHANNEL",
+		Prompt:  "> ",
+		In:      os.Stdin,
+		Out:     os.Stdout,
+	}
+	return cli.Run()
+}
+
+// Run the CLI until the end of input or "exit".
+func (c *CLI) Run() error {
+	Setup("", "", "")
+	if slackBotUserID == "" {
+		slackBotUserID = "UCLIBOT"
+	}
+	SetClient(&cliClient{out: c.Out})
+
+	scanner := bufio.NewScanner(c.In)
+	for {
+		fmt.Fprint(c.Out, c.Prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(c.Out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		}
+
+		runTask(TaskMessage, c.event(line), nil)
+	}
+}
+
+func (c *CLI) event(line string) Event {
+	ts := fmt.Sprintf("%d.%06d", time.Now().Unix(), time.Now().Nanosecond()/1000)
+	e := Event{
+		"type":     "message",
+		"user":     c.User,
+		"channel":  c.Channel,
+		"text":     fmt.Sprintf("<@%s> %s", slackBotUserID, line),
+		"ts":       ts,
+		"event_ts": ts,
+	}
+	e.ModifyText()
+	return e
+}
+
+// cliClient prints messages instead of sending them to Slack.
+type cliClient struct {
+	out io.Writer
+}
+
+func (c *cliClient) print(destination, text string) {
+	fmt.Fprintf(c.out, "[%s] %s\n", destination, text)
+}
+
+func (c *cliClient) values(channelID string, options ...slack.MsgOption) url.Values {
+	_, values, _ := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	return values
+}
+
+func (c *cliClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	values := c.values(channelID, options...)
+	destination := "#" + channelID
+	if ts := values.Get("thread_ts"); ts != "" {
+		destination += " thread " + ts
+	}
+	c.print(destination, values.Get("text"))
+	return channelID, "", nil
+}
+
+func (c *cliClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	values := c.values(channelID, options...)
+	c.print("#"+channelID+" ephemeral @"+userID, values.Get("text"))
+	return "", nil
+}
+
+func (c *cliClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	values := c.values(channelID, options...)
+	c.print("#"+channelID+" update "+timestamp, values.Get("text"))
+	return channelID, timestamp, values.Get("text"), nil
+}
+
+func (c *cliClient) AddReaction(name string, item slack.ItemRef) error {
+	c.print("#"+item.Channel+" reaction "+item.Timestamp, ":"+name+":")
+	return nil
+}
+
+func (c *cliClient) GetUserInfo(user string) (*slack.User, error) {
+	return &slack.User{ID: user, Name: user}, nil
+}
+
+func (c *cliClient) GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error) {
+	channel := &slack.Channel{}
+	channel.ID = channelID
+	return channel, nil
+}
+
+func (c *cliClient) GetConversationHistory(params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error) {
+	return &slack.GetConversationHistoryResponse{}, nil
+}
+
+func (c *cliClient) GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+	return []slack.Message{}, false, "", nil
+}
+
+func (c *cliClient) GetUsersInConversation(params *slack.GetUsersInConversationParameters) ([]string, string, error) {
+	return []string{}, "", nil
+}
+
+func (c *cliClient) OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	channel := &slack.Channel{}
+	channel.ID = "D" + strings.Join(params.Users, "")
+	return channel, false, false, nil
+}
+
+func (c *cliClient) UploadFile(params slack.FileUploadParameters) (*slack.File, error) {
+	c.print("#"+strings.Join(params.Channels, ",#")+" file "+params.Filename, params.Content)
+	return &slack.File{Name: params.Filename, Title: params.Title}, nil
+}
@@ -0,0 +1,116 @@
+package slackbot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCLI_Run(t *testing.T) {
+	original := api
+	clear := func() {
+		ToolsInitCommand()
+		slackBotUserID = ""
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		clear()
+		api = original
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				ReplyMessage(e, "reply")
+				PostMessage(e, "message")
+				PostEphemeral(e, "ephemeral")
+			},
+		})
+		out := &bytes.Buffer{}
+		cli := &CLI{User: "U1", Channel: "C1", In: strings.NewReader("test\n\nexit\ntest\n"), Out: out}
+
+		err := cli.Run()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "UCLIBOT", slackBotUserID)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 3)
+		assert.Regexp(t, `^\[#C1 thread \d+\.\d+\] reply$`, lines[0])
+		assert.Equal(t, "[#C1] message", lines[1])
+		assert.Equal(t, "[#C1 ephemeral @U1] ephemeral", lines[2])
+	})
+
+	testRun(t, "message handler test", func(t *testing.T) {
+		handler := &TestMessageHandler{}
+		SetMessageHandler(handler)
+		defer SetMessageHandler(nil)
+		cli := &CLI{User: "U1", Channel: "C1", In: strings.NewReader("hello"), Out: &bytes.Buffer{}}
+
+		err := cli.Run()
+
+		assert.NoError(t, err)
+		assert.True(t, handler.OnMentionMessaged)
+	})
+
+	testRun(t, "allowed channels test", func(t *testing.T) {
+		SetAllowedChannels("C2")
+		defer SetAllowedChannels()
+		called := false
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				called = true
+			},
+		})
+		cli := &CLI{User: "U1", Channel: "C1", In: strings.NewReader("test"), Out: &bytes.Buffer{}}
+
+		err := cli.Run()
+
+		assert.NoError(t, err)
+		assert.False(t, called)
+	})
+}
+
+func TestCLIClient(t *testing.T) {
+	out := &bytes.Buffer{}
+	client := &cliClient{out: out}
+	testRun := ToolsCreateTestRun(out.Reset, nil)
+
+	testRun(t, "update test", func(t *testing.T) {
+		_, _, text, err := client.UpdateMessage("C1", "1.0", slack.MsgOptionText("test", true))
+		assert.NoError(t, err)
+		assert.Equal(t, "test", text)
+		assert.Equal(t, "[#C1 update 1.0] test\n", out.String())
+	})
+
+	testRun(t, "reaction test", func(t *testing.T) {
+		err := client.AddReaction("eyes", slack.NewRefToMessage("C1", "1.0"))
+		assert.NoError(t, err)
+		assert.Equal(t, "[#C1 reaction 1.0] :eyes:\n", out.String())
+	})
+
+	testRun(t, "upload test", func(t *testing.T) {
+		file, err := client.UploadFile(slack.FileUploadParameters{Channels: []string{"C1"}, Filename: "a.txt", Content: "test"})
+		assert.NoError(t, err)
+		assert.Equal(t, "a.txt", file.Name)
+		assert.Equal(t, "[#C1 file a.txt] test\n", out.String())
+	})
+
+	testRun(t, "conversation test", func(t *testing.T) {
+		user, _ := client.GetUserInfo("U1")
+		assert.Equal(t, "U1", user.ID)
+		channel, _ := client.GetConversationInfo("C1", false)
+		assert.Equal(t, "C1", channel.ID)
+		history, _ := client.GetConversationHistory(&slack.GetConversationHistoryParameters{})
+		assert.Len(t, history.Messages, 0)
+		replies, _, _, _ := client.GetConversationReplies(&slack.GetConversationRepliesParameters{})
+		assert.Len(t, replies, 0)
+		members, _, _ := client.GetUsersInConversation(&slack.GetUsersInConversationParameters{})
+		assert.Len(t, members, 0)
+		dm, _, _, _ := client.OpenConversation(&slack.OpenConversationParameters{Users: []string{"U1"}})
+		assert.Equal(t, "DU1", dm.ID)
+	})
+}
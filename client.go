@@ -0,0 +1,57 @@
+package slackbot
+
+import (
+	"net/http"
+
+	"github.com/nlopes/slack"
+)
+
+// Client of the Slack Web API used by slackbot.
+// *slack.Client satisfies this interface.
+type Client interface {
+	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+	PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error)
+	UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	AddReaction(name string, item slack.ItemRef) error
+	GetUserInfo(user string) (*slack.User, error)
+	GetConversationInfo(channelID string, includeLocale bool) (*slack.Channel, error)
+	GetConversationHistory(params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error)
+	GetConversationReplies(params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+	GetUsersInConversation(params *slack.GetUsersInConversationParameters) ([]string, string, error)
+	OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+	UploadFile(params slack.FileUploadParameters) (*slack.File, error)
+}
+
+var (
+	apiURL     = slack.APIURL
+	httpClient = &http.Client{}
+)
+
+// SetAPIURL of the Slack Web API. Used by the client created in Setup.
+func SetAPIURL(url string) {
+	apiURL = url
+}
+
+// SetHTTPClient for the Slack Web API. Used by the client created in Setup.
+func SetHTTPClient(client *http.Client) {
+	httpClient = client
+}
+
+// SetClient replaces the Slack Web API client.
+func SetClient(client Client) {
+	api = client
+}
+
+// GetClient of the Slack Web API created in Setup.
+// Use ClientFor in commands instead, which returns the client of the team of the Event for multiple workspaces.
+func GetClient() Client {
+	return api
+}
+
+func newClient(token string) Client {
+	return slack.New(
+		token,
+		slack.OptionAPIURL(apiURL),
+		slack.OptionHTTPClient(instrumentHTTPClient(httpClient)),
+	)
+}
@@ -0,0 +1,54 @@
+package slackbot
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAPIURL(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetAPIURL(slack.APIURL)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		fake := ToolsStartFakeSlack()
+		defer fake.Close()
+
+		Setup("", "", "token")
+		_, _, err := api.PostMessage("C1", slack.MsgOptionText("test", true))
+
+		assert.NoError(t, err)
+		assert.Len(t, fake.CallsFor("chat.postMessage"), 1)
+	})
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetHTTPClient(&http.Client{})
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		client := &http.Client{}
+		SetHTTPClient(client)
+
+		assert.Equal(t, client, httpClient)
+	})
+}
+
+func TestSetClient(t *testing.T) {
+	original := api
+	testRun := ToolsCreateTestRun(nil, func() {
+		api = original
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		client := slack.New("token")
+		SetClient(client)
+
+		assert.Equal(t, client, api)
+		assert.Equal(t, client, GetClient())
+	})
+}
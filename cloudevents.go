@@ -0,0 +1,363 @@
+package slackbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CloudEventTypeCommandExecuted is the type of the CloudEvents emitted for command executions.
+const CloudEventTypeCommandExecuted = "com.github.peto-tn.slackbot.command.executed"
+
+// CloudEvent of the CloudEvents specification 1.0.
+type CloudEvent struct {
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	Data            []byte
+
+	// Extensions attributes of string values, by the lowercase name.
+	Extensions map[string]string
+}
+
+// Extension attributes of the signature of Slack, forwarded with the original request body as the data.
+const (
+	CloudEventExtensionSlackSignature        = "slacksignature"
+	CloudEventExtensionSlackRequestTimestamp = "slackrequesttimestamp"
+)
+
+// cloudEventAttributes of the specification, which are not extensions.
+var cloudEventAttributes = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true, "subject": true, "time": true,
+	"datacontenttype": true, "dataschema": true, "data": true, "data_base64": true,
+}
+
+// cloudEventJSON is the structured content mode of CloudEvent.
+type cloudEventJSON struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+var (
+	cloudEventsSink   string
+	cloudEventsSource = "slackbot"
+	cloudEventsClient = &http.Client{Timeout: 5 * time.Second}
+
+	// cloudEventsQueue of the CloudEvents sent in background, not to delay the commands.
+	cloudEventsQueue  = make(chan *queuedCloudEvent, cloudEventsMaxQueue)
+	cloudEventsSender sync.Once
+
+	// cloudEventsPending in the queue or being sent, and cloudEventsIdle closed when it is zero.
+	cloudEventsMu      sync.Mutex
+	cloudEventsPending int
+	cloudEventsIdle    chan struct{}
+)
+
+// cloudEventsMaxQueue of the CloudEvents waiting to be sent. More are dropped while the sink is slow.
+const cloudEventsMaxQueue = 100
+
+// queuedCloudEvent to the sink.
+type queuedCloudEvent struct {
+	ctx  context.Context
+	e    Event
+	sink string
+	ce   *CloudEvent
+}
+
+// SetCloudEventsSink to which the CloudEvents of command executions are sent.
+// K_SINK of Knative SinkBinding is used by default, and nothing is sent if neither is set.
+func SetCloudEventsSink(url string) {
+	cloudEventsSink = url
+}
+
+// SetCloudEventsSource of the emitted CloudEvents. "slackbot" is used by default.
+func SetCloudEventsSource(source string) {
+	cloudEventsSource = source
+}
+
+// ReadCloudEvent from the request in the binary or the structured content mode.
+func ReadCloudEvent(r *http.Request) (*CloudEvent, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/cloudevents+json" {
+		return decodeCloudEvent(body)
+	}
+
+	if specVersion := r.Header.Get("Ce-Specversion"); specVersion == "" {
+		return nil, errors.New("not a cloudevent")
+	} else if !strings.HasPrefix(specVersion, "1.") {
+		return nil, fmt.Errorf("unsupported specversion: %s", specVersion)
+	}
+	ce := &CloudEvent{
+		ID:              r.Header.Get("Ce-Id"),
+		Source:          r.Header.Get("Ce-Source"),
+		Type:            r.Header.Get("Ce-Type"),
+		Subject:         r.Header.Get("Ce-Subject"),
+		DataContentType: r.Header.Get("Content-Type"),
+		Data:            body,
+	}
+	ce.Time, _ = time.Parse(time.RFC3339Nano, r.Header.Get("Ce-Time"))
+	for key := range r.Header {
+		name := strings.ToLower(key)
+		if strings.HasPrefix(name, "ce-") && !cloudEventAttributes[name[3:]] {
+			ce.setExtension(name[3:], r.Header.Get(key))
+		}
+	}
+	return ce, nil
+}
+
+func (ce *CloudEvent) setExtension(name, value string) {
+	if ce.Extensions == nil {
+		ce.Extensions = map[string]string{}
+	}
+	ce.Extensions[name] = value
+}
+
+func decodeCloudEvent(body []byte) (*CloudEvent, error) {
+	var structured cloudEventJSON
+	if err := json.Unmarshal(body, &structured); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(structured.SpecVersion, "1.") {
+		return nil, fmt.Errorf("unsupported specversion: %s", structured.SpecVersion)
+	}
+
+	ce := &CloudEvent{
+		ID:              structured.ID,
+		Source:          structured.Source,
+		Type:            structured.Type,
+		Subject:         structured.Subject,
+		DataContentType: structured.DataContentType,
+		Data:            structured.Data,
+	}
+	ce.Time, _ = time.Parse(time.RFC3339Nano, structured.Time)
+
+	var attributes map[string]json.RawMessage
+	json.Unmarshal(body, &attributes)
+	for name, raw := range attributes {
+		var value string
+		if !cloudEventAttributes[name] && json.Unmarshal(raw, &value) == nil {
+			ce.setExtension(name, value)
+		}
+	}
+
+	switch {
+	case structured.DataBase64 != "":
+		data, err := base64.StdEncoding.DecodeString(structured.DataBase64)
+		if err != nil {
+			return nil, err
+		}
+		ce.Data = data
+	case len(ce.Data) > 0 && ce.Data[0] == '"' && !isJSONContentType(ce.DataContentType):
+		// data of a non-JSON content type is encoded as a string
+		var data string
+		if err := json.Unmarshal(ce.Data, &data); err != nil {
+			return nil, err
+		}
+		ce.Data = []byte(data)
+	}
+	return ce, nil
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// OnCloudEvent is receive slack events handler, whose data is the original request body of the Events API, slash commands or interactions.
+// The events are handled in the same way as OnCall. The signature of Slack is verified against the data
+// by the X-Slack-Signature and X-Slack-Request-Timestamp headers, or the slacksignature and slackrequesttimestamp extensions.
+func OnCloudEvent(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, onCloudEvent)
+}
+
+func onCloudEvent(w http.ResponseWriter, r *http.Request) {
+	if !setupRequest(w) {
+		return
+	}
+	ce, err := ReadCloudEvent(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := verifySignature(cloudEventSignatureHeader(r.Header, ce), ce.Data); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	contentType := ce.DataContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	r.Header.Set("Content-Type", contentType)
+	onBody(w, r, ce.Data)
+}
+
+// cloudEventSignatureHeader of Slack forwarded in the headers, or in the extensions of the CloudEvent.
+func cloudEventSignatureHeader(header http.Header, ce *CloudEvent) http.Header {
+	h := http.Header{}
+	h.Set("X-Slack-Signature", selectString(header.Get("X-Slack-Signature") != "", header.Get("X-Slack-Signature"), ce.Extensions[CloudEventExtensionSlackSignature]))
+	h.Set("X-Slack-Request-Timestamp", selectString(header.Get("X-Slack-Request-Timestamp") != "", header.Get("X-Slack-Request-Timestamp"), ce.Extensions[CloudEventExtensionSlackRequestTimestamp]))
+	return h
+}
+
+// SendCloudEvent to the URL in the binary content mode.
+func SendCloudEvent(ctx context.Context, url string, ce *CloudEvent) error {
+	r, err := http.NewRequest("POST", url, bytes.NewReader(ce.Data))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Ce-Specversion", "1.0")
+	r.Header.Set("Ce-Id", ce.ID)
+	r.Header.Set("Ce-Source", ce.Source)
+	r.Header.Set("Ce-Type", ce.Type)
+	if ce.Subject != "" {
+		r.Header.Set("Ce-Subject", ce.Subject)
+	}
+	if !ce.Time.IsZero() {
+		r.Header.Set("Ce-Time", ce.Time.UTC().Format(time.RFC3339Nano))
+	}
+	if ce.DataContentType != "" {
+		r.Header.Set("Content-Type", ce.DataContentType)
+	}
+	for name, value := range ce.Extensions {
+		r.Header.Set("Ce-"+name, value)
+	}
+	// distributed tracing extension
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	res, err := cloudEventsClient.Do(r.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("cloudevents error: %s", res.Status)
+	}
+	return nil
+}
+
+// emitCommandEvent of the command execution to the sink.
+func emitCommandEvent(c *Command, e Event, outcome string, duration time.Duration) {
+	sink := cloudEventsSink
+	if sink == "" {
+		sink = os.Getenv("K_SINK")
+	}
+	if sink == "" {
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"command":          c.Name,
+		"outcome":          outcome,
+		"duration_seconds": duration.Seconds(),
+		"request_id":       e.String("request_id"),
+		"team":             e.String("team"),
+		"channel":          e.Channel(),
+		"user":             e.User(),
+	})
+	if err != nil {
+		logError(e, "cloudevents error", "error", err)
+		return
+	}
+
+	ce := &CloudEvent{
+		ID:              newRequestID(),
+		Source:          cloudEventsSource,
+		Type:            CloudEventTypeCommandExecuted,
+		Subject:         c.Name,
+		Time:            time.Now(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	// the request context is done after the response, so only its trace is propagated
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(e.Context()))
+	enqueueCloudEvent(&queuedCloudEvent{ctx: ctx, e: e.withoutContext(), sink: sink, ce: ce})
+}
+
+// enqueueCloudEvent to be sent in background. It is dropped if the queue is full.
+func enqueueCloudEvent(q *queuedCloudEvent) {
+	cloudEventsSender.Do(func() {
+		go sendCloudEvents()
+	})
+
+	addPendingCloudEvent(1)
+	select {
+	case cloudEventsQueue <- q:
+	default:
+		addPendingCloudEvent(-1)
+		logWarn(q.e, "cloudevents dropped", "subject", q.ce.Subject)
+	}
+}
+
+// sendCloudEvents in the queue one by one, each within the timeout of the client.
+func sendCloudEvents() {
+	for q := range cloudEventsQueue {
+		if err := SendCloudEvent(q.ctx, q.sink, q.ce); err != nil {
+			logError(q.e, "cloudevents error", "subject", q.ce.Subject, "error", err)
+		}
+		addPendingCloudEvent(-1)
+	}
+}
+
+// addPendingCloudEvent by delta, and notifies FlushCloudEvents if none is pending.
+func addPendingCloudEvent(delta int) {
+	cloudEventsMu.Lock()
+	defer cloudEventsMu.Unlock()
+	if cloudEventsPending == 0 {
+		cloudEventsIdle = make(chan struct{})
+	}
+	cloudEventsPending += delta
+	if cloudEventsPending == 0 {
+		close(cloudEventsIdle)
+	}
+}
+
+// FlushCloudEvents waits until the queued CloudEvents are sent or ctx is done,
+// for environments frozen after the response such as AWS Lambda.
+func FlushCloudEvents(ctx context.Context) error {
+	cloudEventsMu.Lock()
+	idle := cloudEventsIdle
+	pending := cloudEventsPending
+	cloudEventsMu.Unlock()
+	if pending == 0 {
+		return nil
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,285 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCloudEvent(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, nil)
+
+	testRun(t, "binary test", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"type":"event_callback"}`))
+		r.Header.Set("Ce-Specversion", "1.0")
+		r.Header.Set("Ce-Id", "1")
+		r.Header.Set("Ce-Source", "//slack")
+		r.Header.Set("Ce-Type", "com.slack.event")
+		r.Header.Set("Ce-Time", "2020-01-01T00:00:00Z")
+		r.Header.Set("Ce-Slacksignature", "v0=abc")
+		r.Header.Set("Content-Type", "application/json")
+
+		ce, err := ReadCloudEvent(r)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1", ce.ID)
+		assert.Equal(t, "//slack", ce.Source)
+		assert.Equal(t, "com.slack.event", ce.Type)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), ce.Time)
+		assert.Equal(t, "application/json", ce.DataContentType)
+		assert.Equal(t, `{"type":"event_callback"}`, string(ce.Data))
+		assert.Equal(t, map[string]string{"slacksignature": "v0=abc"}, ce.Extensions)
+	})
+
+	testRun(t, "structured test", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"specversion":"1.0","id":"1","source":"//slack","type":"com.slack.event","data":{"type":"event_callback"}}`))
+		r.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+
+		ce, err := ReadCloudEvent(r)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1", ce.ID)
+		assert.Equal(t, `{"type":"event_callback"}`, string(ce.Data))
+	})
+
+	testRun(t, "structured string data test", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"specversion":"1.0","id":"1","source":"//slack","type":"com.slack.command","datacontenttype":"application/x-www-form-urlencoded","data":"command=%2Fbot&text=ping"}`))
+		r.Header.Set("Content-Type", "application/cloudevents+json")
+
+		ce, err := ReadCloudEvent(r)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "command=%2Fbot&text=ping", string(ce.Data))
+	})
+
+	testRun(t, "structured base64 test", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"specversion":"1.0","id":"1","source":"//slack","type":"com.slack.event","data_base64":"eyJ0eXBlIjoiZXZlbnRfY2FsbGJhY2sifQ=="}`))
+		r.Header.Set("Content-Type", "application/cloudevents+json")
+
+		ce, err := ReadCloudEvent(r)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"type":"event_callback"}`, string(ce.Data))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		for _, body := range []string{`invalid`, `{"specversion":"0.3"}`, `{"specversion":"1.0","data_base64":"%"}`} {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/cloudevents+json")
+			_, err := ReadCloudEvent(r)
+			assert.Error(t, err, body)
+		}
+
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(`{}`))
+		_, err := ReadCloudEvent(r)
+		assert.Error(t, err)
+
+		r.Header.Set("Ce-Specversion", "0.3")
+		_, err = ReadCloudEvent(r)
+		assert.Error(t, err)
+	})
+}
+
+func TestOnCloudEvent(t *testing.T) {
+	var texts []string
+	clear := func() {
+		ToolsInitCommand()
+		Setup("bot", "token", "")
+		texts = []string{}
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				texts = append(texts, e.Text())
+			},
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		SetSigningSecret("")
+		ToolsInitCommand()
+	})
+
+	testRun(t, "binary test", func(t *testing.T) {
+		// the signature is forwarded in the headers
+		SetSigningSecret("secret")
+		body := `{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test binary"}}`
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+		for key, value := range ToolsSignLambdaHeaders(body, "secret") {
+			req.Header.Set(key, value)
+		}
+		req.Header.Set("Ce-Specversion", "1.0")
+		req.Header.Set("Ce-Id", "1")
+		req.Header.Set("Content-Type", "application/json")
+
+		OnCloudEvent(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"test binary"}, texts)
+	})
+
+	testRun(t, "structured test", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"specversion":"1.0","id":"1","source":"//slack","type":"com.slack.event","data":{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test structured"}}}`))
+		req.Header.Set("Content-Type", "application/cloudevents+json")
+
+		OnCloudEvent(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"test structured"}, texts)
+	})
+
+	testRun(t, "extension test", func(t *testing.T) {
+		// the signature is forwarded in the extensions
+		SetSigningSecret("secret")
+		data := `{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test extension"}}`
+		signed := ToolsSignLambdaHeaders(data, "secret")
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"specversion":"1.0","id":"1","source":"//slack","type":"com.slack.event",`+
+			`"slacksignature":"`+signed["x-slack-signature"]+`","slackrequesttimestamp":"`+signed["x-slack-request-timestamp"]+`","data":`+data+`}`))
+		req.Header.Set("Content-Type", "application/cloudevents+json")
+
+		OnCloudEvent(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"test extension"}, texts)
+	})
+
+	testRun(t, "unsigned test", func(t *testing.T) {
+		SetSigningSecret("secret")
+		Setup("bot", "", "")
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"type":"event_callback", "event":{"type":"app_mention", "text":"test"}}`))
+		req.Header.Set("Ce-Specversion", "1.0")
+		req.Header.Set("Ce-Id", "1")
+		req.Header.Set("Content-Type", "application/json")
+
+		OnCloudEvent(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, texts)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"type":"event_callback"}`))
+
+		OnCloudEvent(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestEmitCommandEvent(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	clear := func() {
+		ToolsInitCommand()
+		got, body = nil, nil
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		SetCloudEventsSink("")
+		SetCloudEventsSource("slackbot")
+		ToolsInitCommand()
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetCloudEventsSink(server.URL)
+		SetCloudEventsSource("//slackbot/test")
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {}})
+
+		onMessage(Event{"channel": "C1", "user": "U1", "team": "T1", "text": "test"})
+		assert.NoError(t, FlushCloudEvents(context.Background()))
+
+		assert.NotNil(t, got)
+		assert.Equal(t, "1.0", got.Header.Get("Ce-Specversion"))
+		assert.Equal(t, CloudEventTypeCommandExecuted, got.Header.Get("Ce-Type"))
+		assert.Equal(t, "//slackbot/test", got.Header.Get("Ce-Source"))
+		assert.Equal(t, "test", got.Header.Get("Ce-Subject"))
+		assert.NotEmpty(t, got.Header.Get("Ce-Id"))
+		assert.NotEmpty(t, got.Header.Get("Ce-Time"))
+		assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+
+		var data map[string]interface{}
+		json.Unmarshal(body, &data)
+		assert.Equal(t, "test", data["command"])
+		assert.Equal(t, "success", data["outcome"])
+		assert.Equal(t, "C1", data["channel"])
+		assert.Equal(t, "U1", data["user"])
+		assert.Equal(t, "T1", data["team"])
+	})
+
+	testRun(t, "k_sink test", func(t *testing.T) {
+		os.Setenv("K_SINK", server.URL)
+		defer os.Unsetenv("K_SINK")
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			panic("test")
+		}})
+
+		assert.Panics(t, func() {
+			onMessage(Event{"channel": "C1", "text": "test"})
+		})
+		assert.NoError(t, FlushCloudEvents(context.Background()))
+
+		var data map[string]interface{}
+		json.Unmarshal(body, &data)
+		assert.Equal(t, "panic", data["outcome"])
+	})
+
+	testRun(t, "no sink test", func(t *testing.T) {
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {}})
+
+		onMessage(Event{"channel": "C1", "text": "test"})
+		assert.NoError(t, FlushCloudEvents(context.Background()))
+
+		assert.Nil(t, got)
+	})
+
+	testRun(t, "slow sink test", func(t *testing.T) {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+		SetCloudEventsSink(slow.URL)
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {}})
+
+		// the command is not delayed by the sink
+		start := time.Now()
+		onMessage(Event{"channel": "C1", "text": "test"})
+		assert.True(t, time.Since(start) < time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, FlushCloudEvents(ctx))
+
+		close(release)
+		assert.NoError(t, FlushCloudEvents(context.Background()))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetCloudEventsSink("http://127.0.0.1:0")
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {}})
+
+		// the error is logged
+		assert.NotPanics(t, func() {
+			onMessage(Event{"channel": "C1", "text": "test"})
+		})
+		assert.NoError(t, FlushCloudEvents(context.Background()))
+		assert.Error(t, SendCloudEvent(Event{}.Context(), "http://127.0.0.1:0", &CloudEvent{}))
+	})
+}
@@ -1,20 +1,36 @@
 package slackbot
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Command for Slack ChatOps.
+// ExecuteContext is used instead of Execute if set.
+// If Confirm is set, the Command runs after the user clicks the Confirm button.
+// Hidden Command is not listed by help, but "help <command>" shows it.
+// Scopes are the OAuth scopes the Command needs, which are verified by SelfCheck.
 type Command struct {
-	Name        string
-	HelpMessage string
-	Execute     func(e Event, opt interface{})
-	Option      interface{}
+	Name           string
+	HelpMessage    string
+	Examples       []string
+	Category       string
+	Hidden         bool
+	Execute        func(e Event, opt interface{})
+	ExecuteContext func(ctx context.Context, e Event, opt interface{})
+	Option         interface{}
+	Confirm        bool
+	Scopes         []string
 }
 
 var (
@@ -39,13 +55,40 @@ func ClearCommand() {
 	commandKeys = []string{}
 }
 
+// findCommand by the name. Disabled commands are not found.
+func findCommand(name string) (*Command, bool) {
+	c, ok := commands[name]
+	if !ok || !commandEnabled(name) {
+		return nil, false
+	}
+	return c, true
+}
+
 func executeCommand(e Event, texts []string) bool {
-	if c, ok := commands[texts[0]]; ok {
+	if c, ok := findCommand(texts[0]); ok {
+		if !commandAllowed(c, e) {
+			metricCommands.inc(c.Name, "denied")
+			logInfo(e, "command not allowed", "command", c.Name)
+			PostEphemeral(e, T(e, MessageCommandNotAllowed, c.Name))
+			return true
+		}
+
+		_, span := startSpan(e.Context(), "slackbot.parse_option", trace.SpanKindInternal, attribute.String("slackbot.command", c.Name))
 		option, err := ParseOption(c, texts[1:])
-		if err != nil {
+		endSpan(span, err)
+		if _, ok := err.(*OptionError); ok {
+			metricOptionErrors.inc(c.Name)
+			metricCommands.inc(c.Name, "option_error")
+			logInfo(e, "option error", "command", c.Name, "options", strings.Join(texts[1:], " "))
+			ReplyMessage(e, T(e, MessageOptionError)+"\n"+localizedHelp(c, true, Locale(e)))
+		} else if err != nil {
+			metricCommands.inc(c.Name, "error")
 			ReplyMessage(e, err.Error())
+		} else if needsConfirm(c) {
+			metricCommands.inc(c.Name, "confirm")
+			requestConfirm(e, c, texts, option)
 		} else {
-			c.Execute(e, option)
+			runCommand(c, e, option)
 		}
 
 		return true
@@ -54,10 +97,55 @@ func executeCommand(e Event, texts []string) bool {
 	return false
 }
 
+func runCommand(c *Command, e Event, option interface{}) {
+	parent := e.Context()
+	ctx, span := startSpan(parent, "slackbot.command "+c.Name, trace.SpanKindInternal, attribute.String("slackbot.command", c.Name))
+	e.SetContext(ctx)
+
+	start := time.Now()
+	defer func() {
+		e.SetContext(parent)
+		metricCommandDuration.observe(time.Since(start), c.Name)
+		if r := recover(); r != nil {
+			metricCommands.inc(c.Name, "panic")
+			logError(e, "command panic", "command", c.Name, "panic", r)
+			endSpan(span, fmt.Errorf("panic: %v", r))
+			emitCommandEvent(c, e, "panic", time.Since(start))
+			panic(r)
+		}
+		span.End()
+		metricCommands.inc(c.Name, "success")
+		logDebug(e, "command executed", "command", c.Name, "duration", time.Since(start))
+		emitCommandEvent(c, e, "success", time.Since(start))
+	}()
+
+	if c.ExecuteContext != nil {
+		c.ExecuteContext(newContext(e), e, option)
+	} else {
+		c.Execute(e, option)
+	}
+}
+
 // AddCommand for slackbot.
+// The Command is not added if SetRequireScopes and its Scopes are not granted.
 func AddCommand(c *Command) {
+	if missing := missingScopes(c); len(missing) > 0 && refuseCommand(c, missing) {
+		return
+	}
+	if _, ok := commands[c.Name]; !ok {
+		commandKeys = append(commandKeys, c.Name)
+	}
 	commands[c.Name] = c
-	commandKeys = append(commandKeys, c.Name)
+}
+
+func removeCommand(name string) {
+	delete(commands, name)
+	for i, key := range commandKeys {
+		if key == name {
+			commandKeys = append(commandKeys[:i], commandKeys[i+1:]...)
+			break
+		}
+	}
 }
 
 // SetDefaultHelpDescription display.
@@ -71,8 +159,12 @@ func SetDefaultHelpDescription(description bool) {
 
 // Help message command.
 func Help(c *Command, desc bool) string {
+	return localizedHelp(c, desc, defaultLocale)
+}
+
+func localizedHelp(c *Command, desc bool, locale string) string {
 	name := c.Name
-	message := selectString(desc && c.HelpMessage != "", c.HelpMessage, "")
+	message := selectString(desc && c.HelpMessage != "", Translate(locale, c.HelpMessage), "")
 	message = italicString(message)
 	message = boldString(message)
 	message = selectString(message != "", " : "+message, "")
@@ -99,6 +191,99 @@ func Help(c *Command, desc bool) string {
 	return name + option + message
 }
 
+// HelpDetail message of the command with options and examples.
+func HelpDetail(c *Command) string {
+	return localizedHelpDetail(c, defaultLocale)
+}
+
+func localizedHelpDetail(c *Command, locale string) string {
+	detail := localizedHelp(c, false, locale)
+	if c.HelpMessage != "" {
+		detail += "\n" + Translate(locale, c.HelpMessage)
+	}
+
+	if c.Option != nil {
+		rt := reflect.TypeOf(c.Option)
+		if rt.NumField() > 0 {
+			detail += "\n" + boldString(Translate(locale, MessageHelpOptions))
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			defaultValue := f.Tag.Get("default")
+			value := boldSubstring(strings.Join(parseChoice(f), ","), defaultValue)
+			value = selectString(value != "", value, boldString(defaultValue))
+			detail += fmt.Sprintf("\n• %s%s", f.Name, addBrackets(value))
+			if help := f.Tag.Get("help"); help != "" {
+				detail += " : " + Translate(locale, help)
+			}
+		}
+	}
+
+	if len(c.Examples) > 0 {
+		detail += "\n" + boldString(Translate(locale, MessageHelpExamples))
+	}
+	for _, example := range c.Examples {
+		detail += "\n• `" + example + "`"
+	}
+
+	return detail
+}
+
+// helpLimit of a help message in characters. Long help is split into several messages.
+const helpLimit = 3000
+
+// helpMessages of the commands enabled and not hidden, grouped by Category.
+func helpMessages(desc bool, locale string) []string {
+	categories := []string{}
+	lines := map[string][]string{}
+	for _, key := range commandKeys {
+		c := commands[key]
+		if c.Hidden || !commandEnabled(key) {
+			continue
+		}
+		if _, ok := lines[c.Category]; !ok {
+			categories = append(categories, c.Category)
+		}
+		lines[c.Category] = append(lines[c.Category], localizedHelp(c, desc, locale))
+	}
+	// commands without Category come first
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i] == "" && categories[j] != ""
+	})
+
+	messages := []string{}
+	message := ""
+	add := func(line string) {
+		line = truncateString(line, helpLimit-1)
+		if message != "" && utf8.RuneCountInString(message)+utf8.RuneCountInString(line)+1 > helpLimit {
+			messages = append(messages, message)
+			message = ""
+		}
+		message += line + "\n"
+	}
+	for _, category := range categories {
+		if category != "" {
+			add(boldString(Translate(locale, category)))
+		}
+		for _, line := range lines[category] {
+			add(line)
+		}
+	}
+	if message != "" {
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// OptionError is returned by ParseOption if the options are invalid.
+type OptionError struct {
+	Command *Command
+}
+
+func (e *OptionError) Error() string {
+	return Translate(defaultLocale, MessageOptionError) + "\n" + Help(e.Command, true)
+}
+
 // ParseOption of the command.
 func ParseOption(c *Command, options []string) (interface{}, error) {
 	if c.Option == nil {
@@ -123,8 +308,7 @@ func ParseOption(c *Command, options []string) (interface{}, error) {
 		}
 
 		if value == "" {
-			fmt.Printf("%d\n", i)
-			return nil, errors.New("option error.\n" + Help(c, true))
+			return nil, &OptionError{Command: c}
 		}
 
 		setValue(rv.Field(i), value)
@@ -242,14 +426,22 @@ var helpCommand = &Command{
 	Name:        "help",
 	HelpMessage: "Displays all of the help commands.",
 
+	Examples: []string{"help", "help false", "help ping"},
+
 	Execute: func(e Event, opt interface{}) {
 		option := opt.(HelpCommandOption)
 
-		help := ""
-		for _, key := range commandKeys {
-			help += Help(commands[key], option.IsDescription() == "true") + "\n"
+		if o, ok := opt.(HelpCommandNameOption); ok && o.CommandName() != "" {
+			if c, ok := findCommand(o.CommandName()); ok {
+				PostEphemeral(e, localizedHelpDetail(c, Locale(e)))
+			} else {
+				PostEphemeral(e, T(e, MessageCommandNotFound, o.CommandName()))
+			}
+			return
+		}
+		for _, help := range helpMessages(option.IsDescription() == "true", Locale(e)) {
+			PostEphemeral(e, help)
 		}
-		PostEphemeral(e, help)
 	},
 	Option: HelpCommandOptionDesc{},
 }
@@ -259,24 +451,50 @@ type HelpCommandOption interface {
 	IsDescription() string
 }
 
+// HelpCommandNameOption is optionally implemented by HelpCommandOption.
+// CommandName returns the command name if the help of the command is requested.
+type HelpCommandNameOption interface {
+	CommandName() string
+}
+
 // HelpCommandOptionDesc is Help Command Option with default description enabled.
 type HelpCommandOptionDesc struct {
-	Description string `default:"true" choice:"false,true"`
+	Description string `default:"true" help:"true, false or the command name."`
 }
 
 // IsDescription check.
 func (o HelpCommandOptionDesc) IsDescription() string {
-	return o.Description
+	return helpDescription(o.Description, "true")
+}
+
+// CommandName of the help requested.
+func (o HelpCommandOptionDesc) CommandName() string {
+	return helpCommandName(o.Description)
 }
 
 // HelpCommandOptionSimple is Help Command Option with default description enabled.
 type HelpCommandOptionSimple struct {
-	Description string `default:"false" choice:"false,true"`
+	Description string `default:"false" help:"true, false or the command name."`
 }
 
 // IsDescription check.
 func (o HelpCommandOptionSimple) IsDescription() string {
-	return o.Description
+	return helpDescription(o.Description, "false")
+}
+
+// CommandName of the help requested.
+func (o HelpCommandOptionSimple) CommandName() string {
+	return helpCommandName(o.Description)
+}
+
+// helpDescription of "true" or "false", or the default if the argument is the command name.
+func helpDescription(arg, defaultValue string) string {
+	return selectString(arg == "true" || arg == "false", arg, defaultValue)
+}
+
+// helpCommandName of the argument, which is empty if it is "true" or "false".
+func helpCommandName(arg string) string {
+	return selectString(arg == "true" || arg == "false", "", arg)
 }
 
 // PingCommand
@@ -285,6 +503,6 @@ var pingCommand = &Command{
 	HelpMessage: "Reply pong.",
 
 	Execute: func(e Event, opt interface{}) {
-		ReplyMessage(e, "pong! :table_tennis_paddle_and_ball:")
+		ReplyMessage(e, T(e, MessagePong))
 	},
 }
@@ -1,8 +1,12 @@
 package slackbot
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -57,6 +61,22 @@ func TestExecuteCommand(t *testing.T) {
 		assert.True(t, called)
 	})
 
+	testRun(t, "execute context test", func(t *testing.T) {
+		var store Store
+		AddCommand(&Command{
+			Name: "test",
+			ExecuteContext: func(ctx context.Context, e Event, opt interface{}) {
+				store = StoreFromContext(ctx)
+			},
+		})
+
+		texts := []string{"test"}
+		result := executeCommand(Event{}, texts)
+
+		assert.True(t, result)
+		assert.Equal(t, GetStore(), store)
+	})
+
 	testRun(t, "parse option error test", func(t *testing.T) {
 		called := false
 		AddCommand(&Command{
@@ -93,6 +113,14 @@ func TestAddCommand(t *testing.T) {
 		assert.Len(t, commands, 1)
 		assert.Equal(t, "test", commands["test"].Name)
 	})
+
+	testRun(t, "overwrite test", func(t *testing.T) {
+		AddCommand(&Command{Name: "test"})
+		AddCommand(&Command{Name: "test", HelpMessage: "message"})
+		assert.Len(t, commands, 1)
+		assert.Len(t, commandKeys, 1)
+		assert.Equal(t, "message", commands["test"].HelpMessage)
+	})
 }
 
 func TestSetDefaultHelpDescription(t *testing.T) {
@@ -157,6 +185,78 @@ func TestHelp(t *testing.T) {
 	})
 }
 
+func TestHelpDetail(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no option test", func(t *testing.T) {
+		command := &Command{Name: "test"}
+		assert.Equal(t, "test", HelpDetail(command))
+	})
+
+	t.Run("normal test", func(t *testing.T) {
+		command := &Command{
+			Name:        "deploy",
+			HelpMessage: "Deploy the service.",
+			Examples:    []string{"deploy api prod"},
+			Option: struct {
+				Service string `help:"Name of the service."`
+				Env     string `default:"dev" choice:"dev,prod" help:"Environment."`
+				Count   int    `default:"1" max:"3"`
+			}{},
+		}
+		expect := "deploy [Service] [Env(*dev*)] [Count(*1*)]\n" +
+			"Deploy the service.\n" +
+			"*Options*\n" +
+			"• Service : Name of the service.\n" +
+			"• Env(*dev*,prod) : Environment.\n" +
+			"• Count(*1*,min:-2147483648,max:3)\n" +
+			"*Examples*\n" +
+			"• `deploy api prod`"
+		assert.Equal(t, expect, HelpDetail(command))
+	})
+}
+
+func TestHelpMessages(t *testing.T) {
+	testRun := ToolsCreateTestRun(ClearCommand, ToolsInitCommand)
+
+	testRun(t, "category test", func(t *testing.T) {
+		AddCommand(&Command{Name: "deploy", Category: "Ops"})
+		AddCommand(&Command{Name: "ping"})
+		AddCommand(&Command{Name: "secret", Hidden: true})
+		AddCommand(&Command{Name: "rollback", Category: "Ops"})
+
+		assert.Equal(t, []string{"ping\n*Ops*\ndeploy\nrollback\n"}, helpMessages(false, "en"))
+	})
+
+	testRun(t, "split test", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			AddCommand(&Command{Name: fmt.Sprintf("command%02d", i), HelpMessage: strings.Repeat("a", 50)})
+		}
+
+		messages := helpMessages(true, "en")
+		assert.Len(t, messages, 3)
+		for _, message := range messages {
+			assert.True(t, len(message) <= helpLimit)
+		}
+		assert.Equal(t, 100, strings.Count(strings.Join(messages, ""), "\n"))
+	})
+
+	testRun(t, "multibyte split test", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			AddCommand(&Command{Name: fmt.Sprintf("command%02d", i), HelpMessage: strings.Repeat("あ", 50)})
+		}
+		AddCommand(&Command{Name: "long", HelpMessage: strings.Repeat("あ", helpLimit)})
+
+		messages := helpMessages(true, "en")
+		assert.Len(t, messages, 4)
+		for _, message := range messages {
+			assert.True(t, utf8.RuneCountInString(message) <= helpLimit)
+			assert.True(t, utf8.ValidString(message))
+		}
+		assert.Equal(t, 101, strings.Count(strings.Join(messages, ""), "\n"))
+	})
+}
+
 func TestParseOption(t *testing.T) {
 	testRun := ToolsCreateTestRun(ToolsInitCommand, ToolsInitCommand)
 
@@ -374,6 +474,19 @@ func TestHelpCommand_Execute(t *testing.T) {
 	testRun(t, "normal test", func(t *testing.T) {
 		helpCommand.Execute(Event{}, HelpCommandOptionDesc{})
 	})
+
+	testRun(t, "command test", func(t *testing.T) {
+		fake := ToolsStartFakeSlack()
+		defer ToolsStopFakeSlack(fake)
+		Setup("bot", "", "")
+
+		helpCommand.Execute(Event{"channel": "C1", "user": "U1"}, HelpCommandOptionDesc{Description: "ping"})
+		assert.Equal(t, "ping\nReply pong.", fake.CallsFor("chat.postEphemeral")[0].Param("text"))
+
+		fake.Reset()
+		helpCommand.Execute(Event{"channel": "C1", "user": "U1"}, HelpCommandOptionDesc{Description: "undefined"})
+		assert.Equal(t, "command not found: undefined", fake.CallsFor("chat.postEphemeral")[0].Param("text"))
+	})
 }
 
 func TestHelpCommandOptionDesc_IsDescription(t *testing.T) {
@@ -385,6 +498,19 @@ func TestHelpCommandOptionDesc_IsDescription(t *testing.T) {
 	})
 }
 
+func TestHelpCommandOptionDesc_CommandName(t *testing.T) {
+	SetDefaultHelpDescription(true)
+	t.Run("normal test", func(t *testing.T) {
+		option, _ := ParseOption(helpCommand, []string{"ping"})
+		assert.Equal(t, "ping", option.(HelpCommandNameOption).CommandName())
+		assert.Equal(t, "true", option.(HelpCommandOption).IsDescription())
+
+		option, _ = ParseOption(helpCommand, []string{"false"})
+		assert.Equal(t, "", option.(HelpCommandNameOption).CommandName())
+		assert.Equal(t, "false", option.(HelpCommandOption).IsDescription())
+	})
+}
+
 func TestHelpCommandOptionSimple_IsDescription(t *testing.T) {
 	SetDefaultHelpDescription(false)
 	t.Run("normal test", func(t *testing.T) {
@@ -2,6 +2,9 @@ package slackbot
 
 import (
 	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/peto-tn/slackbot-go/fakeslack"
 )
 
 func ToolsCreateTestRun(setup, tearDown func()) func(t *testing.T, testName string, f func(t *testing.T)) {
@@ -19,3 +22,14 @@ func ToolsCreateTestRun(setup, tearDown func()) func(t *testing.T, testName stri
 		})
 	}
 }
+
+func ToolsStartFakeSlack() *fakeslack.Server {
+	s := fakeslack.NewServer()
+	SetAPIURL(s.URL())
+	return s
+}
+
+func ToolsStopFakeSlack(s *fakeslack.Server) {
+	s.Close()
+	SetAPIURL(slack.APIURL)
+}
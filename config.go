@@ -0,0 +1,323 @@
+package slackbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv is the environment variable of the config file path loaded on the first request.
+const ConfigEnv = "SLACKBOT_CONFIG"
+
+// Config of slackbot loaded by LoadConfig.
+type Config struct {
+	Slack    SlackConfig    `json:"slack"`
+	Commands CommandsConfig `json:"commands"`
+
+	// Channels where the bot handles events. All the channels are allowed if empty.
+	Channels []string `json:"channels"`
+
+	Worker WorkerConfig `json:"worker"`
+	Log    LogConfig    `json:"log"`
+}
+
+// SlackConfig of the credentials. Each secret is read from the file of the "_file" key if it is empty.
+type SlackConfig struct {
+	BotUserID             string `json:"bot_user_id"`
+	VerificationToken     string `json:"verification_token"`
+	VerificationTokenFile string `json:"verification_token_file"`
+	AccessToken           string `json:"access_token"`
+	AccessTokenFile       string `json:"access_token_file"`
+	SigningSecret         string `json:"signing_secret"`
+	SigningSecretFile     string `json:"signing_secret_file"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenFile      string `json:"refresh_token_file"`
+	ClientID              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret"`
+	ClientSecretFile      string `json:"client_secret_file"`
+}
+
+// CommandsConfig of enabling the commands and restricting them by name.
+type CommandsConfig struct {
+	Enabled       []string              `json:"enabled"`
+	Disabled      []string              `json:"disabled"`
+	ACL           map[string]CommandACL `json:"acl"`
+	RequireScopes bool                  `json:"require_scopes"`
+}
+
+// WorkerConfig of the MemoryDispatcher run by Server. Events are handled inline if Concurrency is zero.
+// It is only applied by Server, and the config of ConfigEnv with it is rejected by the other handlers.
+type WorkerConfig struct {
+	Concurrency int `json:"concurrency"`
+	MaxQueue    int `json:"max_queue"`
+}
+
+var (
+	// workerConfig of SetupConfig applied by Server.
+	workerConfig WorkerConfig
+)
+
+// LogConfig of the log level: debug, info, warn or error.
+type LogConfig struct {
+	Level string `json:"level"`
+}
+
+// ConfigError has all the invalid values of the Config.
+type ConfigError struct {
+	Errors []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid config: " + strings.Join(e.Errors, "; ")
+}
+
+var (
+	userIDRegexp    = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+	channelIDRegexp = regexp.MustCompile(`^[CGD][A-Z0-9]+$`)
+)
+
+// configEnvs override the values of the config file.
+// The value is read from the file of "<name>_FILE" if the variable is not set.
+var configEnvs = []struct {
+	name  string
+	value func(c *Config) *string
+}{
+	{"SLACK_BOT_USER_ID", func(c *Config) *string { return &c.Slack.BotUserID }},
+	{"SLACK_VERIFICATION_TOKEN", func(c *Config) *string { return &c.Slack.VerificationToken }},
+	{"SLACK_ACCESS_TOKEN", func(c *Config) *string { return &c.Slack.AccessToken }},
+	{"SLACK_SIGNING_SECRET", func(c *Config) *string { return &c.Slack.SigningSecret }},
+	{"SLACK_REFRESH_TOKEN", func(c *Config) *string { return &c.Slack.RefreshToken }},
+	{"SLACK_CLIENT_ID", func(c *Config) *string { return &c.Slack.ClientID }},
+	{"SLACK_CLIENT_SECRET", func(c *Config) *string { return &c.Slack.ClientSecret }},
+	{"SLACKBOT_LOG_LEVEL", func(c *Config) *string { return &c.Log.Level }},
+}
+
+// LoadConfig from the YAML, TOML or JSON file by the extension, overridden by the environment variables.
+// Only the environment variables are loaded if path is empty. Returns ConfigError if the Config is invalid.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeConfig(path, data, c); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	secrets := []struct {
+		key         string
+		value, file *string
+	}{
+		{"slack.verification_token_file", &c.Slack.VerificationToken, &c.Slack.VerificationTokenFile},
+		{"slack.access_token_file", &c.Slack.AccessToken, &c.Slack.AccessTokenFile},
+		{"slack.signing_secret_file", &c.Slack.SigningSecret, &c.Slack.SigningSecretFile},
+		{"slack.refresh_token_file", &c.Slack.RefreshToken, &c.Slack.RefreshTokenFile},
+		{"slack.client_secret_file", &c.Slack.ClientSecret, &c.Slack.ClientSecretFile},
+	}
+	for _, s := range secrets {
+		if *s.value == "" && *s.file != "" {
+			value, err := readSecretFile(*s.file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", s.key, err)
+			}
+			*s.value = value
+		}
+	}
+
+	for _, env := range configEnvs {
+		if value := os.Getenv(env.name); value != "" {
+			*env.value(c) = value
+		} else if file := os.Getenv(env.name + "_FILE"); file != "" {
+			value, err := readSecretFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s_FILE: %v", env.name, err)
+			}
+			*env.value(c) = value
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// decodeConfig through JSON, so that all the formats share the json tags and reject unknown keys.
+func decodeConfig(path string, data []byte, c *Config) error {
+	var raw interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return err
+		}
+	case ".json":
+		raw = json.RawMessage(data)
+	default:
+		return fmt.Errorf("unsupported format: %q", ext)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// readSecretFile mounted by Docker or Kubernetes secrets. The trailing newline is trimmed.
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Validate the Config. Returns ConfigError with all the invalid values.
+func (c *Config) Validate() error {
+	errs := []string{}
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if c.Slack.RefreshToken != "" {
+		if c.Slack.ClientID == "" {
+			add("slack.client_id is required for slack.refresh_token")
+		}
+		if c.Slack.ClientSecret == "" {
+			add("slack.client_secret is required for slack.refresh_token")
+		}
+	}
+
+	for i, name := range c.Commands.Enabled {
+		if containsString(c.Commands.Disabled, name) {
+			add("commands.enabled[%d]: %q is also disabled", i, name)
+		}
+	}
+	names := []string{}
+	for name := range c.Commands.ACL {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		acl := c.Commands.ACL[name]
+		for i, user := range acl.Users {
+			if !userIDRegexp.MatchString(user) {
+				add("commands.acl.%s.users[%d]: invalid user ID %q", name, i, user)
+			}
+		}
+		for i, channel := range acl.Channels {
+			if !channelIDRegexp.MatchString(channel) {
+				add("commands.acl.%s.channels[%d]: invalid channel ID %q", name, i, channel)
+			}
+		}
+	}
+	for i, channel := range c.Channels {
+		if !channelIDRegexp.MatchString(channel) {
+			add("channels[%d]: invalid channel ID %q", i, channel)
+		}
+	}
+
+	if c.Worker.Concurrency < 0 {
+		add("worker.concurrency: must not be negative")
+	}
+	if c.Worker.MaxQueue < 0 {
+		add("worker.max_queue: must not be negative")
+	}
+	if _, ok := parseLogLevel(c.Log.Level); !ok {
+		add("log.level: invalid level %q", c.Log.Level)
+	}
+
+	if len(errs) > 0 {
+		return &ConfigError{Errors: errs}
+	}
+	return nil
+}
+
+// parseLogLevel of the name. Empty is info.
+func parseLogLevel(name string) (LogLevel, bool) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LogLevelDebug, true
+	case "", "info":
+		return LogLevelInfo, true
+	case "warn", "warning":
+		return LogLevelWarn, true
+	case "error":
+		return LogLevelError, true
+	}
+	return LogLevelInfo, false
+}
+
+// SetupConfig of slackbot. The values not set in the Config are left as they are.
+func SetupConfig(c *Config) {
+	if c.Slack.SigningSecret != "" {
+		SetSigningSecret(c.Slack.SigningSecret)
+	}
+	if c.Slack.RefreshToken != "" {
+		tokenRotation = &TokenRotation{
+			ClientID:     c.Slack.ClientID,
+			ClientSecret: c.Slack.ClientSecret,
+			RefreshToken: c.Slack.RefreshToken,
+		}
+	}
+	if c.Log.Level != "" {
+		level, _ := parseLogLevel(c.Log.Level)
+		SetLogLevel(level)
+	}
+
+	if len(c.Commands.Enabled) > 0 {
+		EnableCommands(c.Commands.Enabled...)
+	}
+	if len(c.Commands.Disabled) > 0 {
+		DisableCommands(c.Commands.Disabled...)
+	}
+	for name, acl := range c.Commands.ACL {
+		SetCommandACL(name, acl)
+	}
+	if c.Commands.RequireScopes {
+		SetRequireScopes(true)
+	}
+	if len(c.Channels) > 0 {
+		SetAllowedChannels(c.Channels...)
+	}
+
+	workerConfig = c.Worker
+
+	Setup(c.Slack.BotUserID, c.Slack.VerificationToken, c.Slack.AccessToken)
+	warnUnknownCommands(c.Commands)
+}
+
+// warnUnknownCommands named in the config, such as a typo in enabled disabling every command.
+func warnUnknownCommands(c CommandsConfig) {
+	names := append(append([]string{}, c.Enabled...), c.Disabled...)
+	acl := []string{}
+	for name := range c.ACL {
+		acl = append(acl, name)
+	}
+	sort.Strings(acl)
+	for _, name := range append(names, acl...) {
+		if _, ok := commands[name]; !ok {
+			logWarn(nil, "unknown command in config", "command", name)
+		}
+	}
+}
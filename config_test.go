@@ -0,0 +1,271 @@
+package slackbot
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ToolsWriteConfig(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "config")
+	defer os.RemoveAll(dir)
+
+	clear := func() {
+		for _, env := range configEnvs {
+			os.Unsetenv(env.name)
+			os.Unsetenv(env.name + "_FILE")
+		}
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "yaml test", func(t *testing.T) {
+		path := ToolsWriteConfig(t, dir, "slackbot.yaml", `
+slack:
+  bot_user_id: UBOT
+  access_token: xoxb-yaml
+commands:
+  disabled: [deploy]
+  require_scopes: true
+  acl:
+    status:
+      users: [U1]
+      channels: [C1]
+channels: [C1, C2]
+worker:
+  concurrency: 4
+  max_queue: 100
+log:
+  level: debug
+`)
+		c, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "UBOT", c.Slack.BotUserID)
+		assert.Equal(t, "xoxb-yaml", c.Slack.AccessToken)
+		assert.Equal(t, []string{"deploy"}, c.Commands.Disabled)
+		assert.True(t, c.Commands.RequireScopes)
+		assert.Equal(t, CommandACL{Users: []string{"U1"}, Channels: []string{"C1"}}, c.Commands.ACL["status"])
+		assert.Equal(t, []string{"C1", "C2"}, c.Channels)
+		assert.Equal(t, WorkerConfig{Concurrency: 4, MaxQueue: 100}, c.Worker)
+		assert.Equal(t, "debug", c.Log.Level)
+	})
+
+	testRun(t, "toml test", func(t *testing.T) {
+		path := ToolsWriteConfig(t, dir, "slackbot.toml", `
+channels = ["C1"]
+
+[slack]
+access_token = "xoxb-toml"
+
+[commands.acl.deploy]
+users = ["U1"]
+
+[worker]
+concurrency = 2
+`)
+		c, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-toml", c.Slack.AccessToken)
+		assert.Equal(t, []string{"U1"}, c.Commands.ACL["deploy"].Users)
+		assert.Equal(t, 2, c.Worker.Concurrency)
+	})
+
+	testRun(t, "json test", func(t *testing.T) {
+		path := ToolsWriteConfig(t, dir, "slackbot.json", `{"slack": {"access_token": "xoxb-json"}, "commands": {"enabled": ["help"]}}`)
+		c, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-json", c.Slack.AccessToken)
+		assert.Equal(t, []string{"help"}, c.Commands.Enabled)
+	})
+
+	testRun(t, "file test", func(t *testing.T) {
+		secret := ToolsWriteConfig(t, dir, "access_token", "xoxb-file\n")
+		path := ToolsWriteConfig(t, dir, "file.yaml", "slack:\n  access_token_file: "+secret+"\n")
+		c, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-file", c.Slack.AccessToken)
+
+		os.Setenv("SLACK_SIGNING_SECRET_FILE", ToolsWriteConfig(t, dir, "signing_secret", "secret\n"))
+		c, err = LoadConfig("")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", c.Slack.SigningSecret)
+	})
+
+	testRun(t, "env test", func(t *testing.T) {
+		path := ToolsWriteConfig(t, dir, "env.yaml", "slack:\n  access_token: xoxb-yaml\n  bot_user_id: UBOT\nlog:\n  level: debug\n")
+		os.Setenv("SLACK_ACCESS_TOKEN", "xoxb-env")
+		os.Setenv("SLACKBOT_LOG_LEVEL", "warn")
+
+		c, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-env", c.Slack.AccessToken)
+		assert.Equal(t, "UBOT", c.Slack.BotUserID)
+		assert.Equal(t, "warn", c.Log.Level)
+	})
+
+	testRun(t, "validation error test", func(t *testing.T) {
+		path := ToolsWriteConfig(t, dir, "invalid.yaml", `
+slack:
+  refresh_token: xoxe-1
+commands:
+  enabled: [deploy]
+  disabled: [deploy]
+  acl:
+    deploy:
+      users: [alice]
+      channels: ["#general"]
+channels: [general]
+worker:
+  concurrency: -1
+log:
+  level: verbose
+`)
+		_, err := LoadConfig(path)
+		if assert.IsType(t, &ConfigError{}, err) {
+			assert.Equal(t, []string{
+				"slack.client_id is required for slack.refresh_token",
+				"slack.client_secret is required for slack.refresh_token",
+				`commands.enabled[0]: "deploy" is also disabled`,
+				`commands.acl.deploy.users[0]: invalid user ID "alice"`,
+				`commands.acl.deploy.channels[0]: invalid channel ID "#general"`,
+				`channels[0]: invalid channel ID "general"`,
+				"worker.concurrency: must not be negative",
+				`log.level: invalid level "verbose"`,
+			}, err.(*ConfigError).Errors)
+		}
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(dir, "none.yaml"))
+		assert.Error(t, err)
+
+		path := ToolsWriteConfig(t, dir, "unknown.yaml", "slack:\n  acces_token: xoxb-typo\n")
+		_, err = LoadConfig(path)
+		assert.EqualError(t, err, path+`: unknown field "acces_token"`)
+
+		path = ToolsWriteConfig(t, dir, "slackbot.ini", "")
+		_, err = LoadConfig(path)
+		assert.EqualError(t, err, path+`: unsupported format: ".ini"`)
+
+		path = ToolsWriteConfig(t, dir, "missing.yaml", "slack:\n  access_token_file: "+filepath.Join(dir, "none")+"\n")
+		_, err = LoadConfig(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestSetupConfig(t *testing.T) {
+	clear := func() {
+		ToolsClearACL()
+		ToolsInitCommand()
+		SetDispatcher(nil)
+		workerConfig = WorkerConfig{}
+		SetLogLevel(LogLevelInfo)
+		tokenRotation = nil
+		api = nil
+		accessToken = ""
+		slackBotUserID = ""
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetupConfig(&Config{
+			Slack:    SlackConfig{BotUserID: "UBOT", AccessToken: "xoxb-1"},
+			Commands: CommandsConfig{Disabled: []string{"ping"}, ACL: map[string]CommandACL{"help": {Users: []string{"U1"}}}},
+			Channels: []string{"C1"},
+			Worker:   WorkerConfig{Concurrency: 4, MaxQueue: 10},
+			Log:      LogConfig{Level: "error"},
+		})
+
+		assert.Equal(t, "UBOT", slackBotUserID)
+		assert.Equal(t, "xoxb-1", accessToken)
+		assert.False(t, commandEnabled("ping"))
+		assert.Equal(t, []string{"U1"}, commandACLs["help"].Users)
+		assert.False(t, channelAllowed("C2"))
+		assert.Equal(t, LogLevelError, logLevel)
+
+		// applied by Server
+		assert.Equal(t, WorkerConfig{Concurrency: 4, MaxQueue: 10}, workerConfig)
+		assert.Nil(t, dispatcher)
+	})
+
+	testRun(t, "env test", func(t *testing.T) {
+		path := filepath.Join(os.TempDir(), "slackbot-invalid.yaml")
+		ioutil.WriteFile(path, []byte("log:\n  level: verbose\n"), 0600)
+		defer os.Remove(path)
+		os.Setenv(ConfigEnv, path)
+		defer os.Unsetenv(ConfigEnv)
+
+		assert.Error(t, setupFromEnv())
+		assert.Nil(t, api)
+
+		// the request fails without panic
+		rec := httptest.NewRecorder()
+		OnCall(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"type":"url_verification", "challenge":"test"}`)))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	testRun(t, "worker test", func(t *testing.T) {
+		fake := ToolsStartFakeSlack()
+		defer ToolsStopFakeSlack(fake)
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			ReplyMessage(e, "done")
+		}})
+		call := func() int {
+			rec := httptest.NewRecorder()
+			OnCall(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "channel":"C1", "text":"test"}}`)))
+			return rec.Code
+		}
+		path := filepath.Join(os.TempDir(), "slackbot-worker.yaml")
+		ioutil.WriteFile(path, []byte("slack:\n  bot_user_id: UBOT\n  verification_token: token\nworker:\n  concurrency: 2\n"), 0600)
+		defer os.Remove(path)
+		os.Setenv(ConfigEnv, path)
+		defer os.Unsetenv(ConfigEnv)
+		token := os.Getenv("SLACK_VERIFICATION_TOKEN")
+		os.Unsetenv("SLACK_VERIFICATION_TOKEN")
+		defer os.Setenv("SLACK_VERIFICATION_TOKEN", token)
+		defer func() {
+			verificationToken = ""
+		}()
+
+		// rejected without Server running the workers
+		assert.Equal(t, http.StatusInternalServerError, call())
+		assert.Nil(t, dispatcher)
+		assert.Len(t, fake.CallsFor("chat.postMessage"), 0)
+
+		// handled inline if set up by SetupConfig
+		SetupConfig(&Config{Slack: SlackConfig{BotUserID: "UBOT", VerificationToken: "token"}, Worker: WorkerConfig{Concurrency: 2}})
+		assert.Equal(t, http.StatusOK, call())
+		assert.Nil(t, dispatcher)
+		assert.Len(t, fake.CallsFor("chat.postMessage"), 1)
+	})
+
+	testRun(t, "unknown command test", func(t *testing.T) {
+		l := ToolsSetTestLogger()
+		defer ToolsResetLogger()
+
+		SetupConfig(&Config{Commands: CommandsConfig{
+			Enabled:  []string{"help", "hlep"},
+			Disabled: []string{"ping"},
+			ACL:      map[string]CommandACL{"deploy": {}},
+		}})
+
+		warned := []interface{}{}
+		for _, log := range l.Logs {
+			if log.Msg == "unknown command in config" {
+				warned = append(warned, log.Keyvals[1])
+			}
+		}
+		assert.Equal(t, []interface{}{"hlep", "deploy"}, warned)
+	})
+}
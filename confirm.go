@@ -0,0 +1,264 @@
+package slackbot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+const (
+	confirmNamespace = "confirm"
+
+	// ConfirmActionID of the Confirm button.
+	ConfirmActionID = "slackbot_confirm"
+	// CancelActionID of the Cancel button.
+	CancelActionID = "slackbot_cancel"
+)
+
+var (
+	confirmTimeout = 5 * time.Minute
+)
+
+// SetConfirmTimeout for slackbot.
+func SetConfirmTimeout(timeout time.Duration) {
+	confirmTimeout = timeout
+}
+
+// confirmation of the Command waiting for the user.
+type confirmation struct {
+	ID        string    `json:"id"`
+	Texts     []string  `json:"texts"`
+	Event     Event     `json:"event"`
+	Channel   string    `json:"channel"`
+	Timestamp string    `json:"ts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// needsConfirm if Confirm is set or a field of Option has `confirm:"true"` tag.
+func needsConfirm(c *Command) bool {
+	if c.Confirm {
+		return true
+	}
+	if c.Option == nil {
+		return false
+	}
+	rt := reflect.TypeOf(c.Option)
+	for i := 0; i < rt.NumField(); i++ {
+		if rt.Field(i).Tag.Get("confirm") == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmSummary of the Command and parsed Option.
+func confirmSummary(c *Command, option interface{}, locale string) string {
+	summary := Translate(locale, MessageConfirmSummary, c.Name)
+	if option == nil {
+		return summary
+	}
+	rv := reflect.ValueOf(option)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		summary += fmt.Sprintf("\n• %s: `%v`", rt.Field(i).Name, rv.Field(i).Interface())
+	}
+	return summary
+}
+
+// requestConfirm posts the Confirm and Cancel buttons. The Command runs after the user clicks Confirm.
+func requestConfirm(e Event, c *Command, texts []string, option interface{}) {
+	id, err := newConfirmID()
+	if err != nil {
+		ReplyMessage(e, "error: "+err.Error())
+		return
+	}
+
+	locale := Locale(e)
+	summary := confirmSummary(c, option, locale)
+	channel, ts, err := postMessage(
+		e.Context(),
+		e.Channel(),
+		slack.MsgOptionTS(e.ThreadTimestamp()),
+		slack.MsgOptionText(summary, false),
+		slack.MsgOptionBlocks(confirmBlocks(id, summary, locale)...),
+	)
+	if err != nil {
+		logError(e, "confirm error", "command", c.Name, "error", err)
+		return
+	}
+
+	cf := &confirmation{
+		ID:        id,
+		Texts:     texts,
+		Event:     e.withoutContext(),
+		Channel:   channel,
+		Timestamp: ts,
+		ExpiresAt: time.Now().Add(confirmTimeout),
+	}
+	data, err := json.Marshal(cf)
+	if err != nil {
+		logError(e, "confirm error", "command", c.Name, "error", err)
+		return
+	}
+	// keep after the timeout to tell the expiration to the user
+	if err := GetStore().Set(confirmNamespace, id, data, confirmTimeout+time.Hour); err != nil {
+		logError(e, "confirm error", "command", c.Name, "error", err)
+		return
+	}
+
+	time.AfterFunc(confirmTimeout, func() {
+		expireConfirm(id)
+	})
+}
+
+func confirmBlocks(id, summary, locale string) []slack.Block {
+	confirm := slack.NewButtonBlockElement(ConfirmActionID, id, slack.NewTextBlockObject(slack.PlainTextType, Translate(locale, MessageConfirmButton), false, false))
+	confirm.WithStyle(slack.StyleDanger)
+	cancel := slack.NewButtonBlockElement(CancelActionID, id, slack.NewTextBlockObject(slack.PlainTextType, Translate(locale, MessageCancelButton), false, false))
+
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, summary, false, false), nil, nil),
+		slack.NewActionBlock(confirmNamespace+":"+id, confirm, cancel),
+	}
+}
+
+func newConfirmID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// onConfirmAction handles the click of the Confirm or Cancel button. Returns false if not the button.
+func onConfirmAction(p Payload) bool {
+	if p.Type() != "block_actions" {
+		return false
+	}
+	for _, action := range p.Actions() {
+		switch action.String("action_id") {
+		case ConfirmActionID:
+			resolveConfirm(p, action.String("value"), true)
+			return true
+		case CancelActionID:
+			resolveConfirm(p, action.String("value"), false)
+			return true
+		}
+	}
+	return false
+}
+
+func resolveConfirm(p Payload, id string, confirmed bool) {
+	user := p.Object("user").String("id")
+	channel := p.Object("channel").String("id")
+	ts := p.Object("message").String("ts")
+	e := Event{
+		"request_id": p.String("request_id"),
+		"team":       p.Object("team").String("id"),
+		"channel":    channel,
+		"user":       user,
+	}
+	e.SetContext(p.Context())
+
+	data, err := GetStore().Get(confirmNamespace, id)
+	if err == ErrNotFound {
+		updateConfirm(e.Context(), channel, ts, T(e, MessageConfirmNotFound))
+		return
+	} else if err != nil {
+		logError(e, "confirm error", "confirmation", id, "error", err)
+		return
+	}
+	if len(data) == 0 {
+		// already resolved
+		return
+	}
+	cf := &confirmation{}
+	if err := json.Unmarshal(data, cf); err != nil {
+		logError(e, "confirm error", "confirmation", id, "error", err)
+		return
+	}
+
+	if user != cf.Event.User() {
+		PostEphemeral(e, T(cf.Event, MessageConfirmNotPermitted, cf.Event.User()))
+		return
+	}
+	if time.Now().After(cf.ExpiresAt) {
+		if claimConfirm(e, id, data) {
+			updateConfirm(e.Context(), cf.Channel, cf.Timestamp, T(cf.Event, MessageConfirmExpired, cf.Texts[0]))
+		}
+		return
+	}
+	if !claimConfirm(e, id, data) {
+		return
+	}
+
+	if !confirmed {
+		updateConfirm(e.Context(), cf.Channel, cf.Timestamp, T(cf.Event, MessageConfirmCanceled, cf.Texts[0], user))
+		return
+	}
+	updateConfirm(e.Context(), cf.Channel, cf.Timestamp, T(cf.Event, MessageConfirmConfirmed, cf.Texts[0], user))
+
+	cf.Event.SetContext(e.Context())
+	c, ok := findCommand(cf.Texts[0])
+	if !ok {
+		logWarn(e, "confirm command not found", "command", cf.Texts[0])
+		return
+	}
+	option, err := ParseOption(c, cf.Texts[1:])
+	if err != nil {
+		ReplyMessage(cf.Event, err.Error())
+		return
+	}
+	runCommand(c, cf.Event, option)
+}
+
+// expireConfirm updates the message if the confirmation is still waiting.
+func expireConfirm(id string) {
+	data, err := GetStore().Get(confirmNamespace, id)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	cf := &confirmation{}
+	if err := json.Unmarshal(data, cf); err != nil {
+		logError(nil, "confirm error", "confirmation", id, "error", err)
+		return
+	}
+	if cf.Event == nil {
+		cf.Event = Event{}
+	}
+	if claimConfirm(cf.Event, id, data) {
+		// updated with the client of the installation, as the schedules
+		routeEvent(cf.Event)
+		updateConfirm(cf.Event.Context(), cf.Channel, cf.Timestamp, T(cf.Event, MessageConfirmExpired, cf.Texts[0]))
+	}
+}
+
+// claimConfirm to resolve the confirmation only once.
+func claimConfirm(e Event, id string, data []byte) bool {
+	ok, err := GetStore().CompareAndSwap(confirmNamespace, id, data, []byte{}, time.Hour)
+	if err != nil {
+		logError(e, "confirm error", "confirmation", id, "error", err)
+		return false
+	}
+	return ok
+}
+
+// updateConfirm message to the result without buttons.
+func updateConfirm(ctx context.Context, channel, ts, text string) {
+	if channel == "" || ts == "" {
+		return
+	}
+	updateMessage(
+		ctx,
+		channel,
+		ts,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)),
+	)
+}
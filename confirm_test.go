@@ -0,0 +1,162 @@
+package slackbot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ConfirmTestOption struct {
+	Env string `default:"dev" choice:"dev,prod"`
+}
+
+type ConfirmTagTestOption struct {
+	Env string `default:"dev" confirm:"true"`
+}
+
+func TestNeedsConfirm(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, needsConfirm(&Command{Confirm: true}))
+	assert.True(t, needsConfirm(&Command{Option: ConfirmTagTestOption{}}))
+	assert.False(t, needsConfirm(&Command{Option: ConfirmTestOption{}}))
+	assert.False(t, needsConfirm(&Command{}))
+}
+
+func TestConfirmSummary(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Run `deploy`?", confirmSummary(&Command{Name: "deploy"}, nil, "en"))
+	assert.Equal(t, "Run `deploy`?\n• Env: `prod`", confirmSummary(&Command{Name: "deploy"}, ConfirmTestOption{Env: "prod"}, "en"))
+}
+
+func TestConfirm(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	var executed []string
+	clear := func() {
+		fake.Reset()
+		Setup("bot", "", "")
+		SetStore(nil)
+		SetConfirmTimeout(5 * time.Minute)
+		ToolsInitCommand()
+		executed = []string{}
+		AddCommand(&Command{
+			Name:    "deploy",
+			Confirm: true,
+			Option:  ConfirmTestOption{},
+			Execute: func(e Event, opt interface{}) {
+				executed = append(executed, opt.(ConfirmTestOption).Env)
+			},
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	request := func() (string, string) {
+		onMessage(Event{"channel": "C1", "user": "U1", "event_ts": "1.0", "text": "deploy prod"})
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "Run `deploy`?\n• Env: `prod`", calls[0].Param("text"))
+		assert.Contains(t, calls[0].Param("blocks"), ConfirmActionID)
+		ids, _ := GetStore().List(confirmNamespace)
+		assert.Len(t, ids, 1)
+		return ids[0], fake.Messages()[0].Timestamp
+	}
+	click := func(actionID, id, user, ts string) {
+		fake.Reset()
+		onInteraction(Payload{
+			"type":    "block_actions",
+			"user":    map[string]interface{}{"id": user},
+			"channel": map[string]interface{}{"id": "C1"},
+			"message": map[string]interface{}{"ts": ts},
+			"actions": []interface{}{
+				map[string]interface{}{"action_id": actionID, "value": id},
+			},
+		})
+	}
+
+	testRun(t, "confirm test", func(t *testing.T) {
+		id, ts := request()
+		assert.Empty(t, executed)
+
+		click(ConfirmActionID, id, "U1", ts)
+		assert.Equal(t, []string{"prod"}, executed)
+		update := fake.CallsFor("chat.update")
+		assert.Len(t, update, 1)
+		assert.Equal(t, ts, update[0].Param("ts"))
+		assert.Equal(t, "`deploy` was confirmed by <@U1>.", update[0].Param("text"))
+
+		// clicked twice
+		click(ConfirmActionID, id, "U1", ts)
+		assert.Equal(t, []string{"prod"}, executed)
+		assert.Empty(t, fake.CallsFor("chat.update"))
+	})
+
+	testRun(t, "cancel test", func(t *testing.T) {
+		id, ts := request()
+
+		click(CancelActionID, id, "U1", ts)
+		assert.Empty(t, executed)
+		assert.Equal(t, "`deploy` was canceled by <@U1>.", fake.CallsFor("chat.update")[0].Param("text"))
+	})
+
+	testRun(t, "other user test", func(t *testing.T) {
+		id, ts := request()
+
+		click(ConfirmActionID, id, "U2", ts)
+		assert.Empty(t, executed)
+		assert.Empty(t, fake.CallsFor("chat.update"))
+		ephemeral := fake.CallsFor("chat.postEphemeral")
+		assert.Len(t, ephemeral, 1)
+		assert.Equal(t, "U2", ephemeral[0].Param("user"))
+
+		click(ConfirmActionID, id, "U1", ts)
+		assert.Equal(t, []string{"prod"}, executed)
+	})
+
+	testRun(t, "expired test", func(t *testing.T) {
+		SetConfirmTimeout(time.Millisecond)
+		id, ts := request()
+		time.Sleep(50 * time.Millisecond)
+
+		update := fake.CallsFor("chat.update")
+		assert.Len(t, update, 1)
+		assert.Equal(t, "`deploy` has expired.", update[0].Param("text"))
+
+		click(ConfirmActionID, id, "U1", ts)
+		assert.Empty(t, executed)
+		assert.Empty(t, fake.CallsFor("chat.update"))
+	})
+
+	testRun(t, "expired installation test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		defer ToolsClearInstallation()
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "UBOT1"})
+		installationStore.SaveInstallation(&Installation{TeamID: "T2", BotToken: "xoxb-2", BotUserID: "UBOT2"})
+		SetConfirmTimeout(time.Millisecond)
+
+		runTask(TaskMessage, Event{"channel": "C1", "user": "U1", "event_ts": "1.0", "team_id": "T2", "text": "deploy prod"}, nil)
+		time.Sleep(50 * time.Millisecond)
+
+		update := fake.CallsFor("chat.update")
+		if assert.Len(t, update, 1) {
+			assert.Equal(t, "xoxb-2", update[0].Param("token"))
+		}
+	})
+
+	testRun(t, "not found test", func(t *testing.T) {
+		click(ConfirmActionID, "unknown", "U1", "2.0")
+		assert.Equal(t, "This confirmation has expired.", fake.CallsFor("chat.update")[0].Param("text"))
+	})
+
+	testRun(t, "other action test", func(t *testing.T) {
+		handler := &TestInteractionHandler{}
+		SetInteractionHandler(handler)
+		defer SetInteractionHandler(nil)
+
+		click("other", "", "U1", "2.0")
+		assert.True(t, handler.Called)
+	})
+}
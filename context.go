@@ -0,0 +1,26 @@
+package slackbot
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	storeContextKey contextKey = iota
+)
+
+// newContext for the execution of Command, derived from the context of the request.
+func newContext(e Event) context.Context {
+	ctx := e.Context()
+	ctx = context.WithValue(ctx, storeContextKey, GetStore())
+	return ctx
+}
+
+// StoreFromContext gets Store from the context of Command execution.
+func StoreFromContext(ctx context.Context) Store {
+	if s, ok := ctx.Value(storeContextKey).(Store); ok {
+		return s
+	}
+	return GetStore()
+}
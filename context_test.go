@@ -0,0 +1,26 @@
+package slackbot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreFromContext(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetStore(nil)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		s := NewMemoryStore()
+		SetStore(s)
+		ctx := newContext(Event{})
+
+		assert.Equal(t, s, StoreFromContext(ctx))
+	})
+
+	testRun(t, "default test", func(t *testing.T) {
+		assert.Equal(t, GetStore(), StoreFromContext(context.Background()))
+	})
+}
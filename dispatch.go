@@ -0,0 +1,350 @@
+package slackbot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Types of Task.
+const (
+	TaskMessage         = "message"
+	TaskAppMention      = "app_mention"
+	TaskReactionAdded   = "reaction_added"
+	TaskReactionRemoved = "reaction_removed"
+	TaskSlashCommand    = "slash_command"
+	TaskInteraction     = "interaction"
+)
+
+// Task is the handling of an event received from Slack, which can be deferred by Dispatcher.
+type Task struct {
+	Type    string  `json:"type"`
+	Event   Event   `json:"event,omitempty"`
+	Payload Payload `json:"payload,omitempty"`
+
+	// TraceContext propagated to the worker running the Task.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+
+	// Signature of the Task by the signing secret, verified by RunTask.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ErrTaskSignature is returned by RunTask if the Task is not signed by the secret of slackbot.
+var ErrTaskSignature = errors.New("invalid task signature")
+
+// taskSecret signing the Tasks, which is the signing secret, or the verification token if not set.
+// Tasks are neither signed nor verified without them, as the requests from Slack.
+func taskSecret() string {
+	return selectString(signingSecret != "", signingSecret, verificationToken)
+}
+
+// sign the Task by the secret.
+func (t *Task) sign() {
+	if secret := taskSecret(); secret != "" {
+		t.Signature = t.signature(secret)
+	}
+}
+
+// verify the signature of the Task, so that forged Tasks are not run by the workers.
+func (t *Task) verify() error {
+	secret := taskSecret()
+	if secret == "" {
+		return nil
+	}
+	if t.Signature == "" || !hmac.Equal([]byte(t.Signature), []byte(t.signature(secret))) {
+		return ErrTaskSignature
+	}
+	return nil
+}
+
+// signature of the Task without Signature.
+func (t *Task) signature(secret string) string {
+	unsigned := *t
+	unsigned.Signature = ""
+	data, _ := json.Marshal(&unsigned)
+
+	// encoded again after decoding, so that the Task decoded by the worker has the same bytes
+	decoded := &Task{}
+	if json.Unmarshal(data, decoded) == nil {
+		data, _ = json.Marshal(decoded)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher enqueues the Task to be run by a worker with RunTask.
+// Events are acknowledged to Slack just after dispatching, so that slow commands do not exceed the timeout of 3 seconds.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, task *Task) error
+}
+
+var (
+	dispatcher Dispatcher
+)
+
+// SetDispatcher for slackbot. Tasks are run in the request if nil, which is the default.
+func SetDispatcher(d Dispatcher) {
+	dispatcher = d
+}
+
+// newTask of the Event or the Payload, which is copied without its context.
+func newTask(typeName string, e Event, p Payload) *Task {
+	task := &Task{Type: typeName, TraceContext: map[string]string{}}
+	if e != nil {
+		task.Event = e.withoutContext()
+	}
+	if p != nil {
+		task.Payload = p.withoutContext()
+	}
+	otel.GetTextMapPropagator().Inject(requestContext(e, p), propagation.MapCarrier(task.TraceContext))
+	task.sign()
+	return task
+}
+
+// context of the request handling the Event or the Payload.
+func requestContext(e Event, p Payload) context.Context {
+	if p != nil {
+		return p.Context()
+	}
+	return e.Context()
+}
+
+// handleTask by the Dispatcher if set, otherwise in the request.
+// The Task is run in the request if failed to dispatch.
+func handleTask(typeName string, e Event, p Payload) {
+	if dispatcher == nil {
+		runTask(typeName, e, p)
+		return
+	}
+
+	task := newTask(typeName, e, p)
+	if err := dispatcher.Dispatch(requestContext(e, p), task); err != nil {
+		logError(task.logEvent(), "dispatch error", "type", typeName, "error", err)
+		runTask(typeName, e, p)
+	}
+}
+
+// RunTask dispatched by Dispatcher. Workers call this for each Task.
+// Returns ErrTaskSignature if the Task is not signed by the signing secret or the verification token.
+func RunTask(ctx context.Context, task *Task) error {
+	if err := setupFromEnv(); err != nil {
+		return err
+	}
+	if err := task.verify(); err != nil {
+		logWarn(task.logEvent(), "task rejected", "type", task.Type, "error", err)
+		return err
+	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(task.TraceContext))
+	ctx, span := startSpan(ctx, "slackbot.task "+task.Type, trace.SpanKindConsumer, attribute.String("slackbot.event.type", task.Type))
+	var err error
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	switch task.Type {
+	case TaskInteraction:
+		if task.Payload == nil {
+			err = fmt.Errorf("no payload in task: %s", task.Type)
+			return err
+		}
+		task.Payload.SetContext(ctx)
+	default:
+		if task.Event == nil {
+			err = fmt.Errorf("no event in task: %s", task.Type)
+			return err
+		}
+		task.Event.SetContext(ctx)
+	}
+
+	if !runTask(task.Type, task.Event, task.Payload) {
+		err = fmt.Errorf("unknown task type: %s", task.Type)
+	}
+	return err
+}
+
+func runTask(typeName string, e Event, p Payload) bool {
+	if e != nil {
+		routeEvent(e)
+	}
+	if p != nil {
+		routePayload(p)
+	}
+
+	var handle func()
+	switch typeName {
+	case TaskMessage:
+		handle = func() { onMessage(e) }
+	case TaskAppMention:
+		handle = func() { onMentionMessage(e) }
+	case TaskReactionAdded, TaskReactionRemoved:
+		handle = func() { onReaction(e) }
+	case TaskSlashCommand:
+		handle = func() { onSlashCommand(e) }
+	case TaskInteraction:
+		handle = func() { onInteraction(p) }
+	default:
+		return false
+	}
+
+	if channel := taskChannel(typeName, e, p); !channelAllowed(channel) {
+		logDebug(e, "channel not allowed", "type", typeName, "channel", channel)
+		return true
+	}
+	handle()
+	return true
+}
+
+// logEvent of the Task for the log.
+func (t *Task) logEvent() Event {
+	if t.Event != nil {
+		return t.Event
+	}
+	return Event{"request_id": t.Payload.String("request_id")}
+}
+
+// DecodeTask from JSON.
+func DecodeTask(data []byte) (*Task, error) {
+	task := &Task{}
+	if err := json.Unmarshal(data, task); err != nil {
+		return nil, err
+	}
+	if task.Type == "" {
+		return nil, fmt.Errorf("no type in task")
+	}
+	return task, nil
+}
+
+// ErrQueueFull is returned by MemoryDispatcher if MaxQueue Tasks are queued.
+// The Task is run inline instead.
+var ErrQueueFull = errors.New("queue full")
+
+// MemoryDispatcher queues Tasks in memory, for local servers and tests.
+// Tasks are encoded to JSON in the same way as the other Dispatchers.
+type MemoryDispatcher struct {
+	// Workers running Tasks concurrently in Start. 1 if zero.
+	Workers int
+
+	// MaxQueue of the Tasks waiting for the workers. Zero means no limit.
+	MaxQueue int
+
+	mu     sync.Mutex
+	queue  [][]byte
+	notify chan struct{}
+}
+
+// NewMemoryDispatcher for slackbot.
+func NewMemoryDispatcher() *MemoryDispatcher {
+	return &MemoryDispatcher{notify: make(chan struct{}, 1)}
+}
+
+// Dispatch the Task to the queue.
+func (d *MemoryDispatcher) Dispatch(ctx context.Context, task *Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	if d.MaxQueue > 0 && len(d.queue) >= d.MaxQueue {
+		d.mu.Unlock()
+		return ErrQueueFull
+	}
+	d.queue = append(d.queue, data)
+	d.mu.Unlock()
+
+	d.wake()
+	return nil
+}
+
+// wake a worker waiting for Tasks.
+func (d *MemoryDispatcher) wake() {
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Len of the queued Tasks.
+func (d *MemoryDispatcher) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue)
+}
+
+// RunPending Tasks in order until the queue is empty or ctx is done, and returns the number of them.
+func (d *MemoryDispatcher) RunPending(ctx context.Context) int {
+	count := 0
+	for ctx.Err() == nil && d.runNext(ctx) {
+		count++
+	}
+	return count
+}
+
+// runNext Task in the queue. Returns false if the queue is empty.
+func (d *MemoryDispatcher) runNext(ctx context.Context) bool {
+	d.mu.Lock()
+	if len(d.queue) == 0 {
+		d.mu.Unlock()
+		return false
+	}
+	data := d.queue[0]
+	d.queue = d.queue[1:]
+	remaining := len(d.queue)
+	d.mu.Unlock()
+
+	// let another worker run the rest
+	if remaining > 0 {
+		d.wake()
+	}
+
+	task, err := DecodeTask(data)
+	if err == nil {
+		err = RunTask(ctx, task)
+	}
+	if err != nil {
+		logError(nil, "task error", "error", err)
+	}
+	return true
+}
+
+// Start the workers running Tasks as they are dispatched until ctx is done.
+// Returns after the running Tasks finish. The queued Tasks are left, so drain them by RunPending before ctx is done.
+func (d *MemoryDispatcher) Start(ctx context.Context) {
+	workers := d.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				for d.runNext(ctx) {
+					if ctx.Err() != nil {
+						return
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-d.notify:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,243 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestDispatcher struct {
+	Err error
+}
+
+func (d *TestDispatcher) Dispatch(ctx context.Context, task *Task) error {
+	return d.Err
+}
+
+func TestDispatcher_OnCall(t *testing.T) {
+	var texts []string
+	clear := func() {
+		ToolsInitCommand()
+		Setup("bot", "token", "")
+		texts = []string{}
+		AddCommand(&Command{
+			Name: "test",
+			ExecuteContext: func(ctx context.Context, e Event, opt interface{}) {
+				texts = append(texts, e.Text())
+			},
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		SetDispatcher(nil)
+		ToolsInitCommand()
+	})
+	call := func(body string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader(body))
+		OnCall(rec, req)
+		return rec.Code
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		d := NewMemoryDispatcher()
+		SetDispatcher(d)
+
+		code := call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test 1"}}`)
+		assert.Equal(t, http.StatusOK, code)
+		code = call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test 2"}}`)
+		assert.Equal(t, http.StatusOK, code)
+
+		// deferred until the worker runs
+		assert.Empty(t, texts)
+		assert.Equal(t, 2, d.Len())
+
+		assert.Equal(t, 2, d.RunPending(context.Background()))
+		assert.Equal(t, []string{"test 1", "test 2"}, texts)
+		assert.Equal(t, 0, d.Len())
+
+		// not run after ctx is done
+		call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test 3"}}`)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Equal(t, 0, d.RunPending(ctx))
+		assert.Equal(t, 1, d.Len())
+	})
+
+	testRun(t, "start test", func(t *testing.T) {
+		d := NewMemoryDispatcher()
+		SetDispatcher(d)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			d.Start(ctx)
+			close(done)
+		}()
+
+		call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test"}}`)
+		for i := 0; i < 50 && d.Len() > 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		<-done
+
+		assert.Equal(t, []string{"test"}, texts)
+	})
+
+	testRun(t, "workers test", func(t *testing.T) {
+		d := NewMemoryDispatcher()
+		d.Workers = 3
+		SetDispatcher(d)
+		running := make(chan struct{}, 3)
+		release := make(chan struct{})
+		AddCommand(&Command{
+			Name: "block",
+			Execute: func(e Event, opt interface{}) {
+				running <- struct{}{}
+				<-release
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			d.Start(ctx)
+			close(done)
+		}()
+
+		for i := 0; i < 3; i++ {
+			call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"block"}}`)
+		}
+		// all the tasks run at the same time
+		for i := 0; i < 3; i++ {
+			select {
+			case <-running:
+			case <-time.After(time.Second):
+				t.Fatal("tasks are not run concurrently")
+			}
+		}
+		close(release)
+		cancel()
+		<-done
+	})
+
+	testRun(t, "max queue test", func(t *testing.T) {
+		d := NewMemoryDispatcher()
+		d.MaxQueue = 1
+		SetDispatcher(d)
+
+		call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test 1"}}`)
+		call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test 2"}}`)
+
+		// run in the request if the queue is full
+		assert.Equal(t, []string{"test 2"}, texts)
+		assert.Equal(t, 1, d.Len())
+		assert.Equal(t, ErrQueueFull, d.Dispatch(context.Background(), &Task{Type: TaskMessage}))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetDispatcher(&TestDispatcher{Err: errors.New("error")})
+
+		code := call(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "text":"test"}}`)
+
+		// run in the request if failed to dispatch
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"test"}, texts)
+	})
+}
+
+func TestRunTask(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsInitCommand, ToolsInitCommand)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		var got Event
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				got = e
+			},
+		})
+		e := Event{"type": "app_mention", "text": "test", "request_id": "req"}
+		e.SetContext(context.Background())
+		task := newTask(TaskAppMention, e, nil)
+		assert.NotContains(t, task.Event, contextEventKey)
+
+		err := RunTask(context.Background(), task)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "req", got.String("request_id"))
+		assert.NotNil(t, got.Context())
+	})
+
+	testRun(t, "setup test", func(t *testing.T) {
+		api = nil
+		os.Setenv("SLACK_BOT_USER_ID", "worker")
+		defer os.Unsetenv("SLACK_BOT_USER_ID")
+
+		token := os.Getenv("SLACK_VERIFICATION_TOKEN")
+		os.Setenv("SLACK_VERIFICATION_TOKEN", "secret")
+		defer os.Setenv("SLACK_VERIFICATION_TOKEN", token)
+		verificationToken = "secret"
+		task := newTask(TaskMessage, Event{"text": "none"}, nil)
+
+		err := RunTask(context.Background(), task)
+
+		// set up from the environment variables in a new worker
+		assert.NoError(t, err)
+		assert.NotNil(t, api)
+		assert.Equal(t, "worker", slackBotUserID)
+	})
+
+	testRun(t, "signature test", func(t *testing.T) {
+		secret, token := signingSecret, verificationToken
+		defer func() {
+			signingSecret, verificationToken = secret, token
+		}()
+		signingSecret, verificationToken = "", "secret"
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {}})
+
+		// signed Tasks are run after encoding
+		task := newTask(TaskAppMention, Event{"text": "test", "count": 1, "rate": 0.5, "items": []string{"a"}}, nil)
+		assert.NotEmpty(t, task.Signature)
+		data, _ := json.Marshal(task)
+		decoded, err := DecodeTask(data)
+		assert.NoError(t, err)
+		assert.NoError(t, RunTask(context.Background(), decoded))
+
+		// forged Tasks are rejected
+		assert.Equal(t, ErrTaskSignature, RunTask(context.Background(), &Task{Type: TaskAppMention, Event: Event{"text": "test"}}))
+		decoded, _ = DecodeTask(data)
+		decoded.Event["user"] = "UADMIN"
+		assert.Equal(t, ErrTaskSignature, RunTask(context.Background(), decoded))
+
+		// signed by the signing secret if set
+		signingSecret = "signing"
+		assert.Equal(t, ErrTaskSignature, RunTask(context.Background(), task))
+		assert.NoError(t, RunTask(context.Background(), newTask(TaskAppMention, Event{"text": "test"}, nil)))
+	})
+
+	testRun(t, "decode test", func(t *testing.T) {
+		task, err := DecodeTask([]byte(`{"type":"interaction","payload":{"type":"block_actions"}}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, TaskInteraction, task.Type)
+		assert.Equal(t, "block_actions", task.Payload.Type())
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		assert.Error(t, RunTask(context.Background(), &Task{Type: "unknown", Event: Event{}}))
+		assert.Error(t, RunTask(context.Background(), &Task{Type: TaskMessage}))
+		assert.Error(t, RunTask(context.Background(), &Task{Type: TaskInteraction}))
+
+		_, err := DecodeTask([]byte(`{}`))
+		assert.Error(t, err)
+		_, err = DecodeTask([]byte(`[]`))
+		assert.Error(t, err)
+	})
+}
@@ -1,13 +1,40 @@
 package slackbot
 
 import (
-	"regexp"
-	"strings"
+	"context"
+	"net/url"
 )
 
 // Event of slack.
 type Event map[string]interface{}
 
+// contextKey of Event and Payload for the context of the request.
+const contextEventKey = "slackbot_context"
+
+// Context of the request handling the Event. Returns context.Background if not set.
+func (e Event) Context() context.Context {
+	if ctx, ok := e[contextEventKey].(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// SetContext of the request handling the Event.
+func (e Event) SetContext(ctx context.Context) {
+	e[contextEventKey] = ctx
+}
+
+// withoutContext for saving the Event.
+func (e Event) withoutContext() Event {
+	copied := Event{}
+	for key, value := range e {
+		if key != contextEventKey {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
 // String data in Event.
 func (e Event) String(key string) string {
 	if v, ok := e[key]; !ok {
@@ -49,13 +76,55 @@ func (e Event) ThreadTimestamp() string {
 	return e.String("event_ts")
 }
 
-// ModifyText correctly.
-func (e Event) ModifyText() {
-	// replace non breaking space to space
-	const nbsp = '\u00A0'
-	e["text"] = strings.Replace(e.Text(), string(nbsp), " ", -1)
+// Command of slash command Event.
+func (e Event) Command() string {
+	return e.String("command")
+}
+
+// Reaction of reaction Event.
+func (e Event) Reaction() string {
+	return e.String("reaction")
+}
+
+// Item of reaction Event.
+func (e Event) Item() Event {
+	if v, ok := e["item"].(map[string]interface{}); ok {
+		return v
+	}
+	return Event{}
+}
+
+// newSlashCommandEvent from the form of slash command.
+func newSlashCommandEvent(form url.Values) Event {
+	e := Event{
+		"type":          "slash_command",
+		"command":       form.Get("command"),
+		"text":          form.Get("text"),
+		"channel":       form.Get("channel_id"),
+		"user":          form.Get("user_id"),
+		"team":          form.Get("team_id"),
+		"team_id":       form.Get("team_id"),
+		"enterprise_id": form.Get("enterprise_id"),
+		"response_url":  form.Get("response_url"),
+		"trigger_id":    form.Get("trigger_id"),
+	}
+	e.ModifyText()
+	return e
+}
+
+// RawText of Event before ModifyText.
+func (e Event) RawText() string {
+	if _, ok := e["raw_text"]; ok {
+		return e.String("raw_text")
+	}
+	return e.Text()
+}
 
-	// multiple space to single space
-	rep := regexp.MustCompile(" +")
-	e["text"] = rep.ReplaceAllString(e.Text(), " ")
+// ModifyText correctly by the normalizers set by SetTextNormalizers.
+// The original text is kept as RawText.
+func (e Event) ModifyText() {
+	if _, ok := e["raw_text"]; !ok {
+		e["raw_text"] = e.Text()
+	}
+	e["text"] = NormalizeText(e.Text())
 }
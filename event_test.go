@@ -1,6 +1,7 @@
 package slackbot
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -96,6 +97,64 @@ func TestEvent_ThreadTimestamp(t *testing.T) {
 	})
 }
 
+func TestEvent_Command(t *testing.T) {
+	t.Parallel()
+	event := Event{
+		"command": "/test",
+	}
+
+	t.Run("normal test", func(t *testing.T) {
+		result := event.Command()
+		assert.Equal(t, "/test", result)
+	})
+}
+
+func TestEvent_Reaction(t *testing.T) {
+	t.Parallel()
+	event := Event{
+		"reaction": "test",
+	}
+
+	t.Run("normal test", func(t *testing.T) {
+		result := event.Reaction()
+		assert.Equal(t, "test", result)
+	})
+}
+
+func TestEvent_Item(t *testing.T) {
+	t.Parallel()
+	t.Run("normal test", func(t *testing.T) {
+		event := Event{
+			"item": map[string]interface{}{"channel": "test"},
+		}
+		result := event.Item()
+		assert.Equal(t, "test", result.Channel())
+	})
+
+	t.Run("not found test", func(t *testing.T) {
+		result := Event{}.Item()
+		assert.Len(t, result, 0)
+	})
+}
+
+func TestNewSlashCommandEvent(t *testing.T) {
+	t.Parallel()
+	t.Run("normal test", func(t *testing.T) {
+		form := url.Values{
+			"command":    {"/test"},
+			"text":       {"ho  ge"},
+			"channel_id": {"C1"},
+			"user_id":    {"U1"},
+		}
+		event := newSlashCommandEvent(form)
+		assert.Equal(t, "slash_command", event.Type())
+		assert.Equal(t, "/test", event.Command())
+		assert.Equal(t, "ho ge", event.Text())
+		assert.Equal(t, "C1", event.Channel())
+		assert.Equal(t, "U1", event.User())
+	})
+}
+
 func TestEvent_ModifyText(t *testing.T) {
 	t.Parallel()
 	t.Run("normal test", func(t *testing.T) {
@@ -106,4 +165,27 @@ func TestEvent_ModifyText(t *testing.T) {
 		event.ModifyText()
 		assert.Equal(t, "ho ge ho ge", event.Text())
 	})
+
+	t.Run("full width test", func(t *testing.T) {
+		event := Event{
+			"text": "ｒｅｐｅａｔ\u3000ｈｉ　２\u200B",
+		}
+		event.ModifyText()
+		assert.Equal(t, "repeat hi 2", event.Text())
+		assert.Equal(t, "ｒｅｐｅａｔ\u3000ｈｉ　２\u200B", event.RawText())
+
+		// raw text is kept
+		event.ModifyText()
+		assert.Equal(t, "ｒｅｐｅａｔ\u3000ｈｉ　２\u200B", event.RawText())
+	})
+}
+
+func TestEvent_RawText(t *testing.T) {
+	t.Parallel()
+	t.Run("not modified test", func(t *testing.T) {
+		event := Event{
+			"text": "ho  ge",
+		}
+		assert.Equal(t, "ho  ge", event.RawText())
+	})
 }
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	slackbot "github.com/peto-tn/slackbot-go"
+	"github.com/peto-tn/slackbot-go/mrkdwn"
 )
 
 func init() {
@@ -37,13 +38,14 @@ func repeat(e slackbot.Event, opt interface{}) {
 		message += option.Message
 	}
 
+	// The text is already escaped by Slack, so it is sent as mrkdwn
 	// Font
 	switch option.Font {
 	case "bold":
-		message = "*" + message + "*"
+		message = mrkdwn.Bold(message)
 	case "italic":
-		message = "_" + message + "_"
+		message = mrkdwn.Italic(message)
 	}
 
-	slackbot.ReplyMessage(e, message)
+	slackbot.ReplyMrkdwn(e, message)
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+	// add command
+	_ "github.com/peto-tn/slackbot-go/example/command"
+)
+
+func main() {
+	user := flag.String("user", "UCLIUSER", "user ID of the messages")
+	channel := flag.String("channel", "CCLICHANNEL", "channel ID of the messages")
+	flag.Parse()
+
+	cli := &slackbot.CLI{
+		User:    *user,
+		Channel: *channel,
+		Prompt:  "> ",
+		In:      os.Stdin,
+		Out:     os.Stdout,
+	}
+	if err := cli.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,13 +2,26 @@ package gcp
 
 import (
 	"net/http"
+	"os"
 
 	slackbot "github.com/peto-tn/slackbot-go"
 	// add command
 	_ "github.com/peto-tn/slackbot-go/example/command"
 )
 
+func init() {
+	// defer the commands to OnWorker if the topic is set
+	if topic := os.Getenv("SLACKBOT_PUBSUB_TOPIC"); topic != "" {
+		slackbot.SetDispatcher(slackbot.NewPubSubDispatcher(topic))
+	}
+}
+
 // OnCall is Cloud Functions Entrypoint.
 func OnCall(w http.ResponseWriter, r *http.Request) {
 	slackbot.OnCall(w, r)
 }
+
+// OnWorker is Cloud Functions Entrypoint of the push subscription.
+func OnWorker(w http.ResponseWriter, r *http.Request) {
+	slackbot.OnWorker(w, r)
+}
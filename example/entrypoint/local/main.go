@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log"
 	"os"
 
 	slackbot "github.com/peto-tn/slackbot-go"
@@ -10,5 +11,7 @@ import (
 
 func main() {
 	port := os.Getenv("PORT")
-	slackbot.ListenAndServe("/", ":"+port, nil)
+	if err := slackbot.NewServer(":" + port).ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,391 @@
+// Package fakeslack is an in-process fake of the Slack Web API.
+//
+// The fake records every call and serves canned responses, so that bots can be
+// tested end-to-end without the network.
+//
+//	s := fakeslack.NewServer()
+//	defer s.Close()
+//	slackbot.SetAPIURL(s.URL())
+package fakeslack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// BotUserID returned by auth.test.
+const BotUserID = "UFAKEBOT"
+
+// TeamID returned by auth.test.
+const TeamID = "TFAKETEAM"
+
+// AppID returned by oauth.v2.access.
+const AppID = "AFAKEAPP"
+
+// Call of the Slack Web API received by the Server.
+type Call struct {
+	Method   string
+	Params   url.Values
+	FileName string
+	File     []byte
+}
+
+// Param value of the Call.
+func (c Call) Param(key string) string {
+	return c.Params.Get(key)
+}
+
+// ResponseFunc builds the response of a Call. The result is encoded as JSON.
+type ResponseFunc func(c Call) interface{}
+
+// Message posted to the Server.
+type Message struct {
+	Channel         string
+	User            string
+	Text            string
+	Timestamp       string
+	ThreadTimestamp string
+}
+
+// Server of the fake Slack Web API.
+type Server struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	calls     []Call
+	messages  []Message
+	responses map[string]ResponseFunc
+	scopes    []string
+	sequence  int
+}
+
+// NewServer starts a fake Slack Web API server.
+func NewServer() *Server {
+	s := &Server{responses: map[string]ResponseFunc{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL of the Server to be used as the API base URL.
+func (s *Server) URL() string {
+	return s.server.URL + "/"
+}
+
+// Close the Server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// HTTPClient for the Server.
+func (s *Server) HTTPClient() *http.Client {
+	return s.server.Client()
+}
+
+// Calls received by the Server in order.
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call{}, s.calls...)
+}
+
+// CallsFor the method in order.
+func (s *Server) CallsFor(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := []Call{}
+	for _, c := range s.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Messages posted or updated through chat.postMessage and chat.update.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message{}, s.messages...)
+}
+
+// Reset recorded calls, messages and canned responses.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+	s.messages = nil
+	s.responses = map[string]ResponseFunc{}
+	s.scopes = nil
+}
+
+// SetResponse of the method. The response is encoded as JSON.
+func (s *Server) SetResponse(method string, response interface{}) {
+	s.SetResponseFunc(method, func(Call) interface{} {
+		return response
+	})
+}
+
+// SetResponseFunc of the method.
+func (s *Server) SetResponseFunc(method string, f ResponseFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.responses[method] = f
+}
+
+// SetScopes granted to the token, returned in the X-OAuth-Scopes header of every response.
+// The header is not returned if no scopes are set.
+func (s *Server) SetScopes(scopes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scopes = scopes
+}
+
+// SetError makes the method fail with the Slack error code.
+func (s *Server) SetError(method, code string) {
+	s.SetResponse(method, map[string]interface{}{"ok": false, "error": code})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := readCall(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, c)
+	f, ok := s.responses[c.Method]
+	scopes := s.scopes
+	s.mu.Unlock()
+
+	var response interface{}
+	if ok {
+		response = f(c)
+	} else {
+		response = s.defaultResponse(c)
+	}
+
+	if scopes != nil {
+		w.Header().Set("X-OAuth-Scopes", strings.Join(scopes, ","))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func readCall(r *http.Request) (Call, error) {
+	c := Call{Method: strings.TrimPrefix(r.URL.Path, "/")}
+
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil && err != http.ErrNotMultipart {
+		return c, err
+	}
+	c.Params = r.Form
+
+	if r.MultipartForm != nil {
+		for _, headers := range r.MultipartForm.File {
+			for _, header := range headers {
+				f, err := header.Open()
+				if err != nil {
+					return c, err
+				}
+				c.FileName = header.Filename
+				c.File, err = ioutil.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return c, err
+				}
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (s *Server) nextID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequence++
+	return fmt.Sprintf("%s%08d", prefix, s.sequence)
+}
+
+func (s *Server) nextTimestamp() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequence++
+	return fmt.Sprintf("1500000000.%06d", s.sequence)
+}
+
+func (s *Server) defaultResponse(c Call) interface{} {
+	switch c.Method {
+	case "auth.test":
+		return ok(map[string]interface{}{
+			"user_id": BotUserID,
+			"team_id": TeamID,
+		})
+
+	case "oauth.v2.access":
+		if c.Param("grant_type") == "refresh_token" {
+			return ok(map[string]interface{}{
+				"access_token":  s.nextID("xoxe.xoxb-"),
+				"refresh_token": s.nextID("xoxe-"),
+				"token_type":    "bot",
+				"expires_in":    43200,
+			})
+		}
+		return ok(map[string]interface{}{
+			"app_id":       AppID,
+			"access_token": "xoxb-" + c.Param("code"),
+			"token_type":   "bot",
+			"scope":        "chat:write",
+			"bot_user_id":  BotUserID,
+			"team":         map[string]interface{}{"id": TeamID},
+			"authed_user":  map[string]interface{}{"id": "U" + c.Param("code")},
+		})
+
+	case "chat.postMessage":
+		m := Message{
+			Channel:         c.Param("channel"),
+			User:            BotUserID,
+			Text:            c.Param("text"),
+			Timestamp:       s.nextTimestamp(),
+			ThreadTimestamp: c.Param("thread_ts"),
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, m)
+		s.mu.Unlock()
+		return ok(map[string]interface{}{
+			"channel": m.Channel,
+			"ts":      m.Timestamp,
+			"message": messageJSON(m),
+		})
+
+	case "chat.postEphemeral":
+		return ok(map[string]interface{}{
+			"message_ts": s.nextTimestamp(),
+		})
+
+	case "chat.update":
+		s.mu.Lock()
+		for i, m := range s.messages {
+			if m.Channel == c.Param("channel") && m.Timestamp == c.Param("ts") {
+				s.messages[i].Text = c.Param("text")
+			}
+		}
+		s.mu.Unlock()
+		return ok(map[string]interface{}{
+			"channel": c.Param("channel"),
+			"ts":      c.Param("ts"),
+			"text":    c.Param("text"),
+		})
+
+	case "reactions.add":
+		return ok(nil)
+
+	case "users.info":
+		return ok(map[string]interface{}{
+			"user": map[string]interface{}{
+				"id":   c.Param("user"),
+				"name": c.Param("user"),
+			},
+		})
+
+	case "conversations.info":
+		return ok(map[string]interface{}{
+			"channel": map[string]interface{}{
+				"id": c.Param("channel"),
+			},
+		})
+
+	case "conversations.history":
+		return ok(map[string]interface{}{
+			"messages": s.findMessages(func(m Message) bool {
+				return m.Channel == c.Param("channel") && m.ThreadTimestamp == ""
+			}),
+		})
+
+	case "conversations.replies":
+		return ok(map[string]interface{}{
+			"messages": s.findMessages(func(m Message) bool {
+				return m.Channel == c.Param("channel") &&
+					(m.Timestamp == c.Param("ts") || m.ThreadTimestamp == c.Param("ts"))
+			}),
+		})
+
+	case "conversations.members":
+		return ok(map[string]interface{}{
+			"members": []string{},
+		})
+
+	case "conversations.open":
+		return ok(map[string]interface{}{
+			"channel": map[string]interface{}{
+				"id": s.nextID("D"),
+			},
+		})
+
+	case "files.upload":
+		name := c.Param("filename")
+		if name == "" {
+			name = c.FileName
+		}
+		return ok(map[string]interface{}{
+			"file": map[string]interface{}{
+				"id":    s.nextID("F"),
+				"name":  name,
+				"title": c.Param("title"),
+			},
+		})
+
+	default:
+	}
+
+	return map[string]interface{}{"ok": false, "error": "unknown_method"}
+}
+
+func (s *Server) findMessages(match func(m Message) bool) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []interface{}{}
+	for _, m := range s.messages {
+		if match(m) {
+			messages = append(messages, messageJSON(m))
+		}
+	}
+	return messages
+}
+
+func messageJSON(m Message) map[string]interface{} {
+	message := map[string]interface{}{
+		"type": "message",
+		"user": m.User,
+		"text": m.Text,
+		"ts":   m.Timestamp,
+	}
+	if m.ThreadTimestamp != "" {
+		message["thread_ts"] = m.ThreadTimestamp
+	}
+	return message
+}
+
+func ok(fields map[string]interface{}) map[string]interface{} {
+	response := map[string]interface{}{"ok": true}
+	for k, v := range fields {
+		response[k] = v
+	}
+	return response
+}
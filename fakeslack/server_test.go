@@ -0,0 +1,209 @@
+package fakeslack
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(s *Server) *slack.Client {
+	return slack.New("token", slack.OptionAPIURL(s.URL()))
+}
+
+func TestServer_PostMessage(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("normal test", func(t *testing.T) {
+		s.Reset()
+		channel, ts, err := api.PostMessage("C1", slack.MsgOptionText("test", true), slack.MsgOptionTS("1.0"))
+		assert.NoError(t, err)
+		assert.Equal(t, "C1", channel)
+		assert.NotEmpty(t, ts)
+
+		calls := s.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "test", calls[0].Param("text"))
+		assert.Equal(t, "1.0", calls[0].Param("thread_ts"))
+		assert.Equal(t, "token", calls[0].Param("token"))
+
+		messages := s.Messages()
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "1.0", messages[0].ThreadTimestamp)
+	})
+
+	t.Run("error test", func(t *testing.T) {
+		s.Reset()
+		s.SetError("chat.postMessage", "channel_not_found")
+		_, _, err := api.PostMessage("C1", slack.MsgOptionText("test", true))
+		assert.EqualError(t, err, "channel_not_found")
+		assert.Len(t, s.Messages(), 0)
+	})
+}
+
+func TestServer_UpdateMessage(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("normal test", func(t *testing.T) {
+		_, ts, _ := api.PostMessage("C1", slack.MsgOptionText("before", true))
+		_, _, text, err := api.UpdateMessage("C1", ts, slack.MsgOptionText("after", true))
+		assert.NoError(t, err)
+		assert.Equal(t, "after", text)
+		assert.Equal(t, "after", s.Messages()[0].Text)
+	})
+}
+
+func TestServer_PostEphemeral(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("normal test", func(t *testing.T) {
+		ts, err := api.PostEphemeral("C1", "U1", slack.MsgOptionText("test", true))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ts)
+
+		calls := s.CallsFor("chat.postEphemeral")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "U1", calls[0].Param("user"))
+	})
+}
+
+func TestServer_AddReaction(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("normal test", func(t *testing.T) {
+		err := api.AddReaction("eyes", slack.NewRefToMessage("C1", "1.0"))
+		assert.NoError(t, err)
+		assert.Equal(t, "eyes", s.CallsFor("reactions.add")[0].Param("name"))
+	})
+}
+
+func TestServer_GetUserInfo(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("default test", func(t *testing.T) {
+		user, err := api.GetUserInfo("U1")
+		assert.NoError(t, err)
+		assert.Equal(t, "U1", user.ID)
+	})
+
+	t.Run("response func test", func(t *testing.T) {
+		s.SetResponseFunc("users.info", func(c Call) interface{} {
+			return map[string]interface{}{
+				"ok":   true,
+				"user": map[string]interface{}{"id": c.Param("user"), "locale": "ja-JP"},
+			}
+		})
+		user, err := api.GetUserInfo("U2")
+		assert.NoError(t, err)
+		assert.Equal(t, "U2", user.ID)
+		assert.Equal(t, "ja-JP", user.Locale)
+	})
+}
+
+func TestServer_Conversations(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("info test", func(t *testing.T) {
+		channel, err := api.GetConversationInfo("C1", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "C1", channel.ID)
+	})
+
+	t.Run("history and replies test", func(t *testing.T) {
+		_, ts, _ := api.PostMessage("C1", slack.MsgOptionText("parent", true))
+		api.PostMessage("C1", slack.MsgOptionText("reply", true), slack.MsgOptionTS(ts))
+
+		history, err := api.GetConversationHistory(&slack.GetConversationHistoryParameters{ChannelID: "C1"})
+		assert.NoError(t, err)
+		assert.Len(t, history.Messages, 1)
+		assert.Equal(t, "parent", history.Messages[0].Text)
+
+		replies, _, _, err := api.GetConversationReplies(&slack.GetConversationRepliesParameters{ChannelID: "C1", Timestamp: ts})
+		assert.NoError(t, err)
+		assert.Len(t, replies, 2)
+		assert.Equal(t, "reply", replies[1].Text)
+	})
+
+	t.Run("open test", func(t *testing.T) {
+		channel, _, _, err := api.OpenConversation(&slack.OpenConversationParameters{Users: []string{"U1"}})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, channel.ID)
+	})
+
+	t.Run("members test", func(t *testing.T) {
+		members, _, err := api.GetUsersInConversation(&slack.GetUsersInConversationParameters{ChannelID: "C1"})
+		assert.NoError(t, err)
+		assert.Len(t, members, 0)
+	})
+}
+
+func TestServer_UploadFile(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("content test", func(t *testing.T) {
+		file, err := api.UploadFile(slack.FileUploadParameters{Content: "hello", Filename: "a.txt", Channels: []string{"C1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "a.txt", file.Name)
+		assert.Equal(t, "hello", s.CallsFor("files.upload")[0].Param("content"))
+	})
+
+	t.Run("reader test", func(t *testing.T) {
+		s.Reset()
+		file, err := api.UploadFile(slack.FileUploadParameters{Reader: strings.NewReader("hello"), Filename: "b.txt"})
+		assert.NoError(t, err)
+		assert.Equal(t, "b.txt", file.Name)
+
+		calls := s.CallsFor("files.upload")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "b.txt", calls[0].FileName)
+		assert.Equal(t, "hello", string(calls[0].File))
+	})
+}
+
+func TestServer_UnknownMethod(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	api := newTestClient(s)
+
+	t.Run("error test", func(t *testing.T) {
+		_, err := api.GetTeamInfo()
+		assert.EqualError(t, err, "unknown_method")
+		assert.Len(t, s.Calls(), 1)
+	})
+}
+
+func TestServer_SetScopes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	t.Run("normal test", func(t *testing.T) {
+		s.SetScopes("chat:write", "commands")
+		res, err := http.PostForm(s.URL()+"auth.test", url.Values{"token": {"token"}})
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, "chat:write,commands", res.Header.Get("X-OAuth-Scopes"))
+
+		s.Reset()
+		res, err = http.PostForm(s.URL()+"auth.test", url.Values{"token": {"token"}})
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Empty(t, res.Header["X-Oauth-Scopes"])
+	})
+}
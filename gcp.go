@@ -0,0 +1,224 @@
+package slackbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// PubSubDispatcher publishes the Task to the Pub/Sub topic, and OnWorker receives it by a push subscription.
+type PubSubDispatcher struct {
+	// Topic such as "projects/my-project/topics/slackbot".
+	Topic string
+
+	// Endpoint of the Pub/Sub API. PUBSUB_EMULATOR_HOST is used if set.
+	Endpoint string
+	Client   *http.Client
+
+	// Token for the API. The token of the service account from the metadata server is used by default.
+	Token func(ctx context.Context) (string, error)
+}
+
+// NewPubSubDispatcher publishing to the topic.
+func NewPubSubDispatcher(topic string) *PubSubDispatcher {
+	d := &PubSubDispatcher{Topic: topic, Endpoint: "https://pubsub.googleapis.com", Token: gcpMetadataToken}
+	if host := os.Getenv("PUBSUB_EMULATOR_HOST"); host != "" {
+		d.Endpoint = "http://" + host
+		d.Token = nil
+	}
+	return d
+}
+
+// Dispatch the Task by publishing a message.
+func (d *PubSubDispatcher) Dispatch(ctx context.Context, task *Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	type message struct {
+		Data       string            `json:"data"`
+		Attributes map[string]string `json:"attributes"`
+	}
+	body, err := json.Marshal(map[string][]message{
+		"messages": {{Data: base64.StdEncoding.EncodeToString(data), Attributes: map[string]string{"type": task.Type}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendGCPRequest(ctx, d.Client, d.Token, d.Endpoint+"/v1/"+d.Topic+":publish", body)
+}
+
+// CloudTasksDispatcher creates a Cloud Task of the Task, which is sent to OnWorker at the URL.
+type CloudTasksDispatcher struct {
+	// Queue such as "projects/my-project/locations/asia-northeast1/queues/slackbot".
+	Queue string
+
+	// URL of the worker calling OnWorker.
+	URL string
+
+	// ServiceAccountEmail for the OIDC token attached to the request to the worker. No token is attached if empty.
+	ServiceAccountEmail string
+
+	// Endpoint of the Cloud Tasks API.
+	Endpoint string
+	Client   *http.Client
+
+	// Token for the API. The token of the service account from the metadata server is used by default.
+	Token func(ctx context.Context) (string, error)
+}
+
+// NewCloudTasksDispatcher creating tasks in the queue for the worker at the URL.
+func NewCloudTasksDispatcher(queue, url string) *CloudTasksDispatcher {
+	return &CloudTasksDispatcher{Queue: queue, URL: url, Endpoint: "https://cloudtasks.googleapis.com", Token: gcpMetadataToken}
+}
+
+// Dispatch the Task by creating a Cloud Task.
+func (d *CloudTasksDispatcher) Dispatch(ctx context.Context, task *Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	httpRequest := map[string]interface{}{
+		"url":        d.URL,
+		"httpMethod": "POST",
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       base64.StdEncoding.EncodeToString(data),
+	}
+	if d.ServiceAccountEmail != "" {
+		httpRequest["oidcToken"] = map[string]string{"serviceAccountEmail": d.ServiceAccountEmail, "audience": d.URL}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"task": map[string]interface{}{"httpRequest": httpRequest},
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendGCPRequest(ctx, d.Client, d.Token, d.Endpoint+"/v2/"+d.Queue+"/tasks", body)
+}
+
+// OnWorker is receive the Tasks handler, of Pub/Sub push subscriptions or Cloud Tasks.
+// The Task is acknowledged with 200 if run, otherwise 400 to be retried or dead-lettered.
+func OnWorker(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Pub/Sub push envelope, otherwise the body of a Cloud Task
+	var envelope struct {
+		Message *struct {
+			Data      string `json:"data"`
+			MessageID string `json:"messageId"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Message != nil {
+		if body, err = base64.StdEncoding.DecodeString(envelope.Message.Data); err != nil {
+			logError(nil, "task error", "message_id", envelope.Message.MessageID, "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	task, err := DecodeTask(body)
+	if err == nil {
+		err = RunTask(r.Context(), task)
+	}
+	if err != nil {
+		logError(nil, "task error", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+}
+
+// sendGCPRequest of JSON with the access token.
+func sendGCPRequest(ctx context.Context, client *http.Client, token func(ctx context.Context) (string, error), url string, body []byte) error {
+	r, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if token != nil {
+		t, err := token(ctx)
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Authorization", "Bearer "+t)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(r.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("gcp error: %s %s", res.Status, message)
+	}
+	return nil
+}
+
+var (
+	gcpToken        string
+	gcpTokenExpires time.Time
+	gcpTokenMu      sync.Mutex
+)
+
+// gcpMetadataToken of the service account from the metadata server, cached until it expires.
+func gcpMetadataToken(ctx context.Context) (string, error) {
+	gcpTokenMu.Lock()
+	defer gcpTokenMu.Unlock()
+	if gcpToken != "" && time.Now().Before(gcpTokenExpires) {
+		return gcpToken, nil
+	}
+
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	r, err := http.NewRequest("GET", "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	r.Header.Set("Metadata-Flavor", "Google")
+
+	res, err := http.DefaultClient.Do(r.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata error: %s", res.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	// refresh a minute before it expires
+	gcpToken = token.AccessToken
+	gcpTokenExpires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return gcpToken, nil
+}
@@ -0,0 +1,218 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPubSubDispatcher(t *testing.T) {
+	var got *http.Request
+	var body map[string]interface{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	testRun := ToolsCreateTestRun(func() {
+		status = http.StatusOK
+	}, nil)
+	token := func(ctx context.Context) (string, error) {
+		return "token", nil
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		d := NewPubSubDispatcher("projects/p/topics/slackbot")
+		d.Endpoint = server.URL
+		d.Token = token
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{"text": "test"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/v1/projects/p/topics/slackbot:publish", got.URL.Path)
+		assert.Equal(t, "Bearer token", got.Header.Get("Authorization"))
+		message := body["messages"].([]interface{})[0].(map[string]interface{})
+		data, _ := base64.StdEncoding.DecodeString(message["data"].(string))
+		assert.JSONEq(t, `{"type":"message","event":{"text":"test"}}`, string(data))
+		assert.Equal(t, map[string]interface{}{"type": "message"}, message["attributes"])
+	})
+
+	testRun(t, "emulator test", func(t *testing.T) {
+		os.Setenv("PUBSUB_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+		defer os.Unsetenv("PUBSUB_EMULATOR_HOST")
+		d := NewPubSubDispatcher("projects/p/topics/slackbot")
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", got.Header.Get("Authorization"))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		status = http.StatusNotFound
+		d := &PubSubDispatcher{Topic: "projects/p/topics/slackbot", Endpoint: server.URL}
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{}})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestCloudTasksDispatcher(t *testing.T) {
+	var got *http.Request
+	var body map[string]map[string]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	testRun := ToolsCreateTestRun(nil, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		d := NewCloudTasksDispatcher("projects/p/locations/l/queues/slackbot", "https://worker.example.com/")
+		d.Endpoint = server.URL
+		d.Token = nil
+		d.ServiceAccountEmail = "worker@p.iam.gserviceaccount.com"
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{"text": "test"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/v2/projects/p/locations/l/queues/slackbot/tasks", got.URL.Path)
+		request := body["task"]["httpRequest"]
+		assert.Equal(t, "https://worker.example.com/", request["url"])
+		assert.Equal(t, "POST", request["httpMethod"])
+		data, _ := base64.StdEncoding.DecodeString(request["body"].(string))
+		assert.JSONEq(t, `{"type":"message","event":{"text":"test"}}`, string(data))
+		assert.Equal(t, "worker@p.iam.gserviceaccount.com", request["oidcToken"].(map[string]interface{})["serviceAccountEmail"])
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		d := NewCloudTasksDispatcher("projects/p/locations/l/queues/slackbot", "https://worker.example.com/")
+		d.Endpoint = server.URL
+		d.Token = func(ctx context.Context) (string, error) {
+			return "", assert.AnError
+		}
+
+		err := d.Dispatch(context.Background(), &Task{Type: TaskMessage, Event: Event{}})
+
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestOnWorker(t *testing.T) {
+	token := verificationToken
+	defer func() {
+		verificationToken = token
+	}()
+	verificationToken = "secret"
+
+	var texts []string
+	clear := func() {
+		ToolsInitCommand()
+		texts = []string{}
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				texts = append(texts, e.Text())
+			},
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, ToolsInitCommand)
+	call := func(body string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+		OnWorker(rec, req)
+		return rec.Code
+	}
+
+	taskJSON := func(typeName, text string) string {
+		data, _ := json.Marshal(newTask(typeName, Event{"text": text}, nil))
+		return string(data)
+	}
+
+	testRun(t, "pubsub test", func(t *testing.T) {
+		data := base64.StdEncoding.EncodeToString([]byte(taskJSON(TaskAppMention, "test pubsub")))
+
+		code := call(`{"message":{"data":"` + data + `","messageId":"1"},"subscription":"projects/p/subscriptions/s"}`)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"test pubsub"}, texts)
+	})
+
+	testRun(t, "cloud tasks test", func(t *testing.T) {
+		code := call(taskJSON(TaskAppMention, "test tasks"))
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"test tasks"}, texts)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, call(`{"message":{"data":"%"}}`))
+		assert.Equal(t, http.StatusBadRequest, call(`invalid`))
+		assert.Equal(t, http.StatusBadRequest, call(taskJSON("unknown", "test")))
+		assert.Empty(t, texts)
+	})
+
+	testRun(t, "forged test", func(t *testing.T) {
+		data := base64.StdEncoding.EncodeToString([]byte(`{"type":"app_mention","event":{"text":"test","user":"UADMIN"}}`))
+		assert.Equal(t, http.StatusBadRequest, call(`{"message":{"data":"`+data+`","messageId":"1"}}`))
+		assert.Equal(t, http.StatusBadRequest, call(`{"type":"app_mention","event":{"text":"test"}}`))
+		assert.Empty(t, texts)
+	})
+}
+
+func TestGCPMetadataToken(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Empty(t, body)
+		w.Write([]byte(`{"access_token":"token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+	defer os.Unsetenv("GCE_METADATA_HOST")
+
+	testRun := ToolsCreateTestRun(func() {
+		gcpToken = ""
+		gcpTokenExpires = time.Time{}
+		calls = 0
+	}, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		token, err := gcpMetadataToken(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "token", token)
+
+		// cached
+		token, err = gcpMetadataToken(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "token", token)
+		assert.Equal(t, 1, calls)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		os.Setenv("GCE_METADATA_HOST", "127.0.0.1:0")
+
+		_, err := gcpMetadataToken(context.Background())
+
+		assert.Error(t, err)
+	})
+}
@@ -3,8 +3,18 @@ module github.com/peto-tn/slackbot-go
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/apex/gateway v1.1.1
-	github.com/aws/aws-lambda-go v1.13.3
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/nlopes/slack v0.6.0
-	github.com/stretchr/testify v1.4.0
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/stretchr/testify v1.8.4
+	github.com/tj/assert v0.0.3 // indirect
+	go.etcd.io/bbolt v1.3.7
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+	golang.org/x/text v0.3.8
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,19 +1,30 @@
 package slackbot
 
 import (
-	"log"
+	"bytes"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/nlopes/slack"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	slackBotUserID    string
 	verificationToken string
 	accessToken       string
+	signingSecret     string
 
-	api *slack.Client
+	api Client
 )
 
 // Setup slackbot.
@@ -30,22 +41,116 @@ func Setup(argBotUserID, argVerificationToken, argAccessToken string) {
 	}
 
 	// create slack client
-	api = slack.New(accessToken)
+	api = newSetupClient()
 
 	// setup default command
 	SetupCommand([]*Command{})
 }
 
+// SetSigningSecret for verifying the signature of requests from Slack.
+// The signature is not verified if empty.
+func SetSigningSecret(secret string) {
+	signingSecret = secret
+}
+
+// requestIDHeader of the request ID attached to the log. It is generated if not set.
+const requestIDHeader = "X-Request-Id"
+
 // OnCall is receive slack events handler.
 func OnCall(w http.ResponseWriter, r *http.Request) {
-	if api == nil {
-		Setup(
-			os.Getenv("SLACK_BOT_USER_ID"),
-			os.Getenv("SLACK_VERIFICATION_TOKEN"),
-			os.Getenv("SLACK_ACCESS_TOKEN"),
-		)
-	}
-	p, err := DecodeJSON(r.Body)
+	serve(w, r, onCall)
+}
+
+// serve the request by the handler with the request ID, the span and the metrics.
+func serve(w http.ResponseWriter, r *http.Request, handle http.HandlerFunc) {
+	start := time.Now()
+	if r.Header.Get(requestIDHeader) == "" {
+		r.Header.Set(requestIDHeader, newRequestID())
+	}
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := startSpan(ctx, "slackbot.request", trace.SpanKindServer,
+		attribute.String("http.method", r.Method),
+		attribute.String("http.target", r.URL.Path),
+		attribute.String("slackbot.request_id", r.Header.Get(requestIDHeader)),
+	)
+	recorder := &statusRecorder{ResponseWriter: w}
+	defer func() {
+		status := selectInt(recorder.status != 0, recorder.status, http.StatusOK)
+		metricRequestDuration.observe(time.Since(start), strconv.Itoa(status))
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	}()
+
+	handle(recorder, r.WithContext(ctx))
+}
+
+// setupFromEnv by the config file of ConfigEnv and the environment variables if not set up yet.
+// SelfCheck runs once per process, which resolves the bot user ID and compares the scopes. Returns ConfigError if the config is invalid.
+func setupFromEnv() error {
+	if api != nil {
+		return nil
+	}
+	if err := setupConfigFromEnv(false); err != nil {
+		return err
+	}
+	SelfCheck()
+	return nil
+}
+
+// setupConfigFromEnv by the config file of ConfigEnv and the environment variables if not set up yet.
+// The worker config is rejected unless by Server, because nothing else runs the workers.
+func setupConfigFromEnv(server bool) error {
+	if api != nil {
+		return nil
+	}
+	c, err := LoadConfig(os.Getenv(ConfigEnv))
+	if err != nil {
+		return err
+	}
+	if !server && c.Worker.Concurrency > 0 {
+		return &ConfigError{Errors: []string{"worker.concurrency: only supported by Server"}}
+	}
+	SetupConfig(c)
+	return nil
+}
+
+// setupRequest from the environment variables. Responds 500 and returns false if failed.
+func setupRequest(w http.ResponseWriter) bool {
+	if err := setupFromEnv(); err != nil {
+		logError(nil, "setup error", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func onCall(w http.ResponseWriter, r *http.Request) {
+	if !setupRequest(w) {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := verifySignature(r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	onBody(w, r, body)
+}
+
+// onBody of the request, which is the payload of the Events API or a form.
+func onBody(w http.ResponseWriter, r *http.Request, body []byte) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		onForm(w, r, body)
+		return
+	}
+
+	p, err := DecodeJSON(bytes.NewReader(body))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -54,6 +159,7 @@ func OnCall(w http.ResponseWriter, r *http.Request) {
 	typeName := p.Type()
 	switch typeName {
 	case "url_verification":
+		metricEvents.inc(typeName)
 		// verify url response
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusOK)
@@ -62,29 +168,95 @@ func OnCall(w http.ResponseWriter, r *http.Request) {
 
 	case "event_callback":
 		event := p.Event()
+		event["request_id"] = r.Header.Get(requestIDHeader)
+		event["event_id"] = p.String("event_id")
+		event["team_id"] = p.String("team_id")
+		event["enterprise_id"] = p.String("enterprise_id")
+		if event.String("team") == "" {
+			event["team"] = p.String("team_id")
+		}
 		eventName := event.Type()
+		metricEvents.inc(eventName)
+		span := startEventSpan(r, event, eventName)
+		defer span.End()
 		switch eventName {
 		case "message":
 			verifyToken(w, p.Token())
-			if verifyRequest(r) {
-				onMessage(event)
+			if verifyRequest(r, eventName) {
+				handleTask(TaskMessage, event, nil)
 			}
 
 		case "app_mention":
 			verifyToken(w, p.Token())
-			if verifyRequest(r) {
-				onMentionMessage(event)
+			if verifyRequest(r, eventName) {
+				handleTask(TaskAppMention, event, nil)
 			}
 
+		case "reaction_added", "reaction_removed":
+			verifyToken(w, p.Token())
+			if verifyRequest(r, eventName) {
+				handleTask(eventName, event, nil)
+			}
+
+		case "app_uninstalled", "tokens_revoked":
+			verifyToken(w, p.Token())
+			onUninstalled(event, p)
+
 		default:
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Printf("not support event: %s", eventName)
+			logWarn(event, "not support event", "type", eventName)
+			return
+		}
+
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		logWarn(Event{"request_id": r.Header.Get(requestIDHeader)}, "not support type", "type", typeName)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+}
+
+// onForm handles slash commands and interactions, which are sent as forms.
+func onForm(w http.ResponseWriter, r *http.Request, body []byte) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case form.Get("payload") != "":
+		p, err := DecodeJSON(strings.NewReader(form.Get("payload")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		p["request_id"] = r.Header.Get(requestIDHeader)
+		metricEvents.inc(p.Type())
+		ctx, span := startSpan(r.Context(), "slackbot.event "+p.Type(), trace.SpanKindInternal, attribute.String("slackbot.event.type", p.Type()))
+		defer span.End()
+		p.SetContext(ctx)
+		verifyToken(w, p.Token())
+		if verifyRequest(r, p.Type()) {
+			handleTask(TaskInteraction, nil, p)
+		}
+
+	case form.Get("command") != "":
+		metricEvents.inc("slash_command")
+		verifyToken(w, form.Get("token"))
+		if verifyRequest(r, "slash_command") {
+			e := newSlashCommandEvent(form)
+			e["request_id"] = r.Header.Get(requestIDHeader)
+			span := startEventSpan(r, e, "slash_command")
+			defer span.End()
+			handleTask(TaskSlashCommand, e, nil)
+		}
 
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("not support type: %s", typeName)
+		logWarn(Event{"request_id": r.Header.Get(requestIDHeader)}, "not support form", "content_type", r.Header.Get("Content-Type"))
 		return
 	}
 
@@ -92,6 +264,30 @@ func OnCall(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// startEventSpan of the dispatch of the Event. The context of the span is set to the Event.
+func startEventSpan(r *http.Request, e Event, typeName string) trace.Span {
+	ctx, span := startSpan(r.Context(), "slackbot.event "+typeName, trace.SpanKindInternal,
+		attribute.String("slackbot.event.type", typeName),
+		attribute.String("slackbot.channel", e.Channel()),
+		attribute.String("slackbot.user", e.User()),
+	)
+	e.SetContext(ctx)
+	return span
+}
+
+func verifySignature(header http.Header, body []byte) error {
+	if signingSecret == "" {
+		return nil
+	}
+
+	sv, err := slack.NewSecretsVerifier(header, signingSecret)
+	if err != nil {
+		return err
+	}
+	sv.Write(body)
+	return sv.Ensure()
+}
+
 func verifyToken(w http.ResponseWriter, token string) {
 	if token != verificationToken {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -99,9 +295,10 @@ func verifyToken(w http.ResponseWriter, token string) {
 	}
 }
 
-func verifyRequest(r *http.Request) bool {
+func verifyRequest(r *http.Request, typeName string) bool {
 	// ignore retry
 	if _, ok := r.Header["X-Slack-Retry-Num"]; ok {
+		metricRetriesDropped.inc(typeName)
 		return false
 	}
 
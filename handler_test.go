@@ -1,11 +1,16 @@
 package slackbot
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -107,6 +112,75 @@ func TestOnCall(t *testing.T) {
 		assert.True(t, called)
 	})
 
+	testRun(t, "reaction test", func(t *testing.T) {
+		os.Setenv("SLACK_VERIFICATION_TOKEN", "token")
+		handler := &TestReactionHandler{}
+		SetReactionHandler(handler)
+		defer SetReactionHandler(nil)
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader(`{"type":"event_callback", "token":"token", "event":{"type":"reaction_added", "reaction":"eyes"}}`))
+
+		OnCall(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "eyes", handler.Reaction)
+	})
+
+	testRun(t, "slash command test", func(t *testing.T) {
+		os.Setenv("SLACK_VERIFICATION_TOKEN", "token")
+		var text string
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				text = e.Text()
+			},
+		})
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader("token=token&command=%2Fbot&text=test++hoge"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		OnCall(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "test hoge", text)
+	})
+
+	testRun(t, "interaction test", func(t *testing.T) {
+		os.Setenv("SLACK_VERIFICATION_TOKEN", "token")
+		handler := &TestInteractionHandler{}
+		SetInteractionHandler(handler)
+		defer SetInteractionHandler(nil)
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader(`payload=%7B%22type%22%3A%22block_actions%22%2C%22token%22%3A%22token%22%7D`))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		OnCall(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, handler.Called)
+	})
+
+	testRun(t, "not support form test", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader("test=test"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		OnCall(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	testRun(t, "invalid signature test", func(t *testing.T) {
+		SetSigningSecret("secret")
+		defer SetSigningSecret("")
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader(`{"type":"url_verification", "challenge":"test"}`))
+
+		OnCall(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
 	testRun(t, "not support event test", func(t *testing.T) {
 		os.Setenv("SLACK_VERIFICATION_TOKEN", "token")
 		rec := httptest.NewRecorder()
@@ -128,6 +202,38 @@ func TestOnCall(t *testing.T) {
 	})
 }
 
+func TestVerifySignature(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetSigningSecret("")
+	})
+	body := []byte(`{}`)
+	sign := func(secret string) http.Header {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + timestamp + ":"))
+		mac.Write(body)
+		header := http.Header{}
+		header.Set("X-Slack-Request-Timestamp", timestamp)
+		header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		return header
+	}
+
+	testRun(t, "no secret test", func(t *testing.T) {
+		assert.NoError(t, verifySignature(http.Header{}, body))
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetSigningSecret("secret")
+		assert.NoError(t, verifySignature(sign("secret"), body))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetSigningSecret("secret")
+		assert.Error(t, verifySignature(sign("invalid"), body))
+		assert.Error(t, verifySignature(http.Header{}, body))
+	})
+}
+
 func TestVerifyToken(t *testing.T) {
 	clear := func() {
 		verificationToken = ""
@@ -164,7 +270,7 @@ func TestVerifyRequest(t *testing.T) {
 	testRun(t, "normal test", func(t *testing.T) {
 		req, _ := http.NewRequest("POST", "", strings.NewReader(`{}`))
 
-		result := verifyRequest(req)
+		result := verifyRequest(req, "message")
 
 		assert.True(t, result)
 	})
@@ -173,7 +279,7 @@ func TestVerifyRequest(t *testing.T) {
 		req, _ := http.NewRequest("POST", "", strings.NewReader(`{}`))
 		req.Header.Set("X-Slack-Retry-Num", "1")
 
-		result := verifyRequest(req)
+		result := verifyRequest(req, "message")
 
 		assert.False(t, result)
 	})
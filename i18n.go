@@ -0,0 +1,211 @@
+package slackbot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Messages of a locale keyed by message ID.
+// HelpMessage, Category and help tag of Command are also translated by using them as message IDs.
+type Messages map[string]string
+
+// Message IDs of the bot.
+const (
+	MessageOptionError          = "option_error"
+	MessageCommandNotFound      = "command_not_found"
+	MessageHelpOptions          = "help_options"
+	MessageHelpExamples         = "help_examples"
+	MessagePong                 = "pong"
+	MessageSessionCanceled      = "session_canceled"
+	MessageConfirmSummary       = "confirm_summary"
+	MessageConfirmButton        = "confirm_button"
+	MessageCancelButton         = "cancel_button"
+	MessageConfirmConfirmed     = "confirm_confirmed"
+	MessageConfirmCanceled      = "confirm_canceled"
+	MessageConfirmExpired       = "confirm_expired"
+	MessageConfirmNotFound      = "confirm_not_found"
+	MessageConfirmNotPermitted  = "confirm_not_permitted"
+	MessageCommandNotAllowed    = "command_not_allowed"
+	MessageUsage                = "usage"
+	MessageError                = "error"
+	MessageRemindScheduled      = "remind_scheduled"
+	MessageScheduleAdded        = "schedule_added"
+	MessageScheduleEmpty        = "schedule_empty"
+	MessageScheduleNotFound     = "schedule_not_found"
+	MessageScheduleNotPermitted = "schedule_not_permitted"
+	MessageScheduleDeleted      = "schedule_deleted"
+)
+
+var englishMessages = Messages{
+	MessageOptionError:          "option error.",
+	MessageCommandNotFound:      "command not found: %s",
+	MessageHelpOptions:          "Options",
+	MessageHelpExamples:         "Examples",
+	MessagePong:                 "pong! :table_tennis_paddle_and_ball:",
+	MessageSessionCanceled:      "Canceled.",
+	MessageConfirmSummary:       "Run `%s`?",
+	MessageConfirmButton:        "Confirm",
+	MessageCancelButton:         "Cancel",
+	MessageConfirmConfirmed:     "`%s` was confirmed by <@%s>.",
+	MessageConfirmCanceled:      "`%s` was canceled by <@%s>.",
+	MessageConfirmExpired:       "`%s` has expired.",
+	MessageConfirmNotFound:      "This confirmation has expired.",
+	MessageConfirmNotPermitted:  "Only <@%s> can confirm.",
+	MessageCommandNotAllowed:    "You are not allowed to run `%s` here.",
+	MessageUsage:                "usage: %s",
+	MessageError:                "error: %s",
+	MessageRemindScheduled:      "I will remind you at %s. (id: %s)",
+	MessageScheduleAdded:        "Scheduled %s. Next run at %s.",
+	MessageScheduleEmpty:        "No schedules.",
+	MessageScheduleNotFound:     "error: schedule not found: %s",
+	MessageScheduleNotPermitted: "error: only <@%s> can delete the schedule.",
+	MessageScheduleDeleted:      "Deleted %s.",
+}
+
+var japaneseMessages = Messages{
+	MessageOptionError:          "オプションが正しくありません。",
+	MessageCommandNotFound:      "コマンドが見つかりません: %s",
+	MessageHelpOptions:          "オプション",
+	MessageHelpExamples:         "例",
+	MessagePong:                 "pong! :table_tennis_paddle_and_ball:",
+	MessageSessionCanceled:      "キャンセルしました。",
+	MessageConfirmSummary:       "`%s` を実行しますか？",
+	MessageConfirmButton:        "実行",
+	MessageCancelButton:         "キャンセル",
+	MessageConfirmConfirmed:     "`%s` は <@%s> によって実行されました。",
+	MessageConfirmCanceled:      "`%s` は <@%s> によってキャンセルされました。",
+	MessageConfirmExpired:       "`%s` は期限切れです。",
+	MessageConfirmNotFound:      "この確認は期限切れです。",
+	MessageConfirmNotPermitted:  "<@%s> のみ実行できます。",
+	MessageCommandNotAllowed:    "ここでは `%s` を実行できません。",
+	MessageUsage:                "使い方: %s",
+	MessageError:                "エラー: %s",
+	MessageRemindScheduled:      "%s にリマインドします。(id: %s)",
+	MessageScheduleAdded:        "%s を登録しました。次回の実行は %s です。",
+	MessageScheduleEmpty:        "スケジュールはありません。",
+	MessageScheduleNotFound:     "エラー: スケジュールが見つかりません: %s",
+	MessageScheduleNotPermitted: "エラー: <@%s> のみ削除できます。",
+	MessageScheduleDeleted:      "%s を削除しました。",
+
+	"Displays all of the help commands.":   "コマンドの一覧を表示します。",
+	"Reply pong.":                          "pong を返します。",
+	"true, false or the command name.":     "true、false またはコマンド名。",
+	"Schedule a command. " + scheduleUsage: "コマンドを定期実行します。 " + scheduleUsage,
+	"Remind a message. " + remindUsage:     "メッセージをリマインドします。 " + remindUsage,
+}
+
+const (
+	localeNamespace = "locale"
+
+	// userLocaleTTL of the Slack locale of the user cached in Store.
+	userLocaleTTL = time.Hour
+)
+
+var (
+	catalog = map[string]Messages{
+		"en": englishMessages,
+		"ja": japaneseMessages,
+	}
+	catalogMu     sync.RWMutex
+	defaultLocale = "en"
+)
+
+// AddMessages of the locale. Messages are merged with those already added.
+func AddMessages(locale string, messages Messages) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	merged := Messages{}
+	for id, message := range catalog[locale] {
+		merged[id] = message
+	}
+	for id, message := range messages {
+		merged[id] = message
+	}
+	catalog[locale] = merged
+}
+
+// SetDefaultLocale for slackbot. "en" is used by default.
+func SetDefaultLocale(locale string) {
+	defaultLocale = locale
+}
+
+// SetChannelLocale used for the messages in the channel instead of the locale of the user.
+// An empty locale removes the setting.
+func SetChannelLocale(channel, locale string) error {
+	if locale == "" {
+		return GetStore().Delete(localeNamespace, "channel:"+channel)
+	}
+	return GetStore().Set(localeNamespace, "channel:"+channel, []byte(locale), 0)
+}
+
+// Locale of the Event.
+// The locale of the channel is used if set, then the Slack locale of the user, then the default locale.
+func Locale(e Event) string {
+	if channel := e.Channel(); channel != "" {
+		if locale, err := GetStore().Get(localeNamespace, "channel:"+channel); err == nil {
+			return string(locale)
+		}
+	}
+	if locale := userLocale(e); locale != "" {
+		return locale
+	}
+	return defaultLocale
+}
+
+// userLocale of the user of the Event from Slack, cached in Store.
+func userLocale(e Event) string {
+	client := clientFor(e.Context())
+	user := e.User()
+	if client == nil || user == "" {
+		return ""
+	}
+	if locale, err := GetStore().Get(localeNamespace, "user:"+user); err == nil {
+		return string(locale)
+	}
+
+	info, err := client.GetUserInfo(user)
+	if err != nil {
+		logWarn(Event{"user": user}, "locale error", "error", err)
+		return ""
+	}
+	if err := GetStore().Set(localeNamespace, "user:"+user, []byte(info.Locale), userLocaleTTL); err != nil {
+		logWarn(Event{"user": user}, "locale error", "error", err)
+	}
+	return info.Locale
+}
+
+// Translate the message ID to the locale, formatted with args.
+// The language of the locale such as "ja" of "ja-JP" and the default locale are tried in order,
+// and the message ID itself is used if not found.
+func Translate(locale, id string, args ...interface{}) string {
+	message := lookupMessage(locale, id)
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// T translates the message ID to the locale of the Event.
+func T(e Event, id string, args ...interface{}) string {
+	return Translate(Locale(e), id, args...)
+}
+
+func lookupMessage(locale, id string) string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	locales := []string{locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locales = append(locales, locale[:i])
+	}
+	locales = append(locales, defaultLocale, "en")
+	for _, l := range locales {
+		if message, ok := catalog[l][id]; ok {
+			return message
+		}
+	}
+	return id
+}
@@ -0,0 +1,117 @@
+package slackbot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetDefaultLocale("en")
+		catalogMu.Lock()
+		delete(catalog, "fr")
+		catalogMu.Unlock()
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		assert.Equal(t, "option error.", Translate("en", MessageOptionError))
+		assert.Equal(t, "オプションが正しくありません。", Translate("ja", MessageOptionError))
+		assert.Equal(t, "command not found: test", Translate("en", MessageCommandNotFound, "test"))
+	})
+
+	testRun(t, "language test", func(t *testing.T) {
+		assert.Equal(t, "オプションが正しくありません。", Translate("ja-JP", MessageOptionError))
+		assert.Equal(t, "オプションが正しくありません。", Translate("ja_JP", MessageOptionError))
+	})
+
+	testRun(t, "fallback test", func(t *testing.T) {
+		assert.Equal(t, "option error.", Translate("fr-FR", MessageOptionError))
+		assert.Equal(t, "undefined message", Translate("ja", "undefined message"))
+
+		SetDefaultLocale("ja")
+		assert.Equal(t, "オプションが正しくありません。", Translate("fr-FR", MessageOptionError))
+	})
+
+	testRun(t, "add test", func(t *testing.T) {
+		AddMessages("fr", Messages{MessagePong: "pong!"})
+		AddMessages("fr", Messages{"Deploy.": "Déployer."})
+
+		assert.Equal(t, "pong!", Translate("fr-FR", MessagePong))
+		assert.Equal(t, "Déployer.", Translate("fr-FR", "Deploy."))
+		assert.Equal(t, "option error.", Translate("fr-FR", MessageOptionError))
+	})
+}
+
+func TestLocalizedHelp(t *testing.T) {
+	t.Parallel()
+
+	command := &Command{
+		Name:        "test",
+		HelpMessage: "Reply pong.",
+		Examples:    []string{"test true"},
+		Option: struct {
+			Desc string `default:"true" help:"true, false or the command name."`
+		}{},
+	}
+	assert.Equal(t, "test [Desc(*true*)] : *_pong を返します。_*", localizedHelp(command, true, "ja"))
+	assert.Equal(t, "test [Desc(*true*)]\npong を返します。\n*オプション*\n• Desc(*true*) : true、false またはコマンド名。\n*例*\n• `test true`", localizedHelpDetail(command, "ja"))
+}
+
+func TestLocale(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		fake.Reset()
+		Setup("bot", "", "")
+		SetStore(nil)
+		SetDefaultLocale("en")
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	userInfo := func(locale string) {
+		fake.SetResponse("users.info", map[string]interface{}{
+			"ok":   true,
+			"user": map[string]interface{}{"id": "U1", "locale": locale},
+		})
+	}
+
+	testRun(t, "user test", func(t *testing.T) {
+		userInfo("ja-JP")
+		assert.Equal(t, "ja-JP", Locale(Event{"channel": "C1", "user": "U1"}))
+		assert.Equal(t, "true", fake.CallsFor("users.info")[0].Param("include_locale"))
+
+		// cached
+		assert.Equal(t, "ja-JP", Locale(Event{"channel": "C1", "user": "U1"}))
+		assert.Len(t, fake.CallsFor("users.info"), 1)
+	})
+
+	testRun(t, "channel test", func(t *testing.T) {
+		userInfo("en-US")
+		assert.NoError(t, SetChannelLocale("C1", "ja"))
+		assert.Equal(t, "ja", Locale(Event{"channel": "C1", "user": "U1"}))
+		assert.Equal(t, "en-US", Locale(Event{"channel": "C2", "user": "U1"}))
+
+		assert.NoError(t, SetChannelLocale("C1", ""))
+		assert.Equal(t, "en-US", Locale(Event{"channel": "C1", "user": "U1"}))
+	})
+
+	testRun(t, "default test", func(t *testing.T) {
+		SetDefaultLocale("ja")
+		fake.SetError("users.info", "user_not_found")
+		assert.Equal(t, "ja", Locale(Event{"channel": "C1", "user": "U1"}))
+		assert.Equal(t, "ja", Locale(Event{"channel": "C1"}))
+	})
+
+	testRun(t, "command test", func(t *testing.T) {
+		userInfo("ja-JP")
+		ToolsInitCommand()
+		defer ToolsInitCommand()
+		AddCommand(&Command{Name: "test", Option: struct {
+			Desc string `choice:"false,true"`
+		}{}})
+
+		onMessage(Event{"channel": "C1", "user": "U1", "event_ts": "1.0", "text": "test"})
+		assert.Equal(t, "オプションが正しくありません。\ntest [Desc(false,true)]", fake.CallsFor("chat.postMessage")[0].Param("text"))
+	})
+}
@@ -0,0 +1,223 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Installation of the bot in a workspace or an organization by the OAuth flow.
+type Installation struct {
+	EnterpriseID        string    `json:"enterprise_id,omitempty"`
+	EnterpriseName      string    `json:"enterprise_name,omitempty"`
+	TeamID              string    `json:"team_id,omitempty"`
+	TeamName            string    `json:"team_name,omitempty"`
+	IsEnterpriseInstall bool      `json:"is_enterprise_install,omitempty"`
+	AppID               string    `json:"app_id"`
+	BotToken            string    `json:"bot_token"`
+	BotRefreshToken     string    `json:"bot_refresh_token,omitempty"`
+	BotTokenExpiresAt   time.Time `json:"bot_token_expires_at,omitempty"`
+	BotUserID           string    `json:"bot_user_id"`
+	BotScopes           string    `json:"bot_scopes"`
+	UserID              string    `json:"user_id"`
+	InstalledAt         time.Time `json:"installed_at"`
+}
+
+// InstallationStore saves the Installations keyed by the enterprise ID and the team ID.
+// The team ID is empty for an installation to an organization.
+type InstallationStore interface {
+	SaveInstallation(i *Installation) error
+	FindInstallation(enterpriseID, teamID string) (*Installation, error)
+	DeleteInstallation(enterpriseID, teamID string) error
+}
+
+var (
+	installationStore InstallationStore
+
+	// teamClients cached by the token.
+	teamClients   = map[string]Client{}
+	teamClientsMu sync.Mutex
+)
+
+// SetInstallationStore for multiple workspaces.
+// Events are handled with the client of the installation for the team of the event instead of the one created in Setup.
+func SetInstallationStore(s InstallationStore) {
+	installationStore = s
+}
+
+// GetInstallationStore of slackbot. Returns nil for a single workspace.
+func GetInstallationStore() InstallationStore {
+	return installationStore
+}
+
+const installationNamespace = "installation"
+
+// storeInstallationStore saves the Installations in Store.
+type storeInstallationStore struct{}
+
+// NewInstallationStore saving the Installations in the Store of the bot.
+func NewInstallationStore() InstallationStore {
+	return &storeInstallationStore{}
+}
+
+func installationKey(enterpriseID, teamID string) string {
+	return enterpriseID + ":" + teamID
+}
+
+func (s *storeInstallationStore) SaveInstallation(i *Installation) error {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	teamID := i.TeamID
+	if i.IsEnterpriseInstall {
+		teamID = ""
+	}
+	return GetStore().Set(installationNamespace, installationKey(i.EnterpriseID, teamID), data, 0)
+}
+
+func (s *storeInstallationStore) FindInstallation(enterpriseID, teamID string) (*Installation, error) {
+	data, err := GetStore().Get(installationNamespace, installationKey(enterpriseID, teamID))
+	if err != nil {
+		return nil, err
+	}
+	i := &Installation{}
+	if err := json.Unmarshal(data, i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (s *storeInstallationStore) DeleteInstallation(enterpriseID, teamID string) error {
+	return GetStore().Delete(installationNamespace, installationKey(enterpriseID, teamID))
+}
+
+// findInstallation of the team, or of the organization if the team is not installed.
+func findInstallation(enterpriseID, teamID string) (*Installation, error) {
+	i, err := installationStore.FindInstallation(enterpriseID, teamID)
+	if err == ErrNotFound && enterpriseID != "" && teamID != "" {
+		return installationStore.FindInstallation(enterpriseID, "")
+	}
+	return i, err
+}
+
+type teamContextKey struct{}
+
+// teamContext of the client and the bot user ID of the installation.
+type teamContext struct {
+	client    Client
+	botUserID string
+}
+
+// withTeam returns the context with the installation for the team if InstallationStore is set.
+func withTeam(ctx context.Context, enterpriseID, teamID string) context.Context {
+	if installationStore == nil || (enterpriseID == "" && teamID == "") {
+		return ctx
+	}
+
+	i, err := findInstallation(enterpriseID, teamID)
+	if err != nil {
+		logWarn(Event{"team": teamID}, "installation not found", "enterprise", enterpriseID, "error", err)
+		return ctx
+	}
+	return context.WithValue(ctx, teamContextKey{}, &teamContext{client: teamClient(i), botUserID: i.BotUserID})
+}
+
+// teamClient of the Installation, which is created once for the bot token.
+func teamClient(i *Installation) Client {
+	teamClientsMu.Lock()
+	defer teamClientsMu.Unlock()
+	if c, ok := teamClients[i.BotToken]; ok {
+		return c
+	}
+	c := newInstallationClient(i)
+	teamClients[i.BotToken] = c
+	return c
+}
+
+// installedTeam receiving the Event, rather than the team of the user which differs in shared channels.
+func installedTeam(e Event) string {
+	if teamID := e.String("team_id"); teamID != "" {
+		return teamID
+	}
+	return e.String("team")
+}
+
+// routeEvent to the installation for the team of the Event.
+func routeEvent(e Event) {
+	if installationStore != nil {
+		e.SetContext(withTeam(e.Context(), e.String("enterprise_id"), installedTeam(e)))
+	}
+}
+
+// routePayload to the installation for the team of the Payload.
+func routePayload(p Payload) {
+	if installationStore != nil {
+		p.SetContext(withTeam(p.Context(), p.Object("enterprise").String("id"), p.Object("team").String("id")))
+	}
+}
+
+// clientFor the context. The client of the installation is returned if routed, otherwise the one created in Setup.
+func clientFor(ctx context.Context) Client {
+	if tc, ok := ctx.Value(teamContextKey{}).(*teamContext); ok {
+		return tc.client
+	}
+	return api
+}
+
+// botUserIDFor the context. The bot user of the installation is returned if routed, otherwise the one of Setup.
+func botUserIDFor(ctx context.Context) string {
+	if tc, ok := ctx.Value(teamContextKey{}).(*teamContext); ok {
+		return tc.botUserID
+	}
+	return slackBotUserID
+}
+
+// ClientFor the Event, to call the Web API in commands.
+// The client of the installation of its team is returned for multiple workspaces, otherwise the one created in Setup.
+func ClientFor(e Event) Client {
+	return clientFor(e.Context())
+}
+
+// BotUserIDFor the Event, which is the bot user of the installation of its team for multiple workspaces.
+func BotUserIDFor(e Event) string {
+	return botUserIDFor(e.Context())
+}
+
+// ClientForPayload of the interaction, in the same way as ClientFor.
+func ClientForPayload(p Payload) Client {
+	return clientFor(p.Context())
+}
+
+// onUninstalled deletes the installation for the team of the app_uninstalled event,
+// or of the tokens_revoked event if the bot token is revoked.
+func onUninstalled(e Event, p Payload) {
+	if installationStore == nil {
+		return
+	}
+	if e.Type() == "tokens_revoked" {
+		if bots, _ := Payload(e).Object("tokens")["bot"].([]interface{}); len(bots) == 0 {
+			return
+		}
+	}
+
+	enterpriseID := p.String("enterprise_id")
+	teamID := p.String("team_id")
+	if auths, ok := p["authorizations"].([]interface{}); ok && len(auths) > 0 {
+		if auth, ok := auths[0].(map[string]interface{}); ok && auth["is_enterprise_install"] == true {
+			teamID = ""
+		}
+	}
+
+	if i, err := installationStore.FindInstallation(enterpriseID, teamID); err == nil {
+		teamClientsMu.Lock()
+		delete(teamClients, i.BotToken)
+		teamClientsMu.Unlock()
+	}
+	if err := installationStore.DeleteInstallation(enterpriseID, teamID); err != nil && err != ErrNotFound {
+		logError(e, "installation error", "enterprise", enterpriseID, "error", err)
+		return
+	}
+	logInfo(e, "installation deleted", "enterprise", enterpriseID)
+}
@@ -0,0 +1,173 @@
+package slackbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ToolsClearInstallation() {
+	SetInstallationStore(nil)
+	SetStore(nil)
+	teamClients = map[string]Client{}
+}
+
+func TestInstallationStore(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearInstallation, ToolsClearInstallation)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		s := NewInstallationStore()
+		assert.NoError(t, s.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1"}))
+
+		i, err := s.FindInstallation("", "T1")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-1", i.BotToken)
+
+		assert.NoError(t, s.DeleteInstallation("", "T1"))
+		_, err = s.FindInstallation("", "T1")
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	testRun(t, "enterprise test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{EnterpriseID: "E1", TeamID: "T1", IsEnterpriseInstall: true, BotToken: "xoxb-org"})
+
+		i, err := findInstallation("E1", "T2")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-org", i.BotToken)
+
+		_, err = findInstallation("", "T2")
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestInstallation_OnCall(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		ToolsClearInstallation()
+		ToolsInitCommand()
+		fake.Reset()
+		Setup("bot", "token", "xoxb-default")
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				PostMessage(e, "ok")
+			},
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		ToolsClearInstallation()
+		ToolsInitCommand()
+		verificationToken = ""
+	})
+	call := func(body string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "", strings.NewReader(body))
+		OnCall(rec, req)
+		return rec.Code
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "UBOT1"})
+		installationStore.SaveInstallation(&Installation{TeamID: "T2", BotToken: "xoxb-2", BotUserID: "UBOT2"})
+
+		code := call(`{"type":"event_callback", "token":"token", "team_id":"T2", "event":{"type":"app_mention", "channel":"C1", "text":"<@UBOT2> test", "team":"T1"}}`)
+		assert.Equal(t, http.StatusOK, code)
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "xoxb-2", calls[0].Param("token"))
+	})
+
+	testRun(t, "client for test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "UBOT1"})
+		var botUserID string
+		AddCommand(&Command{
+			Name: "whoami",
+			Execute: func(e Event, opt interface{}) {
+				botUserID = BotUserIDFor(e)
+				ClientFor(e).GetUserInfo("U1")
+			},
+		})
+
+		call(`{"type":"event_callback", "token":"token", "team_id":"T1", "event":{"type":"app_mention", "channel":"C1", "text":"<@UBOT1> whoami"}}`)
+
+		assert.Equal(t, "UBOT1", botUserID)
+		calls := fake.CallsFor("users.info")
+		if assert.Len(t, calls, 1) {
+			assert.Equal(t, "xoxb-1", calls[0].Param("token"))
+		}
+		assert.Equal(t, GetClient(), ClientFor(Event{}))
+	})
+
+	testRun(t, "not installed test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+
+		code := call(`{"type":"event_callback", "token":"token", "team_id":"T1", "event":{"type":"app_mention", "channel":"C1", "text":"<@bot> test"}}`)
+		assert.Equal(t, http.StatusOK, code)
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "xoxb-default", calls[0].Param("token"))
+	})
+
+	testRun(t, "app uninstalled test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1"})
+
+		code := call(`{"type":"event_callback", "token":"token", "team_id":"T1", "event":{"type":"app_uninstalled"}}`)
+		assert.Equal(t, http.StatusOK, code)
+
+		_, err := installationStore.FindInstallation("", "T1")
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	testRun(t, "tokens revoked test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1"})
+
+		// user tokens only
+		call(`{"type":"event_callback", "token":"token", "team_id":"T1", "event":{"type":"tokens_revoked", "tokens":{"oauth":["U1"]}}}`)
+		_, err := installationStore.FindInstallation("", "T1")
+		assert.NoError(t, err)
+
+		call(`{"type":"event_callback", "token":"token", "team_id":"T1", "event":{"type":"tokens_revoked", "tokens":{"bot":["UBOT1"]}}}`)
+		_, err = installationStore.FindInstallation("", "T1")
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestInstallation_Schedule(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		ToolsClearInstallation()
+		ToolsClearSchedule()
+		fake.Reset()
+		Setup("bot", "", "xoxb-default")
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1"})
+		AddScheduleJob(&Schedule{ID: "s1", Spec: "@daily", Channel: "C1", Team: "T1", Job: func(ctx context.Context, e Event) {
+			PostMessage(e, "ok")
+		}})
+
+		assert.True(t, RunSchedule("s1"))
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "xoxb-1", calls[0].Param("token"))
+	})
+}
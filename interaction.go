@@ -0,0 +1,24 @@
+package slackbot
+
+// InteractionHandler for Slack
+type InteractionHandler interface {
+	OnInteraction(p Payload)
+}
+
+var (
+	interactionHandler InteractionHandler
+)
+
+// SetInteractionHandler for slackbot
+func SetInteractionHandler(handler InteractionHandler) {
+	interactionHandler = handler
+}
+
+func onInteraction(p Payload) {
+	if onConfirmAction(p) {
+		return
+	}
+	if interactionHandler != nil {
+		interactionHandler.OnInteraction(p)
+	}
+}
@@ -0,0 +1,40 @@
+package slackbot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestInteractionHandler struct {
+	Called bool
+}
+
+func (h *TestInteractionHandler) OnInteraction(p Payload) {
+	h.Called = true
+}
+
+func TestSetInteractionHandler(t *testing.T) {
+	handler := &TestInteractionHandler{}
+	SetInteractionHandler(handler)
+	defer SetInteractionHandler(nil)
+
+	assert.Equal(t, handler, interactionHandler)
+}
+
+func TestOnInteraction(t *testing.T) {
+	handler := &TestInteractionHandler{}
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetInteractionHandler(nil)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetInteractionHandler(handler)
+		onInteraction(Payload{})
+		assert.True(t, handler.Called)
+	})
+
+	testRun(t, "no handler test", func(t *testing.T) {
+		onInteraction(Payload{})
+	})
+}
@@ -0,0 +1,161 @@
+package slackbot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LogLevel of the log. The values are the same as log/slog.
+type LogLevel int
+
+// LogLevels of slackbot.
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch {
+	case l < LogLevelInfo:
+		return "DEBUG"
+	case l < LogLevelWarn:
+		return "INFO"
+	case l < LogLevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger of slackbot. keyvals are pairs of a key and a value.
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+var (
+	logger   Logger = NewStdLogger(nil)
+	logLevel        = LogLevelInfo
+
+	// logEventKeys of Event attached to the log.
+	logEventKeys = []string{"request_id", "event_id", "team", "channel", "user"}
+
+	redacted         = "[REDACTED]"
+	secretKeyRegexp  = regexp.MustCompile(`(?i)(token|secret|password|authorization)`)
+	secretTextRegexp = regexp.MustCompile(`xox[abposre]-[0-9A-Za-z-]+|xapp-[0-9A-Za-z-]+`)
+)
+
+// SetLogger for slackbot. The log package is used by default.
+func SetLogger(l Logger) {
+	logger = l
+}
+
+// SetLogLevel for slackbot. Logs below the level are discarded.
+func SetLogLevel(level LogLevel) {
+	logLevel = level
+}
+
+// logEvent with the request ID, event ID, team, channel and user of the Event.
+// Secrets such as tokens are redacted.
+func logEvent(level LogLevel, e Event, msg string, keyvals ...interface{}) {
+	if level < logLevel || logger == nil {
+		return
+	}
+
+	fields := []interface{}{}
+	for _, key := range logEventKeys {
+		if value := e.String(key); value != "" {
+			fields = append(fields, key, value)
+		}
+	}
+	fields = append(fields, keyvals...)
+	if len(fields)%2 != 0 {
+		fields = append(fields, "")
+	}
+	for i := 0; i < len(fields); i += 2 {
+		fields[i+1] = redact(fmt.Sprint(fields[i]), fields[i+1])
+	}
+
+	logger.Log(level, redactText(msg), fields...)
+}
+
+func logDebug(e Event, msg string, keyvals ...interface{}) {
+	logEvent(LogLevelDebug, e, msg, keyvals...)
+}
+
+func logInfo(e Event, msg string, keyvals ...interface{}) {
+	logEvent(LogLevelInfo, e, msg, keyvals...)
+}
+
+func logWarn(e Event, msg string, keyvals ...interface{}) {
+	logEvent(LogLevelWarn, e, msg, keyvals...)
+}
+
+func logError(e Event, msg string, keyvals ...interface{}) {
+	logEvent(LogLevelError, e, msg, keyvals...)
+}
+
+// redact the value if the key is secret or the value looks like a token.
+func redact(key string, value interface{}) interface{} {
+	if secretKeyRegexp.MatchString(key) {
+		return redacted
+	}
+	switch v := value.(type) {
+	case string:
+		return redactText(v)
+	case error:
+		return redactText(v.Error())
+	case fmt.Stringer:
+		return redactText(v.String())
+	}
+	return value
+}
+
+func redactText(text string) string {
+	return secretTextRegexp.ReplaceAllString(text, redacted)
+}
+
+// newRequestID for the log of a request without X-Request-Id.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// stdLogger writes the log in logfmt by the log package.
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger of the log package. The standard logger is used if l is nil.
+func NewStdLogger(l *log.Logger) Logger {
+	return &stdLogger{l: l}
+}
+
+func (s *stdLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	line := "level=" + level.String() + " msg=" + logfmtValue(msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		line += fmt.Sprintf(" %v=%s", keyvals[i], logfmtValue(fmt.Sprint(keyvals[i+1])))
+	}
+
+	if s.l == nil {
+		log.Print(line)
+	} else {
+		s.l.Print(line)
+	}
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,136 @@
+package slackbot
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLog struct {
+	Level   LogLevel
+	Msg     string
+	Keyvals []interface{}
+}
+
+type TestLogger struct {
+	Logs []testLog
+}
+
+func (l *TestLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	l.Logs = append(l.Logs, testLog{Level: level, Msg: msg, Keyvals: keyvals})
+}
+
+func ToolsSetTestLogger() *TestLogger {
+	l := &TestLogger{}
+	SetLogger(l)
+	return l
+}
+
+func ToolsResetLogger() {
+	SetLogger(NewStdLogger(nil))
+	SetLogLevel(LogLevelInfo)
+}
+
+func TestLogEvent(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, ToolsResetLogger)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		l := ToolsSetTestLogger()
+		e := Event{"request_id": "R1", "event_id": "Ev1", "team": "T1", "channel": "C1", "user": "U1", "text": "test"}
+		logError(e, "test error", "command", "test", "error", errors.New("failed"))
+
+		assert.Equal(t, []testLog{{
+			Level:   LogLevelError,
+			Msg:     "test error",
+			Keyvals: []interface{}{"request_id", "R1", "event_id", "Ev1", "team", "T1", "channel", "C1", "user", "U1", "command", "test", "error", "failed"},
+		}}, l.Logs)
+	})
+
+	testRun(t, "level test", func(t *testing.T) {
+		l := ToolsSetTestLogger()
+		logDebug(nil, "debug")
+		logInfo(nil, "info")
+		assert.Len(t, l.Logs, 1)
+
+		SetLogLevel(LogLevelDebug)
+		logDebug(nil, "debug")
+		assert.Len(t, l.Logs, 2)
+
+		SetLogLevel(LogLevelError)
+		logWarn(nil, "warn")
+		logError(nil, "error")
+		assert.Len(t, l.Logs, 3)
+		assert.Equal(t, LogLevelError, l.Logs[2].Level)
+	})
+
+	testRun(t, "redact test", func(t *testing.T) {
+		l := ToolsSetTestLogger()
+		logInfo(nil, "token xoxb-1234-abcd", "access_token", "secret value", "text", "use xoxp-12-ab and xapp-1-X", "odd")
+
+		assert.Equal(t, "token [REDACTED]", l.Logs[0].Msg)
+		assert.Equal(t, []interface{}{"access_token", "[REDACTED]", "text", "use [REDACTED] and [REDACTED]", "odd", ""}, l.Logs[0].Keyvals)
+	})
+
+	testRun(t, "no logger test", func(t *testing.T) {
+		SetLogger(nil)
+		logError(nil, "error")
+	})
+}
+
+func TestLogLevel_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "DEBUG", LogLevelDebug.String())
+	assert.Equal(t, "INFO", LogLevelInfo.String())
+	assert.Equal(t, "WARN", LogLevelWarn.String())
+	assert.Equal(t, "ERROR", LogLevelError.String())
+}
+
+func TestStdLogger(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	l := NewStdLogger(log.New(buf, "", 0))
+	l.Log(LogLevelWarn, "not support event", "type", "file_shared", "text", `a "b"`, "empty", "")
+
+	assert.Equal(t, "level=WARN msg=\"not support event\" type=file_shared text=\"a \\\"b\\\"\" empty=\"\"\n", buf.String())
+}
+
+func TestOnCall_RequestID(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		ToolsResetLogger()
+		ClearCommand()
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		l := ToolsSetTestLogger()
+		Setup("bot", "", "")
+
+		body := `{"type":"event_callback","event_id":"Ev1","team_id":"T1","event":{"type":"file_shared","channel":"C1","user":"U1"}}`
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Request-Id", "R1")
+		OnCall(httptest.NewRecorder(), r)
+
+		assert.Len(t, l.Logs, 1)
+		assert.Equal(t, "not support event", l.Logs[0].Msg)
+		assert.Equal(t, []interface{}{"request_id", "R1", "event_id", "Ev1", "team", "T1", "channel", "C1", "user", "U1", "type", "file_shared"}, l.Logs[0].Keyvals)
+	})
+
+	testRun(t, "generate test", func(t *testing.T) {
+		l := ToolsSetTestLogger()
+		Setup("bot", "", "")
+
+		body := `{"type":"unknown"}`
+		OnCall(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		assert.Len(t, l.Logs, 1)
+		assert.Equal(t, "request_id", l.Logs[0].Keyvals[0])
+		assert.Len(t, l.Logs[0].Keyvals[1], 16)
+	})
+}
@@ -13,8 +13,14 @@ type MessageHandler interface {
 	OnMentionMessage(e Event, texts []string)
 }
 
+// ReactionHandler for Slack
+type ReactionHandler interface {
+	OnReaction(e Event)
+}
+
 var (
-	messageHandler MessageHandler
+	messageHandler  MessageHandler
+	reactionHandler ReactionHandler
 )
 
 // SetMessageHandler for slackbot
@@ -22,11 +28,19 @@ func SetMessageHandler(handler MessageHandler) {
 	messageHandler = handler
 }
 
+// SetReactionHandler for slackbot
+func SetReactionHandler(handler ReactionHandler) {
+	reactionHandler = handler
+}
+
 func onMessage(e Event) {
 	texts := strings.Split(strings.TrimSpace(e.Text()), " ")
-	if texts[0] == fmt.Sprintf("<@%s>", slackBotUserID) {
+	if texts[0] == fmt.Sprintf("<@%s>", botUserIDFor(e.Context())) {
 		onMentionMessage(e)
 	} else {
+		if onSession(e, texts) {
+			return
+		}
 		if !executeCommand(e, texts) && messageHandler != nil {
 			messageHandler.OnMessage(e, texts)
 		}
@@ -35,18 +49,33 @@ func onMessage(e Event) {
 
 func onMentionMessage(e Event) {
 	texts := strings.Split(strings.TrimSpace(e.Text()), " ")
-	if texts[0] == fmt.Sprintf("<@%s>", slackBotUserID) {
+	if texts[0] == fmt.Sprintf("<@%s>", botUserIDFor(e.Context())) {
 		texts = texts[1:]
 	}
+	if onSession(e, texts) {
+		return
+	}
 	if !executeCommand(e, texts) && messageHandler != nil {
 		messageHandler.OnMentionMessage(e, texts)
 	}
 }
 
+// onSlashCommand is handled in the same way as mention.
+func onSlashCommand(e Event) {
+	onMentionMessage(e)
+}
+
+func onReaction(e Event) {
+	if reactionHandler != nil {
+		reactionHandler.OnReaction(e)
+	}
+}
+
 // PostMessage to Slack.
 func PostMessage(e Event, message string) {
 	channel := e.Channel()
-	api.PostMessage(
+	postMessage(
+		e.Context(),
 		channel,
 		slack.MsgOptionText(message, true),
 	)
@@ -55,7 +84,8 @@ func PostMessage(e Event, message string) {
 // PostEphemeral message to Slack.
 func PostEphemeral(e Event, message string) {
 	channel := e.Channel()
-	api.PostEphemeral(
+	postEphemeral(
+		e.Context(),
 		channel,
 		e.User(),
 		slack.MsgOptionText(message, true),
@@ -66,9 +96,90 @@ func PostEphemeral(e Event, message string) {
 func ReplyMessage(e Event, message string) {
 	channel := e.Channel()
 	threadTimestamp := e.ThreadTimestamp()
-	api.PostMessage(
+	postMessage(
+		e.Context(),
 		channel,
 		slack.MsgOptionTS(threadTimestamp),
 		slack.MsgOptionText(message, true),
 	)
 }
+
+// PostMrkdwn to Slack. The message is formatted in mrkdwn such as by the mrkdwn package, and not escaped.
+func PostMrkdwn(e Event, message string) {
+	channel := e.Channel()
+	postMessage(
+		e.Context(),
+		channel,
+		slack.MsgOptionText(message, false),
+	)
+}
+
+// PostEphemeralMrkdwn message to Slack, which is not escaped.
+func PostEphemeralMrkdwn(e Event, message string) {
+	channel := e.Channel()
+	postEphemeral(
+		e.Context(),
+		channel,
+		e.User(),
+		slack.MsgOptionText(message, false),
+	)
+}
+
+// ReplyMrkdwn to Slack, which is not escaped.
+func ReplyMrkdwn(e Event, message string) {
+	channel := e.Channel()
+	threadTimestamp := e.ThreadTimestamp()
+	postMessage(
+		e.Context(),
+		channel,
+		slack.MsgOptionTS(threadTimestamp),
+		slack.MsgOptionText(message, false),
+	)
+}
+
+// PostBlocks to Slack. Invalid blocks are not sent and logged.
+func PostBlocks(e Event, blocks *Blocks) {
+	options, err := blocks.msgOptions()
+	if err != nil {
+		logError(e, "invalid blocks", "error", err)
+		return
+	}
+	channel := e.Channel()
+	postMessage(
+		e.Context(),
+		channel,
+		options...,
+	)
+}
+
+// PostEphemeralBlocks to Slack. Invalid blocks are not sent and logged.
+func PostEphemeralBlocks(e Event, blocks *Blocks) {
+	options, err := blocks.msgOptions()
+	if err != nil {
+		logError(e, "invalid blocks", "error", err)
+		return
+	}
+	channel := e.Channel()
+	postEphemeral(
+		e.Context(),
+		channel,
+		e.User(),
+		options...,
+	)
+}
+
+// ReplyBlocks to Slack. Invalid blocks are not sent and logged.
+func ReplyBlocks(e Event, blocks *Blocks) {
+	options, err := blocks.msgOptions()
+	if err != nil {
+		logError(e, "invalid blocks", "error", err)
+		return
+	}
+	channel := e.Channel()
+	threadTimestamp := e.ThreadTimestamp()
+	postMessage(
+		e.Context(),
+		channel,
+		append([]slack.MsgOption{slack.MsgOptionTS(threadTimestamp)}, options...)...,
+	)
+}
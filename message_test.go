@@ -24,6 +24,39 @@ func (h *TestMessageHandler) OnMentionMessage(e Event, texts []string) {
 	h.OnMentionMessaged = true
 }
 
+type TestReactionHandler struct {
+	Reaction string
+}
+
+func (h *TestReactionHandler) OnReaction(e Event) {
+	h.Reaction = e.Reaction()
+}
+
+func TestSetReactionHandler(t *testing.T) {
+	handler := &TestReactionHandler{}
+	SetReactionHandler(handler)
+	defer SetReactionHandler(nil)
+
+	assert.Equal(t, handler, reactionHandler)
+}
+
+func TestOnReaction(t *testing.T) {
+	handler := &TestReactionHandler{}
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetReactionHandler(nil)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetReactionHandler(handler)
+		onReaction(Event{"reaction": "eyes"})
+		assert.Equal(t, "eyes", handler.Reaction)
+	})
+
+	testRun(t, "no handler test", func(t *testing.T) {
+		onReaction(Event{"reaction": "eyes"})
+	})
+}
+
 func TestSetMessageHandler(t *testing.T) {
 	handler := &TestMessageHandler{}
 	SetMessageHandler(handler)
@@ -165,8 +198,12 @@ func TestOnMentionMessage(t *testing.T) {
 }
 
 func TestPostMessage(t *testing.T) {
-	event := Event{}
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	event := Event{"channel": "C1", "user": "U1", "event_ts": "1.0"}
 	clear := func() {
+		fake.Reset()
 		Setup("", "", "")
 	}
 
@@ -174,6 +211,12 @@ func TestPostMessage(t *testing.T) {
 
 	testRun(t, "normal test", func(t *testing.T) {
 		PostMessage(event, "test")
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "C1", calls[0].Param("channel"))
+		assert.Equal(t, "test", calls[0].Param("text"))
+		assert.Equal(t, "", calls[0].Param("thread_ts"))
 	})
 
 	testRun(t, "error test", func(t *testing.T) {
@@ -184,11 +227,39 @@ func TestPostMessage(t *testing.T) {
 		PostMessage(event, "test")
 		assert.Fail(t, "do not reached.")
 	})
+
+	testRun(t, "mrkdwn test", func(t *testing.T) {
+		PostMrkdwn(event, "*test* <@U1> &lt;")
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "*test* <@U1> &lt;", calls[0].Param("text"))
+	})
+
+	testRun(t, "blocks test", func(t *testing.T) {
+		PostBlocks(event, NewBlocks().Section("*test* <@U1>").Text("a < b"))
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "a &lt; b", calls[0].Param("text"))
+		assert.Contains(t, calls[0].Param("blocks"), `"text":"*test* \u003c@U1\u003e"`)
+	})
+
+	testRun(t, "invalid blocks test", func(t *testing.T) {
+		PostBlocks(event, NewBlocks())
+		PostBlocks(event, NewBlocks().Section(""))
+
+		assert.Len(t, fake.CallsFor("chat.postMessage"), 0)
+	})
 }
 
 func TestPostEphemeral(t *testing.T) {
-	event := Event{}
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	event := Event{"channel": "C1", "user": "U1", "event_ts": "1.0"}
 	clear := func() {
+		fake.Reset()
 		Setup("", "", "")
 	}
 
@@ -196,6 +267,12 @@ func TestPostEphemeral(t *testing.T) {
 
 	testRun(t, "normal test", func(t *testing.T) {
 		PostEphemeral(event, "test")
+
+		calls := fake.CallsFor("chat.postEphemeral")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "C1", calls[0].Param("channel"))
+		assert.Equal(t, "test", calls[0].Param("text"))
+		assert.Equal(t, "U1", calls[0].Param("user"))
 	})
 
 	testRun(t, "error test", func(t *testing.T) {
@@ -206,11 +283,41 @@ func TestPostEphemeral(t *testing.T) {
 		PostEphemeral(event, "test")
 		assert.Fail(t, "do not reached.")
 	})
+
+	testRun(t, "mrkdwn test", func(t *testing.T) {
+		PostEphemeralMrkdwn(event, "*test* <@U1> &lt;")
+
+		calls := fake.CallsFor("chat.postEphemeral")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "*test* <@U1> &lt;", calls[0].Param("text"))
+		assert.Equal(t, "U1", calls[0].Param("user"))
+	})
+
+	testRun(t, "blocks test", func(t *testing.T) {
+		PostEphemeralBlocks(event, NewBlocks().Section("*test* <@U1>").Text("a < b"))
+
+		calls := fake.CallsFor("chat.postEphemeral")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "a &lt; b", calls[0].Param("text"))
+		assert.Contains(t, calls[0].Param("blocks"), `"text":"*test* \u003c@U1\u003e"`)
+		assert.Equal(t, "U1", calls[0].Param("user"))
+	})
+
+	testRun(t, "invalid blocks test", func(t *testing.T) {
+		PostEphemeralBlocks(event, NewBlocks())
+		PostEphemeralBlocks(event, NewBlocks().Section(""))
+
+		assert.Len(t, fake.CallsFor("chat.postEphemeral"), 0)
+	})
 }
 
 func TestReplyMessage(t *testing.T) {
-	event := Event{}
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	event := Event{"channel": "C1", "user": "U1", "event_ts": "1.0"}
 	clear := func() {
+		fake.Reset()
 		Setup("", "", "")
 	}
 
@@ -218,6 +325,12 @@ func TestReplyMessage(t *testing.T) {
 
 	testRun(t, "normal test", func(t *testing.T) {
 		ReplyMessage(event, "test")
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "C1", calls[0].Param("channel"))
+		assert.Equal(t, "test", calls[0].Param("text"))
+		assert.Equal(t, "1.0", calls[0].Param("thread_ts"))
 	})
 
 	testRun(t, "error test", func(t *testing.T) {
@@ -228,4 +341,30 @@ func TestReplyMessage(t *testing.T) {
 		ReplyMessage(event, "test")
 		assert.Fail(t, "do not reached.")
 	})
+
+	testRun(t, "mrkdwn test", func(t *testing.T) {
+		ReplyMrkdwn(event, "*test* <@U1> &lt;")
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "*test* <@U1> &lt;", calls[0].Param("text"))
+		assert.Equal(t, "1.0", calls[0].Param("thread_ts"))
+	})
+
+	testRun(t, "blocks test", func(t *testing.T) {
+		ReplyBlocks(event, NewBlocks().Section("*test* <@U1>").Text("a < b"))
+
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "a &lt; b", calls[0].Param("text"))
+		assert.Contains(t, calls[0].Param("blocks"), `"text":"*test* \u003c@U1\u003e"`)
+		assert.Equal(t, "1.0", calls[0].Param("thread_ts"))
+	})
+
+	testRun(t, "invalid blocks test", func(t *testing.T) {
+		ReplyBlocks(event, NewBlocks())
+		ReplyBlocks(event, NewBlocks().Section(""))
+
+		assert.Len(t, fake.CallsFor("chat.postMessage"), 0)
+	})
 }
@@ -0,0 +1,413 @@
+package slackbot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metrics of slackbot in Prometheus text format.
+var (
+	metricEvents = newCounterVec(
+		"slackbot_events_total",
+		"Events received from Slack by type.",
+		"type",
+	)
+	metricRetriesDropped = newCounterVec(
+		"slackbot_retries_dropped_total",
+		"Retried requests from Slack ignored by type.",
+		"type",
+	)
+	metricRequestDuration = newHistogramVec(
+		"slackbot_request_duration_seconds",
+		"Latency of the requests from Slack by status code.",
+		"status",
+	)
+	metricCommands = newCounterVec(
+		"slackbot_commands_total",
+		"Commands executed by name and outcome.",
+		"command", "outcome",
+	)
+	metricOptionErrors = newCounterVec(
+		"slackbot_option_errors_total",
+		"Option errors of the commands by name.",
+		"command",
+	)
+	metricCommandDuration = newHistogramVec(
+		"slackbot_command_duration_seconds",
+		"Latency of the commands by name.",
+		"command",
+	)
+	metricAPIDuration = newHistogramVec(
+		"slackbot_slack_api_duration_seconds",
+		"Latency of the Slack Web API calls by method.",
+		"method",
+	)
+	metricAPIErrors = newCounterVec(
+		"slackbot_slack_api_errors_total",
+		"Errors of the Slack Web API calls by method and error.",
+		"method", "error",
+	)
+
+	metrics = []metric{
+		metricEvents,
+		metricRetriesDropped,
+		metricRequestDuration,
+		metricCommands,
+		metricOptionErrors,
+		metricCommandDuration,
+		metricAPIDuration,
+		metricAPIErrors,
+	}
+
+	// metricBuckets of the histograms in seconds.
+	metricBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+type metric interface {
+	write(w io.Writer)
+	reset()
+}
+
+// MetricsPath is the path of the metrics exposed by ListenAndServe.
+const MetricsPath = "/metrics"
+
+// MetricsHandler exposes the metrics in Prometheus text format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w)
+	})
+}
+
+// WriteMetrics in Prometheus text format.
+func WriteMetrics(w io.Writer) {
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// ResetMetrics all for slackbot.
+func ResetMetrics() {
+	for _, m := range metrics {
+		m.reset()
+	}
+}
+
+var (
+	metricsPushURL string
+	metricsLogging bool
+)
+
+// SetMetricsPushURL of Prometheus Pushgateway, such as "http://pushgateway:9091/metrics/job/slackbot".
+// The metrics are pushed by FlushMetrics, which is called after each invocation of AWS Lambda.
+func SetMetricsPushURL(url string) {
+	metricsPushURL = url
+}
+
+// SetMetricsLogging to log the metrics by FlushMetrics.
+func SetMetricsLogging(logging bool) {
+	metricsLogging = logging
+}
+
+// FlushMetrics to Pushgateway and log if set, for environments not scraped such as AWS Lambda.
+func FlushMetrics() error {
+	if metricsLogging {
+		buf := &bytes.Buffer{}
+		WriteMetrics(buf)
+		logInfo(nil, "metrics", "metrics", buf.String())
+	}
+	if metricsPushURL != "" {
+		return PushMetrics(metricsPushURL)
+	}
+	return nil
+}
+
+// PushMetrics to Prometheus Pushgateway.
+func PushMetrics(url string) error {
+	buf := &bytes.Buffer{}
+	WriteMetrics(buf)
+
+	req, err := http.NewRequest(http.MethodPut, url, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("push metrics: %s", resp.Status)
+	}
+	return nil
+}
+
+// counterVec counts by labels.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+func (c *counterVec) inc(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[metricKey(c.labels, values)]++
+}
+
+func (c *counterVec) get(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.values[metricKey(c.labels, values)]
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+}
+
+func (c *counterVec) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = map[string]float64{}
+}
+
+// histogramVec observes by labels.
+type histogramVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, values: map[string]*histogram{}}
+}
+
+func (h *histogramVec) observe(d time.Duration, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := metricKey(h.labels, values)
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogram{counts: make([]uint64, len(metricBuckets))}
+		h.values[key] = v
+	}
+	seconds := d.Seconds()
+	for i, bucket := range metricBuckets {
+		if seconds <= bucket {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += seconds
+}
+
+func (h *histogramVec) count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if v, ok := h.values[metricKey(h.labels, values)]; ok {
+		return v.count
+	}
+	return 0
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := []string{}
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v := h.values[key]
+		for i, bucket := range metricBuckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, "le", formatFloat(bucket)), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, v.count)
+	}
+}
+
+func (h *histogramVec) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.values = map[string]*histogram{}
+}
+
+// metricKey formats the labels such as `{type="message"}`.
+func metricKey(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = label + "=" + strconv.Quote(value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel adds the label to the key.
+func withLabel(key, label, value string) string {
+	pair := label + "=" + strconv.Quote(value)
+	if key == "" {
+		return "{" + pair + "}"
+	}
+	return key[:len(key)-1] + "," + pair + "}"
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// apiTransport observes the Slack Web API calls by the metrics and client spans.
+type apiTransport struct {
+	base http.RoundTripper
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	ctx, span := startSpan(req.Context(), "slack "+method, trace.SpanKindClient,
+		attribute.String("rpc.system", "slack"),
+		attribute.String("rpc.method", method),
+	)
+	defer span.End()
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	metricAPIDuration.observe(time.Since(start), method)
+	if err != nil {
+		metricAPIErrors.inc(method, "transport")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	code := ""
+	if resp.StatusCode/100 != 2 {
+		code = strconv.Itoa(resp.StatusCode)
+	} else {
+		code = apiErrorCode(resp)
+	}
+	if code != "" {
+		metricAPIErrors.inc(method, code)
+		span.SetAttributes(attribute.String("slack.error", code))
+		span.SetStatus(codes.Error, code)
+	}
+	return resp, nil
+}
+
+// apiErrorCode of the response with "ok": false. The body is restored for the Client.
+func apiErrorCode(resp *http.Response) string {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return ""
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	p, err := DecodeJSON(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	if ok, _ := p["ok"].(bool); ok {
+		return ""
+	}
+	return selectString(p.String("error") != "", p.String("error"), "unknown")
+}
+
+// instrumentHTTPClient for the metrics and traces of the Slack Web API calls.
+func instrumentHTTPClient(client *http.Client) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	instrumented := *client
+	instrumented.Transport = &apiTransport{base: base}
+	return &instrumented
+}
+
+// statusRecorder records the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,163 @@
+package slackbot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	testRun := ToolsCreateTestRun(ResetMetrics, ResetMetrics)
+
+	testRun(t, "counter test", func(t *testing.T) {
+		metricEvents.inc("message")
+		metricEvents.inc("message")
+		metricAPIErrors.inc("chat.postMessage", `"quoted"`)
+
+		buf := &bytes.Buffer{}
+		WriteMetrics(buf)
+		assert.Contains(t, buf.String(), "# TYPE slackbot_events_total counter\nslackbot_events_total{type=\"message\"} 2\n")
+		assert.Contains(t, buf.String(), `slackbot_slack_api_errors_total{method="chat.postMessage",error="\"quoted\""} 1`)
+	})
+
+	testRun(t, "histogram test", func(t *testing.T) {
+		metricCommandDuration.observe(20*time.Millisecond, "ping")
+		metricCommandDuration.observe(3*time.Second, "ping")
+
+		buf := &bytes.Buffer{}
+		WriteMetrics(buf)
+		assert.Contains(t, buf.String(), "# TYPE slackbot_command_duration_seconds histogram\n")
+		assert.Contains(t, buf.String(), `slackbot_command_duration_seconds_bucket{command="ping",le="0.01"} 0`)
+		assert.Contains(t, buf.String(), `slackbot_command_duration_seconds_bucket{command="ping",le="0.025"} 1`)
+		assert.Contains(t, buf.String(), `slackbot_command_duration_seconds_bucket{command="ping",le="5"} 2`)
+		assert.Contains(t, buf.String(), `slackbot_command_duration_seconds_bucket{command="ping",le="+Inf"} 2`)
+		assert.Contains(t, buf.String(), `slackbot_command_duration_seconds_sum{command="ping"} 3.02`)
+		assert.Contains(t, buf.String(), `slackbot_command_duration_seconds_count{command="ping"} 2`)
+	})
+
+	testRun(t, "handler test", func(t *testing.T) {
+		metricEvents.inc("message")
+
+		w := httptest.NewRecorder()
+		MetricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+		assert.Contains(t, w.Body.String(), `slackbot_events_total{type="message"} 1`)
+	})
+}
+
+func TestMetrics_Command(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		ResetMetrics()
+		ToolsInitCommand()
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		Setup("bot", "", "")
+		AddCommand(&Command{Name: "test", Option: struct {
+			Desc string `choice:"false,true"`
+		}{}, Execute: func(e Event, opt interface{}) {}})
+
+		executeCommand(Event{"channel": "C1"}, []string{"test", "true"})
+		executeCommand(Event{"channel": "C1"}, []string{"test", "invalid"})
+
+		assert.Equal(t, float64(1), metricCommands.get("test", "success"))
+		assert.Equal(t, float64(1), metricCommands.get("test", "option_error"))
+		assert.Equal(t, float64(1), metricOptionErrors.get("test"))
+		assert.Equal(t, uint64(1), metricCommandDuration.count("test"))
+	})
+
+	testRun(t, "panic test", func(t *testing.T) {
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			panic("test")
+		}})
+
+		assert.Panics(t, func() {
+			executeCommand(Event{}, []string{"test"})
+		})
+		assert.Equal(t, float64(1), metricCommands.get("test", "panic"))
+	})
+
+	testRun(t, "slack api test", func(t *testing.T) {
+		Setup("bot", "", "")
+		fake.SetError("chat.postMessage", "channel_not_found")
+
+		ReplyMessage(Event{"channel": "C1"}, "test")
+		api.AddReaction("ok", slack.NewRefToMessage("C1", "1.0"))
+
+		assert.Equal(t, uint64(1), metricAPIDuration.count("chat.postMessage"))
+		assert.Equal(t, float64(1), metricAPIErrors.get("chat.postMessage", "channel_not_found"))
+		assert.Equal(t, uint64(1), metricAPIDuration.count("reactions.add"))
+		assert.Equal(t, float64(0), metricAPIErrors.get("reactions.add", "channel_not_found"))
+	})
+}
+
+func TestMetrics_OnCall(t *testing.T) {
+	testRun := ToolsCreateTestRun(ResetMetrics, ResetMetrics)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		Setup("bot", "", "")
+		body := `{"type":"url_verification","challenge":"test"}`
+		OnCall(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		assert.Equal(t, float64(1), metricEvents.get("url_verification"))
+		assert.Equal(t, uint64(1), metricRequestDuration.count("200"))
+	})
+
+	testRun(t, "retry test", func(t *testing.T) {
+		Setup("bot", "", "")
+		body := `{"type":"event_callback","event":{"type":"message","text":"test"}}`
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Slack-Retry-Num", "1")
+		OnCall(httptest.NewRecorder(), r)
+
+		assert.Equal(t, float64(1), metricEvents.get("message"))
+		assert.Equal(t, float64(1), metricRetriesDropped.get("message"))
+	})
+}
+
+func TestFlushMetrics(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	clear := func() {
+		ResetMetrics()
+		SetMetricsPushURL("")
+		SetMetricsLogging(false)
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "push test", func(t *testing.T) {
+		metricEvents.inc("message")
+		SetMetricsPushURL(server.URL + "/metrics/job/slackbot")
+		SetMetricsLogging(true)
+
+		assert.NoError(t, FlushMetrics())
+		assert.Contains(t, body, `slackbot_events_total{type="message"} 1`)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetMetricsPushURL(server.URL + "/%")
+		assert.Error(t, FlushMetrics())
+	})
+
+	testRun(t, "no push test", func(t *testing.T) {
+		assert.NoError(t, FlushMetrics())
+	})
+}
@@ -0,0 +1,124 @@
+package mrkdwn
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdComment   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	mdFence     = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading   = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(\s+#+)?\s*$`)
+	mdRule      = regexp.MustCompile(`^\s{0,3}(-\s*-\s*-[-\s]*|\*\s*\*\s*\*[*\s]*|_\s*_\s*_[_\s]*)$`)
+	mdTask      = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	mdBullet    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdQuote     = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	mdCodeSpan  = regexp.MustCompile("`+[^`]*`+")
+	mdLink      = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdAutolink  = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdBold      = regexp.MustCompile(`\*\*([^*\s](?:[^*]*[^*\s])?)\*\*|__([^_\s](?:[^_]*[^_\s])?)__`)
+	mdItalic    = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdStrike    = regexp.MustCompile(`~~([^~\s](?:[^~]*[^~\s])?)~~`)
+	placeholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// boldMark stands for "*" of bold until italics are converted.
+const boldMark = "\x01"
+
+// FromMarkdown converts the Markdown of GitHub or CI tools to mrkdwn.
+//
+// Headings become bold, lists become bullets, links become <url|text>, and &, < and > are escaped.
+// Code spans and code blocks are kept as is. HTML comments are removed.
+func FromMarkdown(md string) string {
+	md = mdComment.ReplaceAllString(strings.Replace(md, "\r\n", "\n", -1), "")
+
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for _, line := range lines {
+		if m := mdFence.FindStringSubmatch(line); m != nil && (fence == "" || m[1] == fence) {
+			// the language of the code block is not supported
+			fence = selectFence(fence, m[1])
+			out = append(out, "```")
+			continue
+		}
+		if fence != "" {
+			out = append(out, Escape(line))
+			continue
+		}
+		out = append(out, convertLine(line))
+	}
+	if fence != "" {
+		out = append(out, "```")
+	}
+	return strings.Join(out, "\n")
+}
+
+func selectFence(current, fence string) string {
+	if current == "" {
+		return fence
+	}
+	return ""
+}
+
+func convertLine(line string) string {
+	if mdRule.MatchString(line) {
+		return "──────────"
+	}
+	if m := mdHeading.FindStringSubmatch(line); m != nil {
+		return Bold(convertInline(strings.Replace(m[1], "**", "", -1)))
+	}
+	if m := mdTask.FindStringSubmatch(line); m != nil {
+		box := "☐ "
+		if m[2] != " " {
+			box = "☑ "
+		}
+		return m[1] + box + convertInline(m[3])
+	}
+	if m := mdBullet.FindStringSubmatch(line); m != nil {
+		return m[1] + "• " + convertInline(m[2])
+	}
+	if m := mdQuote.FindStringSubmatch(line); m != nil {
+		return "> " + convertLine(m[1])
+	}
+	return convertInline(line)
+}
+
+// convertInline formatting of the line, except in the code spans.
+func convertInline(line string) string {
+	out := ""
+	last := 0
+	for _, loc := range mdCodeSpan.FindAllStringIndex(line, -1) {
+		out += convertText(line[last:loc[0]]) + Escape(line[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	return out + convertText(line[last:])
+}
+
+func convertText(s string) string {
+	// links are replaced with placeholders, so that the URLs are not formatted
+	links := []string{}
+	hold := func(link string) string {
+		links = append(links, link)
+		return "\x00" + strconv.Itoa(len(links)-1) + "\x00"
+	}
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		return hold(Link(sub[2], sub[1]))
+	})
+	s = mdAutolink.ReplaceAllStringFunc(s, func(m string) string {
+		return hold(Link(mdAutolink.FindStringSubmatch(m)[1], ""))
+	})
+
+	s = Escape(s)
+	s = mdBold.ReplaceAllString(s, boldMark+"$1$2"+boldMark)
+	s = mdItalic.ReplaceAllString(s, "${1}_${2}_")
+	s = mdStrike.ReplaceAllString(s, "~$1~")
+	s = strings.Replace(s, boldMark, "*", -1)
+
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		i, _ := strconv.Atoi(placeholder.FindStringSubmatch(m)[1])
+		return links[i]
+	})
+}
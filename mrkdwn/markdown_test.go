@@ -0,0 +1,57 @@
+package mrkdwn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromMarkdown(t *testing.T) {
+	t.Run("inline test", func(t *testing.T) {
+		assert.Equal(t, "*bold* _italic_ _italic_ ~strike~", FromMarkdown("**bold** *italic* _italic_ ~~strike~~"))
+		assert.Equal(t, "*bold* and _italic_", FromMarkdown("__bold__ and *italic*"))
+		assert.Equal(t, "a &amp; b &lt; c", FromMarkdown("a & b < c"))
+		assert.Equal(t, "2 * 3 * 4", FromMarkdown("2 * 3 * 4"))
+		assert.Equal(t, "snake_case_name", FromMarkdown("snake_case_name"))
+	})
+
+	t.Run("link test", func(t *testing.T) {
+		assert.Equal(t, "see <https://example.com/a_b_c|the *docs*>", FromMarkdown("see [the *docs*](https://example.com/a_b_c)"))
+		assert.Equal(t, "<https://example.com/a.png|logo>", FromMarkdown("![logo](https://example.com/a.png)"))
+		assert.Equal(t, "<https://example.com|docs>", FromMarkdown(`[docs](https://example.com "title")`))
+		assert.Equal(t, "<https://example.com/a_b>", FromMarkdown("<https://example.com/a_b>"))
+	})
+
+	t.Run("code test", func(t *testing.T) {
+		assert.Equal(t, "run `go test **/*` &amp; *check*", FromMarkdown("run `go test **/*` & **check**"))
+		assert.Equal(t, "```\nif a &lt; b {\n\t**x**\n}\n```", FromMarkdown("```go\nif a < b {\n\t**x**\n}\n```"))
+		assert.Equal(t, "```\n# not heading\n```", FromMarkdown("~~~\n# not heading\n~~~"))
+		assert.Equal(t, "```\nunclosed\n```", FromMarkdown("```\nunclosed"))
+	})
+
+	t.Run("block test", func(t *testing.T) {
+		md := "## Release **v1.2.0**\n" +
+			"<!-- generated -->\n" +
+			"### Changes\n" +
+			"- Fix [#12](https://github.com/o/r/pull/12)\n" +
+			"  * nested\n" +
+			"- [x] done\n" +
+			"- [ ] todo\n" +
+			"1. first\n" +
+			"> quoted **text**\n" +
+			"---\n" +
+			"Thanks!"
+		expected := "*Release v1.2.0*\n" +
+			"\n" +
+			"*Changes*\n" +
+			"• Fix <https://github.com/o/r/pull/12|#12>\n" +
+			"  • nested\n" +
+			"☑ done\n" +
+			"☐ todo\n" +
+			"1. first\n" +
+			"> quoted *text*\n" +
+			"──────────\n" +
+			"Thanks!"
+		assert.Equal(t, expected, FromMarkdown(md))
+	})
+}
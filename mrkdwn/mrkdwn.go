@@ -0,0 +1,164 @@
+// Package mrkdwn formats text in the mrkdwn syntax of Slack.
+//
+// Formatting functions such as Bold do not escape the text, so that they can be nested.
+// Escape the text from users or external tools by Escape first.
+// Code, Pre, Link and Email are the exceptions, which escape their arguments because formatting is not applied in them.
+//
+//	mrkdwn.Bold(mrkdwn.Escape(title)) + " by " + mrkdwn.User("U0123456789")
+//
+// The formatted text is mrkdwn, so send it without escaping, such as by slackbot.ReplyMrkdwn.
+package mrkdwn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special mentions notifying the members of the channel.
+const (
+	Here      = "<!here>"
+	AtChannel = "<!channel>"
+	Everyone  = "<!everyone>"
+)
+
+// Tokens of the Date format, shown in the timezone of the reader.
+const (
+	DateNum         = "{date_num}"
+	DateShort       = "{date_short}"
+	DateLong        = "{date_long}"
+	DatePretty      = "{date_pretty}"
+	DateShortPretty = "{date_short_pretty}"
+	DateLongPretty  = "{date_long_pretty}"
+	Time            = "{time}"
+	TimeSecs        = "{time_secs}"
+	Ago             = "{ago}"
+)
+
+var (
+	escaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	unescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+)
+
+// Escape the control characters &, < and >.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Unescape the control characters, such as the text of an Event.
+func Unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+func enclose(s, character string) string {
+	if s == "" {
+		return s
+	}
+	return character + s + character
+}
+
+// Bold text. Returns empty if s is empty.
+func Bold(s string) string {
+	return enclose(s, "*")
+}
+
+// Italic text. Returns empty if s is empty.
+func Italic(s string) string {
+	return enclose(s, "_")
+}
+
+// Strike text. Returns empty if s is empty.
+func Strike(s string) string {
+	return enclose(s, "~")
+}
+
+// Code of inline. The code is escaped, because formatting is not applied in it.
+func Code(s string) string {
+	return enclose(Escape(s), "`")
+}
+
+// Pre of a code block. The code is escaped.
+func Pre(s string) string {
+	return "```\n" + Escape(strings.TrimSuffix(s, "\n")) + "\n```"
+}
+
+// Quote each line of the text.
+func Quote(s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// List of the items with bullets.
+func List(items ...string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "• " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
+// OrderedList of the items numbered from 1.
+func OrderedList(items ...string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = strconv.Itoa(i+1) + ". " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
+// User mention of the ID.
+func User(id string) string {
+	return "<@" + id + ">"
+}
+
+// Channel link of the ID.
+func Channel(id string) string {
+	return "<#" + id + ">"
+}
+
+// UserGroup mention of the ID.
+func UserGroup(id string) string {
+	return "<!subteam^" + id + ">"
+}
+
+// Link to the URL. The URL is shown if text is empty. Both are escaped.
+func Link(url, text string) string {
+	if text == "" {
+		return "<" + escapeLink(url) + ">"
+	}
+	return "<" + escapeLink(url) + "|" + escapeLink(text) + ">"
+}
+
+// Email link to the address.
+func Email(address string) string {
+	return Link("mailto:"+address, address)
+}
+
+// escapeLink also escapes "|", which separates the URL and the text.
+func escapeLink(s string) string {
+	return strings.Replace(Escape(s), "|", "%7C", -1)
+}
+
+// Date of t in the format with the tokens such as DateShort, shown in the timezone of the reader.
+// The fallback is shown if the client cannot format it.
+func Date(t time.Time, format, fallback string) string {
+	return DateLink(t, format, "", fallback)
+}
+
+// DateLink of t linking to the URL.
+func DateLink(t time.Time, format, url, fallback string) string {
+	s := fmt.Sprintf("<!date^%d^%s", t.Unix(), format)
+	if url != "" {
+		s += "^" + escapeLink(url)
+	}
+	return s + "|" + escapeLink(fallback) + ">"
+}
+
+// Emoji of the name.
+func Emoji(name string) string {
+	return ":" + strings.Trim(name, ":") + ":"
+}
@@ -0,0 +1,66 @@
+package mrkdwn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscape(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		assert.Equal(t, "a &amp; &lt;b&gt;", Escape("a & <b>"))
+		assert.Equal(t, "a & <b>", Unescape("a &amp; &lt;b&gt;"))
+		assert.Equal(t, "&lt;!channel&gt;", Escape(AtChannel))
+	})
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		assert.Equal(t, "*test*", Bold("test"))
+		assert.Equal(t, "_test_", Italic("test"))
+		assert.Equal(t, "~test~", Strike("test"))
+		assert.Equal(t, "`a &lt; b`", Code("a < b"))
+		assert.Equal(t, "```\nif a &lt; b {\n}\n```", Pre("if a < b {\n}\n"))
+		assert.Equal(t, "> a\n> b", Quote("a\nb"))
+		assert.Equal(t, "• a\n• b", List("a", "b"))
+		assert.Equal(t, "1. a\n2. b", OrderedList("a", "b"))
+		assert.Equal(t, "*_test_*", Bold(Italic("test")))
+	})
+
+	t.Run("empty test", func(t *testing.T) {
+		assert.Equal(t, "", Bold(""))
+		assert.Equal(t, "", Italic(""))
+		assert.Equal(t, "", Strike(""))
+		assert.Equal(t, "", Code(""))
+		assert.Equal(t, "", List())
+	})
+}
+
+func TestMention(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		assert.Equal(t, "<@U012AB3CD>", User("U012AB3CD"))
+		assert.Equal(t, "<#C012AB3CD>", Channel("C012AB3CD"))
+		assert.Equal(t, "<!subteam^S012AB3CD>", UserGroup("S012AB3CD"))
+		assert.Equal(t, ":smile:", Emoji("smile"))
+		assert.Equal(t, ":smile:", Emoji(":smile:"))
+	})
+}
+
+func TestLink(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		assert.Equal(t, "<https://example.com>", Link("https://example.com", ""))
+		assert.Equal(t, "<https://example.com?a=1&amp;b=2|a &lt;b&gt;>", Link("https://example.com?a=1&b=2", "a <b>"))
+		assert.Equal(t, "<https://example.com|a%7Cb>", Link("https://example.com", "a|b"))
+		assert.Equal(t, "<mailto:bot@example.com|bot@example.com>", Email("bot@example.com"))
+	})
+}
+
+func TestDate(t *testing.T) {
+	at := time.Unix(1392734382, 0)
+
+	t.Run("normal test", func(t *testing.T) {
+		assert.Equal(t, "<!date^1392734382^{date_short} {time}|Feb 18, 2014>", Date(at, DateShort+" "+Time, "Feb 18, 2014"))
+		assert.Equal(t, "<!date^1392734382^{ago}^https://example.com|2014-02-18>", DateLink(at, Ago, "https://example.com", "2014-02-18"))
+	})
+}
@@ -0,0 +1,87 @@
+package slackbot
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// TextNormalizer converts the text of Event before it is split into command and options.
+type TextNormalizer func(text string) string
+
+var (
+	textNormalizers = DefaultTextNormalizers()
+
+	zeroWidthReplacer = strings.NewReplacer(
+		"\u200B", "", // zero width space
+		"\u200C", "", // zero width non-joiner
+		"\u200D", "", // zero width joiner
+		"\u2060", "", // word joiner
+		"\uFEFF", "", // zero width no-break space
+	)
+	quoteReplacer = strings.NewReplacer(
+		"\u201C", `"`, "\u201D", `"`, "\u201E", `"`, "\u2033", `"`, "\uFF02", `"`,
+		"\u2018", "'", "\u2019", "'", "\u201A", "'", "\u2032", "'", "\uFF07", "'",
+	)
+	spaceReplacer = strings.NewReplacer(
+		"\t", " ",
+		"\r\n", " ",
+		"\n", " ",
+		"\r", " ",
+		"\u00A0", " ", // non breaking space
+		"\u3000", " ", // ideographic space
+	)
+	spacesRegexp = regexp.MustCompile(" +")
+)
+
+// DefaultTextNormalizers used by slackbot.
+func DefaultTextNormalizers() []TextNormalizer {
+	return []TextNormalizer{
+		NormalizeNFKC,
+		NormalizeWidth,
+		StripZeroWidth,
+		NormalizeQuotes,
+		NormalizeSpaces,
+	}
+}
+
+// SetTextNormalizers for slackbot. Normalizers are applied in order.
+// NormalizeSpaces should be included, because options are split by a space.
+func SetTextNormalizers(normalizers ...TextNormalizer) {
+	textNormalizers = normalizers
+}
+
+// NormalizeText by the normalizers of slackbot.
+func NormalizeText(text string) string {
+	for _, normalize := range textNormalizers {
+		text = normalize(text)
+	}
+	return text
+}
+
+// NormalizeNFKC converts compatibility characters such as "ｒｅｐｅａｔ" and "①" to "repeat" and "1".
+func NormalizeNFKC(text string) string {
+	return norm.NFKC.String(text)
+}
+
+// NormalizeWidth converts full-width alphanumerics to half-width, and half-width katakana to full-width.
+func NormalizeWidth(text string) string {
+	return width.Fold.String(text)
+}
+
+// StripZeroWidth characters.
+func StripZeroWidth(text string) string {
+	return zeroWidthReplacer.Replace(text)
+}
+
+// NormalizeQuotes converts smart quotes to straight quotes.
+func NormalizeQuotes(text string) string {
+	return quoteReplacer.Replace(text)
+}
+
+// NormalizeSpaces converts tabs, newlines and wide spaces to a space, and multiple spaces to a single space.
+func NormalizeSpaces(text string) string {
+	return spacesRegexp.ReplaceAllString(spaceReplacer.Replace(text), " ")
+}
@@ -0,0 +1,55 @@
+package slackbot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeText(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetTextNormalizers(DefaultTextNormalizers()...)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		assert.Equal(t, "repeat hi 2", NormalizeText("ｒｅｐｅａｔ　ｈｉ　２"))
+		assert.Equal(t, "repeat テスト 1", NormalizeText("repeat\tﾃｽﾄ\n①"))
+		assert.Equal(t, `schedule "every day" ping`, NormalizeText("schedule  “every day” \u200Bping"))
+	})
+
+	testRun(t, "custom test", func(t *testing.T) {
+		SetTextNormalizers(strings.ToLower, NormalizeSpaces)
+		assert.Equal(t, "repeat ｈｉ", NormalizeText("REPEAT   ｈｉ"))
+	})
+
+	testRun(t, "empty test", func(t *testing.T) {
+		SetTextNormalizers()
+		assert.Equal(t, "ho  ge", NormalizeText("ho  ge"))
+	})
+}
+
+func TestNormalizeNFKC(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "repeat 2 テスト", NormalizeNFKC("ｒｅｐｅａｔ　２ ﾃｽﾄ"))
+}
+
+func TestNormalizeWidth(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "repeat 2 テスト", NormalizeWidth("ｒｅｐｅａｔ　２ ﾃｽﾄ"))
+}
+
+func TestStripZeroWidth(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "ping", StripZeroWidth("\u200Bp\u200Ci\u200Dn\u2060g\uFEFF"))
+}
+
+func TestNormalizeQuotes(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `"a" 'b'`, NormalizeQuotes("“a” ‘b’"))
+}
+
+func TestNormalizeSpaces(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "a b c d e f", NormalizeSpaces("a\tb\r\nc\nd\u00A0e\u3000\u3000f"))
+}
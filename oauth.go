@@ -0,0 +1,232 @@
+package slackbot
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Paths of the OAuth flow served by Server.
+const (
+	InstallPath       = "/slack/install"
+	OAuthRedirectPath = "/slack/oauth_redirect"
+)
+
+// OAuthConfig of the Slack app installed to multiple workspaces.
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	UserScopes   []string
+
+	// RedirectURL of OAuthRedirectPath. The Redirect URL of the app settings is used if empty.
+	RedirectURL string
+
+	// SuccessURL redirected to after the installation. A simple page is shown if empty.
+	SuccessURL string
+}
+
+const (
+	oauthStateNamespace = "oauth_state"
+	oauthStateCookie    = "slackbot_oauth_state"
+	oauthStateTTL       = 10 * time.Minute
+	oauthAuthorizeURL   = "https://slack.com/oauth/v2/authorize"
+)
+
+var (
+	oauthConfig *OAuthConfig
+)
+
+// SetOAuthConfig for installing the app to multiple workspaces.
+// NewInstallationStore is set if InstallationStore is not set.
+func SetOAuthConfig(c OAuthConfig) {
+	oauthConfig = &c
+	if installationStore == nil {
+		installationStore = NewInstallationStore()
+	}
+}
+
+// InstallHandler redirects to the authorization page of Slack.
+func InstallHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if oauthConfig == nil {
+			http.Error(w, "oauth is not configured", http.StatusNotFound)
+			return
+		}
+
+		state := newRequestID()
+		if err := GetStore().Set(oauthStateNamespace, state, []byte{1}, oauthStateTTL); err != nil {
+			logError(nil, "oauth error", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		// bind the state to the browser against CSRF
+		http.SetCookie(w, &http.Cookie{
+			Name:     oauthStateCookie,
+			Value:    state,
+			Path:     "/",
+			MaxAge:   int(oauthStateTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		params := url.Values{
+			"client_id": {oauthConfig.ClientID},
+			"scope":     {strings.Join(oauthConfig.Scopes, ",")},
+			"state":     {state},
+		}
+		if len(oauthConfig.UserScopes) > 0 {
+			params.Set("user_scope", strings.Join(oauthConfig.UserScopes, ","))
+		}
+		if oauthConfig.RedirectURL != "" {
+			params.Set("redirect_uri", oauthConfig.RedirectURL)
+		}
+		http.Redirect(w, r, oauthAuthorizeURL+"?"+params.Encode(), http.StatusFound)
+	})
+}
+
+// OAuthRedirectHandler exchanges the code for the tokens by oauth.v2.access, and saves the Installation.
+func OAuthRedirectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if oauthConfig == nil {
+			http.Error(w, "oauth is not configured", http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		if e := query.Get("error"); e != "" {
+			oauthPage(w, http.StatusForbidden, "The installation was canceled: "+e)
+			return
+		}
+		if !consumeOAuthState(r, query.Get("state")) {
+			oauthPage(w, http.StatusBadRequest, "The installation has expired. Please try again.")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/", MaxAge: -1})
+
+		i, err := exchangeOAuthCode(query.Get("code"))
+		if err == nil {
+			err = installationStore.SaveInstallation(i)
+		}
+		if err != nil {
+			logError(nil, "oauth error", "error", err)
+			oauthPage(w, http.StatusInternalServerError, "The installation failed.")
+			return
+		}
+		logInfo(Event{"team": i.TeamID}, "installation saved", "enterprise", i.EnterpriseID)
+
+		if oauthConfig.SuccessURL != "" {
+			http.Redirect(w, r, oauthConfig.SuccessURL, http.StatusFound)
+			return
+		}
+		oauthPage(w, http.StatusOK, "The app was installed to "+selectString(i.TeamName != "", i.TeamName, i.EnterpriseName)+".")
+	})
+}
+
+// consumeOAuthState issued by InstallHandler only once, which must equal the cookie.
+func consumeOAuthState(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		return false
+	}
+	ok, err := GetStore().CompareAndSwap(oauthStateNamespace, state, []byte{1}, []byte{}, oauthStateTTL)
+	return err == nil && ok
+}
+
+// oauthAccess response of oauth.v2.access.
+type oauthAccess struct {
+	OK                  bool   `json:"ok"`
+	Error               string `json:"error"`
+	AppID               string `json:"app_id"`
+	AccessToken         string `json:"access_token"`
+	RefreshToken        string `json:"refresh_token"`
+	ExpiresIn           int64  `json:"expires_in"`
+	Scope               string `json:"scope"`
+	BotUserID           string `json:"bot_user_id"`
+	IsEnterpriseInstall bool   `json:"is_enterprise_install"`
+	Team                *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+	Enterprise *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"enterprise"`
+	AuthedUser struct {
+		ID string `json:"id"`
+	} `json:"authed_user"`
+}
+
+// expiresAt of the access token. Returns zero time if the token does not expire.
+func (a *oauthAccess) expiresAt(now time.Time) time.Time {
+	if a.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(a.ExpiresIn) * time.Second)
+}
+
+// postOAuthAccess of the grant to oauth.v2.access.
+func postOAuthAccess(params url.Values) (*oauthAccess, error) {
+	res, err := instrumentHTTPClient(httpClient).PostForm(apiURL+"oauth.v2.access", params)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	a := &oauthAccess{}
+	if err := json.NewDecoder(res.Body).Decode(a); err != nil {
+		return nil, err
+	}
+	if !a.OK {
+		return nil, errors.New("oauth.v2.access: " + a.Error)
+	}
+	return a, nil
+}
+
+// exchangeOAuthCode for the Installation by oauth.v2.access.
+func exchangeOAuthCode(code string) (*Installation, error) {
+	params := url.Values{
+		"client_id":     {oauthConfig.ClientID},
+		"client_secret": {oauthConfig.ClientSecret},
+		"code":          {code},
+	}
+	if oauthConfig.RedirectURL != "" {
+		params.Set("redirect_uri", oauthConfig.RedirectURL)
+	}
+
+	a, err := postOAuthAccess(params)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	i := &Installation{
+		IsEnterpriseInstall: a.IsEnterpriseInstall,
+		AppID:               a.AppID,
+		BotToken:            a.AccessToken,
+		BotRefreshToken:     a.RefreshToken,
+		BotTokenExpiresAt:   a.expiresAt(now),
+		BotUserID:           a.BotUserID,
+		BotScopes:           a.Scope,
+		UserID:              a.AuthedUser.ID,
+		InstalledAt:         now,
+	}
+	if a.Team != nil {
+		i.TeamID, i.TeamName = a.Team.ID, a.Team.Name
+	}
+	if a.Enterprise != nil {
+		i.EnterpriseID, i.EnterpriseName = a.Enterprise.ID, a.Enterprise.Name
+	}
+	return i, nil
+}
+
+func oauthPage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte("<!DOCTYPE html><html><body><p>" + html.EscapeString(message) + "</p></body></html>"))
+}
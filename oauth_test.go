@@ -0,0 +1,124 @@
+package slackbot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/peto-tn/slackbot-go/fakeslack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		ToolsClearInstallation()
+		oauthConfig = nil
+		fake.Reset()
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	install := func() *http.Cookie {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", InstallPath, nil)
+		InstallHandler().ServeHTTP(rec, req)
+		return rec.Result().Cookies()[0]
+	}
+	redirect := func(query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", OAuthRedirectPath+"?"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		OAuthRedirectHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	testRun(t, "install test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{ClientID: "client", Scopes: []string{"chat:write", "commands"}, RedirectURL: "https://example.com/slack/oauth_redirect"})
+
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", InstallPath, nil)
+		InstallHandler().ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		location, _ := url.Parse(rec.Header().Get("Location"))
+		assert.Equal(t, "slack.com", location.Host)
+		assert.Equal(t, "client", location.Query().Get("client_id"))
+		assert.Equal(t, "chat:write,commands", location.Query().Get("scope"))
+		assert.Equal(t, "https://example.com/slack/oauth_redirect", location.Query().Get("redirect_uri"))
+
+		cookie := rec.Result().Cookies()[0]
+		assert.Equal(t, location.Query().Get("state"), cookie.Value)
+		assert.True(t, cookie.HttpOnly)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{ClientID: "client", ClientSecret: "secret"})
+		cookie := install()
+
+		rec := redirect("code=1&state="+cookie.Value, cookie)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		calls := fake.CallsFor("oauth.v2.access")
+		assert.Len(t, calls, 1)
+		assert.Equal(t, "client", calls[0].Param("client_id"))
+		assert.Equal(t, "secret", calls[0].Param("client_secret"))
+
+		i, err := GetInstallationStore().FindInstallation("", fakeslack.TeamID)
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxb-1", i.BotToken)
+		assert.Equal(t, fakeslack.BotUserID, i.BotUserID)
+		assert.Equal(t, "U1", i.UserID)
+
+		// the state is used only once
+		rec = redirect("code=1&state="+cookie.Value, cookie)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	testRun(t, "token rotation test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{})
+		fake.SetResponse("oauth.v2.access", map[string]interface{}{
+			"ok": true, "access_token": "xoxe.xoxb-1", "refresh_token": "xoxe-1", "expires_in": 43200, "team": map[string]interface{}{"id": "T1"},
+		})
+		cookie := install()
+
+		assert.Equal(t, http.StatusOK, redirect("code=1&state="+cookie.Value, cookie).Code)
+
+		i, err := GetInstallationStore().FindInstallation("", "T1")
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxe-1", i.BotRefreshToken)
+		assert.WithinDuration(t, time.Now().Add(12*time.Hour), i.BotTokenExpiresAt, time.Minute)
+	})
+
+	testRun(t, "success url test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{SuccessURL: "https://example.com/installed"})
+		cookie := install()
+
+		rec := redirect("code=1&state="+cookie.Value, cookie)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "https://example.com/installed", rec.Header().Get("Location"))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{})
+		cookie := install()
+
+		assert.Equal(t, http.StatusForbidden, redirect("error=access_denied&state="+cookie.Value, cookie).Code)
+		assert.Equal(t, http.StatusBadRequest, redirect("code=1&state="+cookie.Value, nil).Code)
+		assert.Equal(t, http.StatusBadRequest, redirect("code=1&state=invalid", cookie).Code)
+
+		fake.SetError("oauth.v2.access", "invalid_code")
+		assert.Equal(t, http.StatusInternalServerError, redirect("code=1&state="+cookie.Value, cookie).Code)
+	})
+
+	testRun(t, "not configured test", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", InstallPath, nil)
+		InstallHandler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
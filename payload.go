@@ -1,6 +1,7 @@
 package slackbot
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 )
@@ -28,6 +29,30 @@ func (p Payload) String(key string) string {
 	}
 }
 
+// Context of the request handling the Payload. Returns context.Background if not set.
+func (p Payload) Context() context.Context {
+	if ctx, ok := p[contextEventKey].(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// SetContext of the request handling the Payload.
+func (p Payload) SetContext(ctx context.Context) {
+	p[contextEventKey] = ctx
+}
+
+// withoutContext for saving the Payload.
+func (p Payload) withoutContext() Payload {
+	copied := Payload{}
+	for key, value := range p {
+		if key != contextEventKey {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
 // Type of Payload.
 func (p Payload) Type() string {
 	return p.String("type")
@@ -44,3 +69,24 @@ func (p Payload) Event() Event {
 	e.ModifyText()
 	return e
 }
+
+// Object data in Payload.
+func (p Payload) Object(key string) Payload {
+	if v, ok := p[key].(map[string]interface{}); ok {
+		return v
+	}
+	return Payload{}
+}
+
+// Actions of block_actions Payload.
+func (p Payload) Actions() []Payload {
+	actions := []Payload{}
+	if v, ok := p["actions"].([]interface{}); ok {
+		for _, action := range v {
+			if a, ok := action.(map[string]interface{}); ok {
+				actions = append(actions, a)
+			}
+		}
+	}
+	return actions
+}
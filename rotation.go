@@ -0,0 +1,410 @@
+package slackbot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// TokenRotation of the access token of Setup, which expires and is refreshed by the refresh token.
+type TokenRotation struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+
+	// ExpiresAt of the access token. The token is refreshed on token_expired if zero.
+	ExpiresAt time.Time
+}
+
+// tokenRefreshMargin before the expiry to refresh the token.
+const tokenRefreshMargin = 10 * time.Minute
+
+const (
+	tokenNamespace = "token"
+	tokenKey       = "bot"
+
+	// tokenRefreshNamespace of the claims of the refresh tokens in the Store, shared by the processes.
+	tokenRefreshNamespace = "token_refresh"
+)
+
+const (
+	// tokenRefreshClaimTTL of the claim, after which another process can refresh the token if the claiming one failed.
+	tokenRefreshClaimTTL = 30 * time.Second
+
+	// tokenRefreshWait for the token refreshed by the process claiming the refresh.
+	tokenRefreshWait = 5 * time.Second
+	tokenRefreshPoll = 50 * time.Millisecond
+)
+
+// errTokenRefreshing is returned if the token refreshed by another process is not saved in time.
+var errTokenRefreshing = errors.New("token refresh in progress by another process")
+
+var (
+	tokenRotation *TokenRotation
+)
+
+// SetTokenRotation for the access token of Setup.
+// The rotated tokens are saved in the Store, and take precedence over the tokens set here at the next start.
+func SetTokenRotation(r TokenRotation) {
+	tokenRotation = &r
+	if api != nil {
+		api = newSetupClient()
+	}
+}
+
+// newSetupClient of the access token of Setup.
+func newSetupClient() Client {
+	if tokenRotation == nil {
+		return newClient(accessToken)
+	}
+
+	r := tokenRotation
+	c := &rotatingClient{
+		clientID:     r.ClientID,
+		clientSecret: r.ClientSecret,
+		load:         loadSetupToken,
+		save: func(old string, t *rotatedToken) error {
+			data, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			return GetStore().Set(tokenNamespace, tokenKey, data, 0)
+		},
+	}
+	if t, err := loadSetupToken(); err == nil {
+		c.set(t)
+	} else {
+		c.set(&rotatedToken{AccessToken: accessToken, RefreshToken: r.RefreshToken, ExpiresAt: r.ExpiresAt})
+	}
+	return c
+}
+
+func loadSetupToken() (*rotatedToken, error) {
+	data, err := GetStore().Get(tokenNamespace, tokenKey)
+	if err != nil {
+		return nil, err
+	}
+	t := &rotatedToken{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newInstallationClient of the bot token of the Installation, which is refreshed if it has the refresh token.
+func newInstallationClient(i *Installation) Client {
+	if i.BotRefreshToken == "" {
+		return newClient(i.BotToken)
+	}
+
+	teamID := selectString(i.IsEnterpriseInstall, "", i.TeamID)
+	c := &rotatingClient{
+		load: func() (*rotatedToken, error) {
+			saved, err := installationStore.FindInstallation(i.EnterpriseID, teamID)
+			if err != nil {
+				return nil, err
+			}
+			return &rotatedToken{AccessToken: saved.BotToken, RefreshToken: saved.BotRefreshToken, ExpiresAt: saved.BotTokenExpiresAt}, nil
+		},
+	}
+	c.save = func(old string, t *rotatedToken) error {
+		saved, err := installationStore.FindInstallation(i.EnterpriseID, teamID)
+		if err != nil {
+			return err
+		}
+		saved.BotToken, saved.BotRefreshToken, saved.BotTokenExpiresAt = t.AccessToken, t.RefreshToken, t.ExpiresAt
+		return installationStore.SaveInstallation(saved)
+	}
+	c.rotated = func(old string, t *rotatedToken) {
+		// the installation found by the new token is served by this client
+		teamClientsMu.Lock()
+		delete(teamClients, old)
+		teamClients[t.AccessToken] = c
+		teamClientsMu.Unlock()
+	}
+	if oauthConfig != nil {
+		c.clientID, c.clientSecret = oauthConfig.ClientID, oauthConfig.ClientSecret
+	}
+	c.set(&rotatedToken{AccessToken: i.BotToken, RefreshToken: i.BotRefreshToken, ExpiresAt: i.BotTokenExpiresAt})
+	return c
+}
+
+// rotatedToken pair of the access token and the refresh token.
+type rotatedToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// expiring within the refresh margin. The token without the expiry never expires.
+func (t *rotatedToken) expiring(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.Add(tokenRefreshMargin).After(t.ExpiresAt)
+}
+
+// refreshToken by oauth.v2.access.
+func refreshToken(clientID, clientSecret, refreshToken string) (*rotatedToken, error) {
+	a, err := postOAuthAccess(url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rotatedToken{AccessToken: a.AccessToken, RefreshToken: a.RefreshToken, ExpiresAt: a.expiresAt(time.Now())}, nil
+}
+
+// rotatingClient refreshes the access token before the expiry, and retries the call once on token_expired.
+type rotatingClient struct {
+	clientID     string
+	clientSecret string
+
+	// load the token saved by another client, and save the refreshed token replacing the old access token.
+	load func() (*rotatedToken, error)
+	save func(old string, t *rotatedToken) error
+
+	// rotated is called with the old access token after refreshed or reloaded, if set.
+	rotated func(old string, t *rotatedToken)
+
+	mu     sync.Mutex
+	token  rotatedToken
+	client Client
+}
+
+func (c *rotatingClient) set(t *rotatedToken) {
+	c.token = *t
+	c.client = newClient(t.AccessToken)
+}
+
+// current client, which is refreshed if the token is expiring.
+func (c *rotatingClient) current() (Client, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token.expiring(time.Now()) {
+		if err := c.refresh(); err != nil {
+			logError(nil, "token refresh error", "error", err)
+		}
+	}
+	return c.client, c.token.AccessToken
+}
+
+// refresh the token unless it has been refreshed after the expired one.
+func (c *rotatingClient) refreshExpired(expired string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token.AccessToken != expired {
+		return nil
+	}
+	return c.refresh()
+}
+
+// refresh the token, which is claimed in the Store so that only one process uses the refresh token.
+// The other processes reload the token refreshed by the claiming one.
+func (c *rotatingClient) refresh() error {
+	// another process may have rotated the token
+	if c.reload() {
+		return nil
+	}
+
+	claim := refreshClaimKey(c.token.RefreshToken)
+	claimed, err := GetStore().CompareAndSwap(tokenRefreshNamespace, claim, nil, []byte(time.Now().Format(time.RFC3339)), tokenRefreshClaimTTL)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return c.waitRefreshed()
+	}
+
+	t, err := refreshToken(c.clientID, c.clientSecret, c.token.RefreshToken)
+	if err != nil {
+		// another process can retry
+		GetStore().Delete(tokenRefreshNamespace, claim)
+		return err
+	}
+	old := c.token.AccessToken
+	c.set(t)
+	logInfo(nil, "token refreshed", "expires_at", t.ExpiresAt)
+	if c.rotated != nil {
+		c.rotated(old, t)
+	}
+	return c.save(old, t)
+}
+
+// reload the token rotated by another process. Returns true if reloaded.
+func (c *rotatingClient) reload() bool {
+	t, err := c.load()
+	if err != nil || t.AccessToken == c.token.AccessToken || t.expiring(time.Now()) {
+		return false
+	}
+	old := c.token.AccessToken
+	c.set(t)
+	if c.rotated != nil {
+		c.rotated(old, t)
+	}
+	return true
+}
+
+// waitRefreshed by the process claiming the refresh, until it saves the token.
+func (c *rotatingClient) waitRefreshed() error {
+	for deadline := time.Now().Add(tokenRefreshWait); time.Now().Before(deadline); {
+		time.Sleep(tokenRefreshPoll)
+		if c.reload() {
+			return nil
+		}
+	}
+	return errTokenRefreshing
+}
+
+// refreshClaimKey of the refresh token, which is hashed not to save it in the Store.
+func refreshClaimKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// do the call, and retry it once with the refreshed token on token_expired.
+func (c *rotatingClient) do(call func(api Client) error) error {
+	api, token := c.current()
+	err := call(api)
+	if err == nil || err.Error() != "token_expired" {
+		return err
+	}
+	if err := c.refreshExpired(token); err != nil {
+		logError(nil, "token refresh error", "error", err)
+		return err
+	}
+	api, _ = c.current()
+	return call(api)
+}
+
+func (c *rotatingClient) PostMessage(channelID string, options ...slack.MsgOption) (channel string, timestamp string, err error) {
+	err = c.do(func(api Client) error {
+		channel, timestamp, err = api.PostMessage(channelID, options...)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (timestamp string, err error) {
+	err = c.do(func(api Client) error {
+		timestamp, err = api.PostEphemeral(channelID, userID, options...)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (channel string, ts string, text string, err error) {
+	err = c.do(func(api Client) error {
+		channel, ts, text, err = api.UpdateMessage(channelID, timestamp, options...)
+		return err
+	})
+	return
+}
+
+// PostMessageContext joins the span of the call to the trace of ctx, as the other context methods.
+func (c *rotatingClient) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (channel string, timestamp string, err error) {
+	err = c.do(func(api Client) error {
+		if cc, ok := api.(contextClient); ok {
+			channel, timestamp, err = cc.PostMessageContext(ctx, channelID, options...)
+		} else {
+			channel, timestamp, err = api.PostMessage(channelID, options...)
+		}
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (timestamp string, err error) {
+	err = c.do(func(api Client) error {
+		if cc, ok := api.(contextClient); ok {
+			timestamp, err = cc.PostEphemeralContext(ctx, channelID, userID, options...)
+		} else {
+			timestamp, err = api.PostEphemeral(channelID, userID, options...)
+		}
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) UpdateMessageContext(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (channel string, ts string, text string, err error) {
+	err = c.do(func(api Client) error {
+		if cc, ok := api.(contextClient); ok {
+			channel, ts, text, err = cc.UpdateMessageContext(ctx, channelID, timestamp, options...)
+		} else {
+			channel, ts, text, err = api.UpdateMessage(channelID, timestamp, options...)
+		}
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) AddReaction(name string, item slack.ItemRef) error {
+	return c.do(func(api Client) error {
+		return api.AddReaction(name, item)
+	})
+}
+
+func (c *rotatingClient) GetUserInfo(user string) (u *slack.User, err error) {
+	err = c.do(func(api Client) error {
+		u, err = api.GetUserInfo(user)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) GetConversationInfo(channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	err = c.do(func(api Client) error {
+		channel, err = api.GetConversationInfo(channelID, includeLocale)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) GetConversationHistory(params *slack.GetConversationHistoryParameters) (res *slack.GetConversationHistoryResponse, err error) {
+	err = c.do(func(api Client) error {
+		res, err = api.GetConversationHistory(params)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) GetConversationReplies(params *slack.GetConversationRepliesParameters) (messages []slack.Message, hasMore bool, cursor string, err error) {
+	err = c.do(func(api Client) error {
+		messages, hasMore, cursor, err = api.GetConversationReplies(params)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) GetUsersInConversation(params *slack.GetUsersInConversationParameters) (users []string, cursor string, err error) {
+	err = c.do(func(api Client) error {
+		users, cursor, err = api.GetUsersInConversation(params)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) OpenConversation(params *slack.OpenConversationParameters) (channel *slack.Channel, noOp bool, alreadyOpen bool, err error) {
+	err = c.do(func(api Client) error {
+		channel, noOp, alreadyOpen, err = api.OpenConversation(params)
+		return err
+	})
+	return
+}
+
+func (c *rotatingClient) UploadFile(params slack.FileUploadParameters) (file *slack.File, err error) {
+	err = c.do(func(api Client) error {
+		file, err = api.UploadFile(params)
+		return err
+	})
+	return
+}
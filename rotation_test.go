@@ -0,0 +1,190 @@
+package slackbot
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/peto-tn/slackbot-go/fakeslack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenRotation(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		fake.Reset()
+		tokenRotation = nil
+		accessToken = ""
+		api = nil
+		SetStore(nil)
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	expireToken := func(token string) {
+		fake.SetResponseFunc("chat.postMessage", func(c fakeslack.Call) interface{} {
+			if c.Param("token") == token {
+				return map[string]interface{}{"ok": false, "error": "token_expired"}
+			}
+			return map[string]interface{}{"ok": true, "channel": c.Param("channel"), "ts": "1.0"}
+		})
+	}
+	post := func() error {
+		_, _, err := GetClient().PostMessage("C1", slack.MsgOptionText("test", true))
+		return err
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetTokenRotation(TokenRotation{ClientID: "client", ClientSecret: "secret", RefreshToken: "xoxe-1", ExpiresAt: time.Now().Add(time.Minute)})
+		Setup("bot", "", "xoxb-1")
+
+		assert.NoError(t, post())
+		refreshes := fake.CallsFor("oauth.v2.access")
+		assert.Len(t, refreshes, 1)
+		assert.Equal(t, "xoxe-1", refreshes[0].Param("refresh_token"))
+		assert.Equal(t, "client", refreshes[0].Param("client_id"))
+
+		token := fake.CallsFor("chat.postMessage")[0].Param("token")
+		assert.NotEqual(t, "xoxb-1", token)
+
+		saved, err := loadSetupToken()
+		assert.NoError(t, err)
+		assert.Equal(t, token, saved.AccessToken)
+		assert.WithinDuration(t, time.Now().Add(12*time.Hour), saved.ExpiresAt, time.Minute)
+
+		// not expiring
+		assert.NoError(t, post())
+		assert.Len(t, fake.CallsFor("oauth.v2.access"), 1)
+	})
+
+	testRun(t, "token expired test", func(t *testing.T) {
+		SetTokenRotation(TokenRotation{RefreshToken: "xoxe-1"})
+		Setup("bot", "", "xoxb-1")
+		expireToken("xoxb-1")
+
+		assert.NoError(t, post())
+		calls := fake.CallsFor("chat.postMessage")
+		assert.Len(t, calls, 2)
+		assert.Equal(t, "xoxb-1", calls[0].Param("token"))
+		assert.NotEqual(t, "xoxb-1", calls[1].Param("token"))
+	})
+
+	testRun(t, "concurrent test", func(t *testing.T) {
+		SetTokenRotation(TokenRotation{RefreshToken: "xoxe-1"})
+		Setup("bot", "", "xoxb-1")
+		expireToken("xoxb-1")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, post())
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, fake.CallsFor("oauth.v2.access"), 1)
+	})
+
+	testRun(t, "multiple processes test", func(t *testing.T) {
+		SetTokenRotation(TokenRotation{RefreshToken: "xoxe-1", ExpiresAt: time.Now().Add(time.Minute)})
+		accessToken = "xoxb-1"
+		// the clients of two processes sharing the Store
+		clients := []Client{newSetupClient(), newSetupClient()}
+		fake.SetResponseFunc("oauth.v2.access", func(c fakeslack.Call) interface{} {
+			time.Sleep(100 * time.Millisecond)
+			return map[string]interface{}{"ok": true, "access_token": "xoxb-2", "refresh_token": "xoxe-2", "expires_in": 43200}
+		})
+
+		var wg sync.WaitGroup
+		for _, c := range clients {
+			wg.Add(1)
+			go func(c Client) {
+				defer wg.Done()
+				_, _, err := c.PostMessage("C1", slack.MsgOptionText("test", true))
+				assert.NoError(t, err)
+			}(c)
+		}
+		wg.Wait()
+
+		// refreshed once, and the other process reloads the token
+		assert.Len(t, fake.CallsFor("oauth.v2.access"), 1)
+		for _, call := range fake.CallsFor("chat.postMessage") {
+			assert.Equal(t, "xoxb-2", call.Param("token"))
+		}
+		saved, err := loadSetupToken()
+		assert.NoError(t, err)
+		assert.Equal(t, "xoxe-2", saved.RefreshToken)
+	})
+
+	testRun(t, "saved token test", func(t *testing.T) {
+		GetStore().Set(tokenNamespace, tokenKey, []byte(`{"access_token":"xoxb-2","refresh_token":"xoxe-2"}`), 0)
+		SetTokenRotation(TokenRotation{RefreshToken: "xoxe-1"})
+		Setup("bot", "", "xoxb-1")
+
+		assert.NoError(t, post())
+		assert.Equal(t, "xoxb-2", fake.CallsFor("chat.postMessage")[0].Param("token"))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		SetTokenRotation(TokenRotation{RefreshToken: "xoxe-1"})
+		Setup("bot", "", "xoxb-1")
+		expireToken("xoxb-1")
+		fake.SetError("oauth.v2.access", "invalid_refresh_token")
+
+		assert.Error(t, post())
+		assert.Len(t, fake.CallsFor("chat.postMessage"), 1)
+	})
+}
+
+func TestTokenRotation_Installation(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		fake.Reset()
+		ToolsClearInstallation()
+		oauthConfig = nil
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{ClientID: "client", ClientSecret: "secret"})
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1", BotRefreshToken: "xoxe-1", BotTokenExpiresAt: time.Now().Add(time.Minute)})
+
+		e := Event{"channel": "C1", "team": "T1"}
+		routeEvent(e)
+		PostMessage(e, "test")
+
+		i, err := installationStore.FindInstallation("", "T1")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "xoxb-1", i.BotToken)
+		assert.NotEqual(t, "xoxe-1", i.BotRefreshToken)
+		assert.Equal(t, i.BotToken, fake.CallsFor("chat.postMessage")[0].Param("token"))
+		assert.Equal(t, "client", fake.CallsFor("oauth.v2.access")[0].Param("client_id"))
+
+		// the cached client serves the refreshed token
+		e = Event{"channel": "C1", "team": "T1"}
+		routeEvent(e)
+		assert.Equal(t, clientFor(e.Context()), teamClients[i.BotToken])
+		assert.Len(t, teamClients, 1)
+	})
+
+	testRun(t, "rotated by another process test", func(t *testing.T) {
+		SetInstallationStore(NewInstallationStore())
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-1", BotRefreshToken: "xoxe-1", BotTokenExpiresAt: time.Now().Add(time.Minute)})
+		e := Event{"channel": "C1", "team": "T1"}
+		routeEvent(e)
+
+		// another process rotates the token
+		installationStore.SaveInstallation(&Installation{TeamID: "T1", BotToken: "xoxb-2", BotRefreshToken: "xoxe-2", BotTokenExpiresAt: time.Now().Add(time.Hour)})
+		PostMessage(e, "test")
+
+		assert.Len(t, fake.CallsFor("oauth.v2.access"), 0)
+		assert.Equal(t, "xoxb-2", fake.CallsFor("chat.postMessage")[0].Param("token"))
+		assert.Equal(t, clientFor(e.Context()), teamClients["xoxb-2"])
+		assert.Len(t, teamClients, 1)
+	})
+}
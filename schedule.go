@@ -0,0 +1,258 @@
+package slackbot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScheduleJob runs at the scheduled time. The Event has the channel and user of the Schedule.
+type ScheduleJob func(ctx context.Context, e Event)
+
+// Schedule of the job in cron syntax.
+// Spec accepts 5 fields, descriptors such as "@daily", and "CRON_TZ=Asia/Tokyo " prefix for the timezone.
+type Schedule struct {
+	ID       string
+	Spec     string
+	Location *time.Location
+	Channel  string
+	User     string
+	Job      ScheduleJob
+
+	// Team and Enterprise of the installation posting to the channel, for multiple workspaces.
+	Team       string
+	Enterprise string
+
+	schedule cron.Schedule
+	after    func()
+}
+
+// Next time of the Schedule after t. Returns zero time if no next.
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
+
+// Event passed to the job.
+func (s *Schedule) Event() Event {
+	e := Event{
+		"type":     "schedule",
+		"schedule": s.ID,
+		"channel":  s.Channel,
+		"user":     s.User,
+	}
+	if s.Team != "" {
+		e["team"] = s.Team
+	}
+	if s.Enterprise != "" {
+		e["enterprise_id"] = s.Enterprise
+	}
+	return e
+}
+
+// due returns the scheduled time within the minute of t.
+// Overdue time is also returned for the Schedule run once.
+func (s *Schedule) due(t time.Time) (time.Time, bool) {
+	minute := t.Truncate(time.Minute)
+	if once, ok := s.schedule.(onceSchedule); ok {
+		return once.at, once.at.Before(minute.Add(time.Minute))
+	}
+	next := s.Next(minute.Add(-time.Nanosecond))
+	return next, !next.IsZero() && next.Before(minute.Add(time.Minute))
+}
+
+func (s *Schedule) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			logError(s.Event(), "schedule panic", "schedule", s.ID, "panic", r)
+		}
+	}()
+	if s.after != nil {
+		defer s.after()
+	}
+
+	e := s.Event()
+	ctx, span := startSpan(context.Background(), "slackbot.schedule "+s.ID, trace.SpanKindInternal, attribute.String("slackbot.schedule", s.ID))
+	defer span.End()
+	e.SetContext(ctx)
+	routeEvent(e)
+	s.Job(newContext(e), e)
+}
+
+// nextRun after t. Overdue time is also returned for the Schedule run once.
+func (s *Schedule) nextRun(t time.Time) time.Time {
+	if once, ok := s.schedule.(onceSchedule); ok {
+		return once.at
+	}
+	return s.Next(t)
+}
+
+// runAt the scheduled time only once among processes sharing the Store.
+func (s *Schedule) runAt(t time.Time) bool {
+	key := s.ID + "@" + strconv.FormatInt(t.UnixNano(), 10)
+	ok, err := GetStore().CompareAndSwap(scheduleRunNamespace, key, nil, []byte{1}, time.Hour)
+	if err != nil {
+		logError(s.Event(), "schedule error", "schedule", s.ID, "error", err)
+		return false
+	}
+	if ok {
+		s.run()
+	}
+	return ok
+}
+
+// onceSchedule runs at the time only once.
+type onceSchedule struct {
+	at time.Time
+}
+
+func (o onceSchedule) Next(t time.Time) time.Time {
+	if o.at.After(t) {
+		return o.at
+	}
+	return time.Time{}
+}
+
+const scheduleRunNamespace = "schedule-run"
+
+var (
+	schedules   = []*Schedule{}
+	schedulesMu sync.Mutex
+)
+
+// AddSchedule of the job posting to the channel.
+func AddSchedule(spec, channel string, job ScheduleJob) (*Schedule, error) {
+	s := &Schedule{Spec: spec, Channel: channel, Job: job}
+	return s, AddScheduleJob(s)
+}
+
+// AddCommandSchedule runs the command text in the channel as if the user had typed it.
+func AddCommandSchedule(spec, channel, user, text string) (*Schedule, error) {
+	s := &Schedule{Spec: spec, Channel: channel, User: user, Job: CommandJob(text)}
+	return s, AddScheduleJob(s)
+}
+
+// AddScheduleJob for slackbot. ID is generated if empty.
+func AddScheduleJob(s *Schedule) error {
+	spec := s.Spec
+	if s.Location != nil && !strings.HasPrefix(spec, "CRON_TZ=") && !strings.HasPrefix(spec, "TZ=") {
+		spec = "CRON_TZ=" + s.Location.String() + " " + spec
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return err
+	}
+	s.schedule = schedule
+
+	schedulesMu.Lock()
+	defer schedulesMu.Unlock()
+
+	if s.ID == "" {
+		s.ID = fmt.Sprintf("schedule-%d", len(schedules)+1)
+	}
+	schedules = append(schedules, s)
+	return nil
+}
+
+// GetSchedules of slackbot.
+func GetSchedules() []*Schedule {
+	schedulesMu.Lock()
+	defer schedulesMu.Unlock()
+
+	return append([]*Schedule{}, schedules...)
+}
+
+// ClearSchedule all for slackbot.
+func ClearSchedule() {
+	schedulesMu.Lock()
+	defer schedulesMu.Unlock()
+
+	schedules = []*Schedule{}
+}
+
+// CommandJob runs the command text as if the user of the Schedule had typed it.
+func CommandJob(text string) ScheduleJob {
+	return func(ctx context.Context, e Event) {
+		if !RunCommand(e, text) {
+			logWarn(e, "schedule command not found", "command", text)
+		}
+	}
+}
+
+// RunCommand text with the Event. Returns false if the command is not found.
+func RunCommand(e Event, text string) bool {
+	e["text"] = text
+	e.ModifyText()
+	texts := strings.Split(strings.TrimSpace(e.Text()), " ")
+	return executeCommand(e, texts)
+}
+
+// allSchedules added by AddSchedule and saved by users.
+func allSchedules() []*Schedule {
+	return append(GetSchedules(), loadUserSchedules()...)
+}
+
+// RunSchedule by ID. Returns false if not found.
+func RunSchedule(id string) bool {
+	for _, s := range allSchedules() {
+		if s.ID == id {
+			s.run()
+			return true
+		}
+	}
+	return false
+}
+
+// RunDueSchedules at the minute of t, for triggering from outside such as a scheduled event every minute.
+func RunDueSchedules(t time.Time) int {
+	count := 0
+	for _, s := range allSchedules() {
+		if at, ok := s.due(t); ok && s.runAt(at) {
+			count++
+		}
+	}
+	return count
+}
+
+// StartScheduler runs the schedules until ctx is done, and returns after the running schedules finish.
+func StartScheduler(ctx context.Context) {
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	last := time.Now()
+	for {
+		// wake up at least every minute to pick up added schedules
+		now := time.Now()
+		next := now.Add(time.Minute)
+		for _, s := range allSchedules() {
+			if t := s.Next(now); !t.IsZero() && t.Before(next) {
+				next = t
+			}
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now = <-timer.C:
+		}
+
+		for _, s := range allSchedules() {
+			if t := s.nextRun(last); !t.IsZero() && !t.After(now) {
+				running.Add(1)
+				go func(s *Schedule, t time.Time) {
+					defer running.Done()
+					s.runAt(t)
+				}(s, t)
+			}
+		}
+		last = now
+	}
+}
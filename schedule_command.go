@@ -0,0 +1,518 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/robfig/cron/v3"
+)
+
+// UserSchedule saved in Store by the schedule and remind commands.
+// It is run once at At if Spec is empty.
+type UserSchedule struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	Spec        string    `json:"spec,omitempty"`
+	At          time.Time `json:"at,omitempty"`
+	Channel     string    `json:"channel"`
+	User        string    `json:"user"`
+	Team        string    `json:"team,omitempty"`
+	Enterprise  string    `json:"enterprise,omitempty"`
+	Text        string    `json:"text"`
+	Remind      bool      `json:"remind,omitempty"`
+}
+
+const (
+	userScheduleNamespace = "schedule"
+	userScheduleSequence  = "schedule-sequence"
+)
+
+// Schedule to run the UserSchedule under the identity of the creator.
+func (u *UserSchedule) Schedule() (*Schedule, error) {
+	s := &Schedule{
+		ID:         "user-" + u.ID,
+		Spec:       u.Spec,
+		Channel:    u.Channel,
+		User:       u.User,
+		Job:        CommandJob(u.Text),
+		Team:       u.Team,
+		Enterprise: u.Enterprise,
+	}
+	if u.Remind {
+		s.Job = remindJob(u.Text)
+	}
+
+	if u.Spec == "" {
+		s.schedule = onceSchedule{at: u.At}
+		s.after = func() {
+			if err := DeleteUserSchedule(u.ID); err != nil {
+				logError(s.Event(), "schedule error", "schedule", s.ID, "error", err)
+			}
+		}
+		return s, nil
+	}
+
+	schedule, err := cron.ParseStandard(u.Spec)
+	if err != nil {
+		return nil, err
+	}
+	s.schedule = schedule
+	return s, nil
+}
+
+// String of the UserSchedule for listing.
+func (u *UserSchedule) String() string {
+	if u.Remind {
+		return fmt.Sprintf("%s: remind %s \"%s\" by <@%s>", u.ID, u.Description, u.Text, u.User)
+	}
+	return fmt.Sprintf("%s: %s `%s` by <@%s>", u.ID, u.Description, u.Text, u.User)
+}
+
+// SaveUserSchedule in Store. ID is generated.
+func SaveUserSchedule(u *UserSchedule) error {
+	if _, err := u.Schedule(); err != nil {
+		return err
+	}
+
+	id, err := nextUserScheduleID()
+	if err != nil {
+		return err
+	}
+	u.ID = id
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return GetStore().Set(userScheduleNamespace, u.ID, data, 0)
+}
+
+// GetUserSchedule by ID. Returns ErrNotFound if not found.
+func GetUserSchedule(id string) (*UserSchedule, error) {
+	data, err := GetStore().Get(userScheduleNamespace, id)
+	if err != nil {
+		return nil, err
+	}
+	u := &UserSchedule{}
+	return u, json.Unmarshal(data, u)
+}
+
+// ListUserSchedules in order of ID.
+func ListUserSchedules() ([]*UserSchedule, error) {
+	keys, err := GetStore().List(userScheduleNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*UserSchedule{}
+	for _, key := range keys {
+		u, err := GetUserSchedule(key)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, _ := strconv.Atoi(result[i].ID)
+		b, _ := strconv.Atoi(result[j].ID)
+		return a < b
+	})
+	return result, nil
+}
+
+// DeleteUserSchedule by ID.
+func DeleteUserSchedule(id string) error {
+	return GetStore().Delete(userScheduleNamespace, id)
+}
+
+func nextUserScheduleID() (string, error) {
+	for i := 0; i < 10; i++ {
+		old, err := GetStore().Get(userScheduleSequence, "id")
+		if err != nil && err != ErrNotFound {
+			return "", err
+		}
+		n, _ := strconv.Atoi(string(old))
+		id := strconv.Itoa(n + 1)
+
+		ok, err := GetStore().CompareAndSwap(userScheduleSequence, "id", old, []byte(id), 0)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return id, nil
+		}
+	}
+	return "", errors.New("schedule id conflict")
+}
+
+// loadUserSchedules to run. Invalid ones are skipped.
+func loadUserSchedules() []*Schedule {
+	list, err := ListUserSchedules()
+	if err != nil {
+		logError(nil, "schedule error", "error", err)
+		return nil
+	}
+
+	result := []*Schedule{}
+	for _, u := range list {
+		s, err := u.Schedule()
+		if err != nil {
+			logError(Event{"channel": u.Channel, "user": u.User}, "schedule error", "schedule", "user-"+u.ID, "error", err)
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// remindJob mentions the user. The text is not escaped because it is already escaped by Slack.
+func remindJob(text string) ScheduleJob {
+	return func(ctx context.Context, e Event) {
+		postMessage(
+			ctx,
+			e.Channel(),
+			slack.MsgOptionText(fmt.Sprintf("<@%s> %s", e.User(), text), false),
+		)
+	}
+}
+
+// userLocation from the timezone of the Slack user of the Event. Returns time.Local if unknown.
+func userLocation(e Event) *time.Location {
+	client := clientFor(e.Context())
+	if client == nil || e.User() == "" {
+		return time.Local
+	}
+	info, err := client.GetUserInfo(e.User())
+	if err != nil || info.TZ == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(info.TZ)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+var weekdays = map[string]string{
+	"day":       "*",
+	"weekday":   "1-5",
+	"weekend":   "0,6",
+	"sunday":    "0",
+	"monday":    "1",
+	"tuesday":   "2",
+	"wednesday": "3",
+	"thursday":  "4",
+	"friday":    "5",
+	"saturday":  "6",
+}
+
+// parseClock of "HH:MM".
+func parseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, errors.New("invalid time: " + s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// parseInterval of "every N minutes" from 1 to max.
+func parseInterval(s string, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("invalid interval: %s (1-%d)", s, max)
+	}
+	return n, nil
+}
+
+// ParseScheduleSpec of human readable description or cron syntax in the location.
+// e.g. "every weekday 09:30", "every monday 10:00", "every hour", "every 15 minutes", "0 9 * * *", "@daily"
+func ParseScheduleSpec(description string, loc *time.Location) (string, error) {
+	words := strings.Fields(strings.ToLower(description))
+	if len(words) == 0 {
+		return "", errors.New("empty schedule")
+	}
+
+	spec := description
+	if words[0] == "every" {
+		switch {
+		case len(words) == 2 && words[1] == "hour":
+			spec = "0 * * * *"
+
+		// cron fields, since "@every" is never due within the minute
+		case len(words) == 3 && strings.HasPrefix(words[2], "minute"):
+			n, err := parseInterval(words[1], 59)
+			if err != nil {
+				return "", err
+			}
+			spec = fmt.Sprintf("*/%d * * * *", n)
+
+		case len(words) == 3 && strings.HasPrefix(words[2], "hour"):
+			n, err := parseInterval(words[1], 23)
+			if err != nil {
+				return "", err
+			}
+			spec = fmt.Sprintf("0 */%d * * *", n)
+
+		case len(words) == 3:
+			dow, ok := weekdays[strings.TrimSuffix(words[1], "s")]
+			if !ok {
+				return "", errors.New("invalid day: " + words[1])
+			}
+			hour, minute, err := parseClock(words[2])
+			if err != nil {
+				return "", err
+			}
+			spec = fmt.Sprintf("%d %d * * %s", minute, hour, dow)
+
+		default:
+			return "", errors.New("invalid schedule: " + description)
+		}
+	}
+
+	if strings.Contains(spec, "@every") {
+		return "", errors.New("@every is not supported, use \"every N minutes\" instead")
+	}
+	if !strings.HasPrefix(spec, "CRON_TZ=") && !strings.HasPrefix(spec, "TZ=") {
+		spec = "CRON_TZ=" + loc.String() + " " + spec
+	}
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return "", err
+	}
+	return spec, nil
+}
+
+// ParseRemindTime of "in 2h" or "at 15:00" in the location.
+func ParseRemindTime(words []string, now time.Time, loc *time.Location) (time.Time, error) {
+	if len(words) != 2 {
+		return time.Time{}, errors.New("invalid time: " + strings.Join(words, " "))
+	}
+
+	switch words[0] {
+	case "in":
+		value := words[1]
+		if strings.HasSuffix(value, "d") {
+			days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+			if err != nil {
+				return time.Time{}, errors.New("invalid duration: " + value)
+			}
+			return now.AddDate(0, 0, days), nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return time.Time{}, errors.New("invalid duration: " + value)
+		}
+		return now.Add(d), nil
+
+	case "at":
+		hour, minute, err := parseClock(words[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		local := now.In(loc)
+		at := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+
+	default:
+	}
+
+	return time.Time{}, errors.New("invalid time: " + strings.Join(words, " "))
+}
+
+// commandArguments of the Event after the command name.
+func commandArguments(e Event) string {
+	texts := strings.Split(strings.TrimSpace(e.Text()), " ")
+	if len(texts) > 0 && texts[0] == fmt.Sprintf("<@%s>", botUserIDFor(e.Context())) {
+		texts = texts[1:]
+	}
+	if len(texts) > 0 {
+		texts = texts[1:]
+	}
+	return strings.Join(texts, " ")
+}
+
+// splitQuoted returns the quoted first part and the rest.
+// The first word is returned if not quoted.
+func splitQuoted(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	for _, quote := range [][2]string{{`"`, `"`}, {"“", "”"}} {
+		if strings.HasPrefix(s, quote[0]) {
+			s = s[len(quote[0]):]
+			end := strings.Index(s, quote[1])
+			if end < 0 {
+				return "", "", errors.New("unclosed quote")
+			}
+			return s[:end], strings.TrimSpace(s[end+len(quote[1]):]), nil
+		}
+	}
+
+	words := strings.SplitN(s, " ", 2)
+	if len(words) == 1 {
+		return words[0], "", nil
+	}
+	return words[0], words[1], nil
+}
+
+// SetupScheduleCommand adds schedule and remind commands.
+func SetupScheduleCommand() {
+	AddCommand(scheduleCommand)
+	AddCommand(remindCommand)
+}
+
+const (
+	scheduleUsage = `schedule "every weekday 09:30" <command> | schedule list | schedule delete <id>`
+	remindUsage   = "remind me in 2h <message> | remind me at 15:00 <message>"
+)
+
+// ScheduleCommand
+var scheduleCommand = &Command{
+	Name:        "schedule",
+	HelpMessage: "Schedule a command. " + scheduleUsage,
+
+	Execute: func(e Event, opt interface{}) {
+		args := commandArguments(e)
+		words := strings.Fields(args)
+
+		switch {
+		case len(words) == 0:
+			ReplyMessage(e, T(e, MessageUsage, scheduleUsage))
+
+		case words[0] == "list":
+			listUserSchedules(e)
+
+		case words[0] == "delete" && len(words) == 2:
+			deleteUserSchedule(e, words[1])
+
+		default:
+			addUserSchedule(e, args)
+		}
+	},
+}
+
+// RemindCommand
+var remindCommand = &Command{
+	Name:        "remind",
+	HelpMessage: "Remind a message. " + remindUsage,
+
+	Execute: func(e Event, opt interface{}) {
+		words := strings.Fields(commandArguments(e))
+		if len(words) < 4 || words[0] != "me" {
+			ReplyMessage(e, T(e, MessageUsage, remindUsage))
+			return
+		}
+
+		loc := userLocation(e)
+		at, err := ParseRemindTime(words[1:3], time.Now(), loc)
+		if err != nil {
+			ReplyMessage(e, T(e, MessageError, err.Error()))
+			return
+		}
+
+		u := &UserSchedule{
+			Description: strings.Join(words[1:3], " "),
+			At:          at,
+			Channel:     e.Channel(),
+			User:        e.User(),
+			Team:        installedTeam(e),
+			Enterprise:  e.String("enterprise_id"),
+			Text:        strings.Join(words[3:], " "),
+			Remind:      true,
+		}
+		if err := SaveUserSchedule(u); err != nil {
+			ReplyMessage(e, T(e, MessageError, err.Error()))
+			return
+		}
+
+		ReplyMessage(e, T(e, MessageRemindScheduled, at.In(loc).Format("2006-01-02 15:04 MST"), u.ID))
+	},
+}
+
+func addUserSchedule(e Event, args string) {
+	description, text, err := splitQuoted(args)
+	if err != nil {
+		ReplyMessage(e, T(e, MessageError, err.Error()))
+		return
+	}
+	if text == "" {
+		ReplyMessage(e, T(e, MessageUsage, scheduleUsage))
+		return
+	}
+	if _, ok := findCommand(strings.Fields(text)[0]); !ok {
+		ReplyMessage(e, T(e, MessageError, T(e, MessageCommandNotFound, strings.Fields(text)[0])))
+		return
+	}
+
+	loc := userLocation(e)
+	spec, err := ParseScheduleSpec(description, loc)
+	if err != nil {
+		ReplyMessage(e, T(e, MessageError, err.Error()))
+		return
+	}
+
+	u := &UserSchedule{
+		Description: description,
+		Spec:        spec,
+		Channel:     e.Channel(),
+		User:        e.User(),
+		Team:        installedTeam(e),
+		Enterprise:  e.String("enterprise_id"),
+		Text:        text,
+	}
+	if err := SaveUserSchedule(u); err != nil {
+		ReplyMessage(e, T(e, MessageError, err.Error()))
+		return
+	}
+
+	s, _ := u.Schedule()
+	next := s.Next(time.Now()).In(loc)
+	ReplyMessage(e, T(e, MessageScheduleAdded, u, next.Format("2006-01-02 15:04 MST")))
+}
+
+func listUserSchedules(e Event) {
+	list, err := ListUserSchedules()
+	if err != nil {
+		ReplyMessage(e, T(e, MessageError, err.Error()))
+		return
+	}
+
+	message := ""
+	for _, u := range list {
+		if u.Channel == e.Channel() {
+			message += u.String() + "\n"
+		}
+	}
+	ReplyMessage(e, selectString(message != "", message, T(e, MessageScheduleEmpty)))
+}
+
+func deleteUserSchedule(e Event, id string) {
+	u, err := GetUserSchedule(id)
+	if err == ErrNotFound {
+		ReplyMessage(e, T(e, MessageScheduleNotFound, id))
+		return
+	} else if err != nil {
+		ReplyMessage(e, T(e, MessageError, err.Error()))
+		return
+	}
+	if u.User != e.User() {
+		ReplyMessage(e, T(e, MessageScheduleNotPermitted, u.User))
+		return
+	}
+
+	if err := DeleteUserSchedule(id); err != nil {
+		ReplyMessage(e, T(e, MessageError, err.Error()))
+		return
+	}
+	ReplyMessage(e, T(e, MessageScheduleDeleted, id))
+}
@@ -0,0 +1,322 @@
+package slackbot
+
+import (
+	"context"
+	"html"
+	"testing"
+	"time"
+
+	"github.com/peto-tn/slackbot-go/fakeslack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScheduleSpec(t *testing.T) {
+	t.Parallel()
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+
+	t.Run("normal test", func(t *testing.T) {
+		cases := map[string]string{
+			"every weekday 09:30": "CRON_TZ=Asia/Tokyo 30 9 * * 1-5",
+			"every day 9:05":      "CRON_TZ=Asia/Tokyo 5 9 * * *",
+			"every weekend 10:00": "CRON_TZ=Asia/Tokyo 0 10 * * 0,6",
+			"Every Mondays 18:00": "CRON_TZ=Asia/Tokyo 0 18 * * 1",
+			"every hour":          "CRON_TZ=Asia/Tokyo 0 * * * *",
+			"every 15 minutes":    "CRON_TZ=Asia/Tokyo */15 * * * *",
+			"every 1 minute":      "CRON_TZ=Asia/Tokyo */1 * * * *",
+			"every 2 hours":       "CRON_TZ=Asia/Tokyo 0 */2 * * *",
+			"0 9 * * *":           "CRON_TZ=Asia/Tokyo 0 9 * * *",
+			"@daily":              "CRON_TZ=Asia/Tokyo @daily",
+			"CRON_TZ=UTC @daily":  "CRON_TZ=UTC @daily",
+		}
+		for description, expect := range cases {
+			spec, err := ParseScheduleSpec(description, tokyo)
+			assert.NoError(t, err, description)
+			assert.Equal(t, expect, spec, description)
+		}
+	})
+
+	t.Run("error test", func(t *testing.T) {
+		for _, description := range []string{"", "every", "every someday 09:00", "every day 25:00", "every day at 09:00", "invalid",
+			"every 0 minutes", "every 60 minutes", "every 24 hours", "every few hours", "@every 15m"} {
+			_, err := ParseScheduleSpec(description, tokyo)
+			assert.Error(t, err, description)
+		}
+	})
+}
+
+func TestParseScheduleSpec_Run(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	// the parsed specs fire at the minutes
+	cases := map[string]int{
+		"every 15 minutes": 8,
+		"every 2 hours":    1,
+		"every hour":       2,
+	}
+	for description, expect := range cases {
+		testRun(t, description, func(t *testing.T) {
+			spec, err := ParseScheduleSpec(description, time.UTC)
+			assert.NoError(t, err)
+			count := 0
+			err = AddScheduleJob(&Schedule{Spec: spec, Channel: "C1", Job: func(ctx context.Context, e Event) {
+				count++
+			}})
+			assert.NoError(t, err)
+
+			start := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+			for m := 0; m < 120; m++ {
+				RunDueSchedules(start.Add(time.Duration(m) * time.Minute))
+			}
+			assert.Equal(t, expect, count)
+		})
+	}
+}
+
+func TestParseRemindTime(t *testing.T) {
+	t.Parallel()
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, tokyo)
+
+	t.Run("in test", func(t *testing.T) {
+		at, err := ParseRemindTime([]string{"in", "2h"}, now, tokyo)
+		assert.NoError(t, err)
+		assert.Equal(t, now.Add(2*time.Hour), at)
+
+		at, err = ParseRemindTime([]string{"in", "3d"}, now, tokyo)
+		assert.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, 3), at)
+	})
+
+	t.Run("at test", func(t *testing.T) {
+		at, err := ParseRemindTime([]string{"at", "15:00"}, now, tokyo)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2020, 1, 1, 15, 0, 0, 0, tokyo), at)
+
+		at, err = ParseRemindTime([]string{"at", "09:00"}, now, tokyo)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2020, 1, 2, 9, 0, 0, 0, tokyo), at)
+	})
+
+	t.Run("error test", func(t *testing.T) {
+		for _, words := range [][]string{{"in"}, {"in", "xh"}, {"in", "-1h"}, {"in", "xd"}, {"at", "noon"}, {"on", "monday"}} {
+			_, err := ParseRemindTime(words, now, tokyo)
+			assert.Error(t, err, words)
+		}
+	})
+}
+
+func TestSplitQuoted(t *testing.T) {
+	t.Parallel()
+
+	t.Run("normal test", func(t *testing.T) {
+		first, rest, err := splitQuoted(`"every weekday 09:30" deploy status`)
+		assert.NoError(t, err)
+		assert.Equal(t, "every weekday 09:30", first)
+		assert.Equal(t, "deploy status", rest)
+	})
+
+	t.Run("smart quote test", func(t *testing.T) {
+		first, rest, err := splitQuoted("“every day 09:30” ping")
+		assert.NoError(t, err)
+		assert.Equal(t, "every day 09:30", first)
+		assert.Equal(t, "ping", rest)
+	})
+
+	t.Run("not quoted test", func(t *testing.T) {
+		first, rest, err := splitQuoted("@daily ping")
+		assert.NoError(t, err)
+		assert.Equal(t, "@daily", first)
+		assert.Equal(t, "ping", rest)
+
+		first, rest, err = splitQuoted("@daily")
+		assert.NoError(t, err)
+		assert.Equal(t, "@daily", first)
+		assert.Equal(t, "", rest)
+	})
+
+	t.Run("error test", func(t *testing.T) {
+		_, _, err := splitQuoted(`"every day ping`)
+		assert.Error(t, err)
+	})
+}
+
+func TestScheduleCommand(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		fake.Reset()
+		Setup("bot", "", "")
+		ToolsClearSchedule()
+		ToolsInitCommand()
+		SetupScheduleCommand()
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	run := func(user, text string) string {
+		fake.Reset()
+		fake.SetResponse("users.info", map[string]interface{}{
+			"ok":   true,
+			"user": map[string]interface{}{"id": user, "tz": "Asia/Tokyo"},
+		})
+		e := Event{"channel": "C1", "user": user, "event_ts": "1.0", "text": text}
+		onMessage(e)
+		calls := fake.CallsFor("chat.postMessage")
+		if len(calls) == 0 {
+			return ""
+		}
+		return html.UnescapeString(calls[len(calls)-1].Param("text"))
+	}
+
+	testRun(t, "add test", func(t *testing.T) {
+		reply := run("U1", `<@bot> schedule "every weekday 09:30" ping`)
+		assert.Regexp(t, "^Scheduled 1: every weekday 09:30 `ping` by <@U1>. Next run at .* JST.$", reply)
+
+		u, err := GetUserSchedule("1")
+		assert.NoError(t, err)
+		assert.Equal(t, "CRON_TZ=Asia/Tokyo 30 9 * * 1-5", u.Spec)
+		assert.Equal(t, "U1", u.User)
+		assert.Equal(t, "C1", u.Channel)
+	})
+
+	testRun(t, "list test", func(t *testing.T) {
+		run("U1", `schedule "every day 09:30" ping`)
+		run("U1", `schedule @hourly help`)
+		run("U1", `remind me in 2h check canary`)
+
+		reply := run("U1", "schedule list")
+		assert.Equal(t, "1: every day 09:30 `ping` by <@U1>\n2: @hourly `help` by <@U1>\n3: remind in 2h \"check canary\" by <@U1>\n", reply)
+	})
+
+	testRun(t, "list empty test", func(t *testing.T) {
+		assert.Equal(t, "No schedules.", run("U1", "schedule list"))
+	})
+
+	testRun(t, "delete test", func(t *testing.T) {
+		run("U1", `schedule @daily ping`)
+
+		assert.Equal(t, "error: only <@U1> can delete the schedule.", run("U2", "schedule delete 1"))
+		assert.Equal(t, "Deleted 1.", run("U1", "schedule delete 1"))
+		assert.Equal(t, "error: schedule not found: 1", run("U1", "schedule delete 1"))
+	})
+
+	testRun(t, "usage test", func(t *testing.T) {
+		assert.Equal(t, "usage: "+scheduleUsage, run("U1", "schedule"))
+		assert.Equal(t, "usage: "+scheduleUsage, run("U1", "schedule @daily"))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		assert.Equal(t, "error: command not found: undefined", run("U1", "schedule @daily undefined"))
+		assert.Equal(t, "error: invalid day: someday", run("U1", `schedule "every someday 09:00" ping`))
+		assert.Equal(t, "error: unclosed quote", run("U1", `schedule "every day 09:00 ping`))
+	})
+
+	testRun(t, "ja test", func(t *testing.T) {
+		assert.NoError(t, SetChannelLocale("C1", "ja"))
+		defer SetChannelLocale("C1", "")
+
+		assert.Regexp(t, "^1: @daily `ping` by <@U1> を登録しました。次回の実行は .* JST です。$", run("U1", `schedule @daily ping`))
+		assert.Equal(t, "エラー: <@U1> のみ削除できます。", run("U2", "schedule delete 1"))
+		assert.Equal(t, "1 を削除しました。", run("U1", "schedule delete 1"))
+		assert.Equal(t, "スケジュールはありません。", run("U1", "schedule list"))
+		assert.Equal(t, "エラー: コマンドが見つかりません: undefined", run("U1", "schedule @daily undefined"))
+		assert.Equal(t, "使い方: "+scheduleUsage, run("U1", "schedule"))
+	})
+
+	testRun(t, "run under creator test", func(t *testing.T) {
+		var user string
+		AddCommand(&Command{
+			Name: "whoami",
+			Execute: func(e Event, opt interface{}) {
+				user = e.User()
+			},
+		})
+		run("U1", `schedule @daily whoami`)
+
+		assert.True(t, RunSchedule("user-1"))
+		assert.Equal(t, "U1", user)
+	})
+}
+
+func TestRemindCommand(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		fake.Reset()
+		Setup("bot", "", "")
+		ToolsClearSchedule()
+		ToolsInitCommand()
+		SetupScheduleCommand()
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	run := func(text string) []fakeslack.Call {
+		fake.Reset()
+		onMessage(Event{"channel": "C1", "user": "U1", "event_ts": "1.0", "text": text})
+		return fake.CallsFor("chat.postMessage")
+	}
+	text := func(calls []fakeslack.Call) string {
+		return html.UnescapeString(calls[0].Param("text"))
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		calls := run("remind me in 2h check canary")
+		assert.Regexp(t, `^I will remind you at .*\. \(id: 1\)$`, text(calls))
+
+		u, err := GetUserSchedule("1")
+		assert.NoError(t, err)
+		assert.True(t, u.Remind)
+		assert.Equal(t, "check canary", u.Text)
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), u.At, time.Minute)
+	})
+
+	testRun(t, "due test", func(t *testing.T) {
+		run("remind me in 1m check canary")
+		fake.Reset()
+
+		assert.Equal(t, 0, RunDueSchedules(time.Now().Add(-time.Minute)))
+		assert.Equal(t, 1, RunDueSchedules(time.Now().Add(2*time.Minute)))
+		assert.Equal(t, "<@U1> check canary", fake.CallsFor("chat.postMessage")[0].Param("text"))
+
+		_, err := GetUserSchedule("1")
+		assert.Equal(t, ErrNotFound, err)
+		assert.Equal(t, 0, RunDueSchedules(time.Now().Add(3*time.Minute)))
+	})
+
+	testRun(t, "usage test", func(t *testing.T) {
+		assert.Equal(t, "usage: "+remindUsage, text(run("remind me in 2h")))
+		assert.Equal(t, "usage: "+remindUsage, text(run("remind you in 2h test")))
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		assert.Equal(t, "error: invalid duration: xh", text(run("remind me in xh test")))
+	})
+
+	testRun(t, "ja test", func(t *testing.T) {
+		assert.NoError(t, SetChannelLocale("C1", "ja"))
+		defer SetChannelLocale("C1", "")
+
+		assert.Regexp(t, `^.* にリマインドします。\(id: 1\)$`, text(run("remind me in 2h check canary")))
+		assert.Equal(t, "使い方: "+remindUsage, text(run("remind me in 2h")))
+	})
+}
+
+func TestStartScheduler_UserSchedule(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	testRun(t, "overdue test", func(t *testing.T) {
+		SaveUserSchedule(&UserSchedule{Description: "in 1s", At: time.Now().Add(-time.Hour), Channel: "C1", User: "U1", Text: "ping"})
+		var called bool
+		AddCommand(&Command{Name: "ping", Execute: func(e Event, opt interface{}) { called = true }})
+		defer ToolsInitCommand()
+		AddSchedule("@every 1s", "C1", func(ctx context.Context, e Event) {})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer cancel()
+		StartScheduler(ctx)
+
+		time.Sleep(10 * time.Millisecond)
+		assert.True(t, called)
+		_, err := GetUserSchedule("1")
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
@@ -0,0 +1,165 @@
+package slackbot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ToolsClearSchedule() {
+	ClearSchedule()
+	SetStore(nil)
+}
+
+func TestAddSchedule(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		s, err := AddSchedule("30 9 * * 1-5", "C1", func(ctx context.Context, e Event) {})
+		assert.NoError(t, err)
+		assert.Equal(t, "schedule-1", s.ID)
+		assert.Len(t, GetSchedules(), 1)
+
+		now := time.Date(2020, 1, 3, 10, 0, 0, 0, time.Local)
+		assert.Equal(t, time.Date(2020, 1, 6, 9, 30, 0, 0, time.Local), s.Next(now))
+	})
+
+	testRun(t, "timezone test", func(t *testing.T) {
+		s, err := AddSchedule("CRON_TZ=Asia/Tokyo 0 9 * * *", "C1", func(ctx context.Context, e Event) {})
+		assert.NoError(t, err)
+
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), s.Next(now.Add(-time.Second)).Unix())
+	})
+
+	testRun(t, "location test", func(t *testing.T) {
+		tokyo, _ := time.LoadLocation("Asia/Tokyo")
+		s := &Schedule{ID: "test", Spec: "0 9 * * *", Location: tokyo, Job: func(ctx context.Context, e Event) {}}
+		err := AddScheduleJob(s)
+		assert.NoError(t, err)
+		assert.Equal(t, "test", s.ID)
+
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, now.Unix(), s.Next(now.Add(-time.Second)).Unix())
+	})
+
+	testRun(t, "parse error test", func(t *testing.T) {
+		_, err := AddSchedule("invalid", "C1", func(ctx context.Context, e Event) {})
+		assert.Error(t, err)
+		assert.Len(t, GetSchedules(), 0)
+	})
+}
+
+func TestAddCommandSchedule(t *testing.T) {
+	clear := func() {
+		ToolsClearSchedule()
+		ToolsInitCommand()
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		var event Event
+		var option interface{}
+		AddCommand(&Command{
+			Name: "test",
+			Execute: func(e Event, opt interface{}) {
+				event = e
+				option = opt
+			},
+			Option: struct{ Message string }{},
+		})
+		s, err := AddCommandSchedule("@daily", "C1", "U1", "test  hoge")
+		assert.NoError(t, err)
+
+		assert.True(t, RunSchedule(s.ID))
+		assert.Equal(t, "C1", event.Channel())
+		assert.Equal(t, "U1", event.User())
+		assert.Equal(t, struct{ Message string }{"hoge"}, option)
+	})
+
+	testRun(t, "not found test", func(t *testing.T) {
+		s, _ := AddCommandSchedule("@daily", "C1", "U1", "undefined")
+		assert.True(t, RunSchedule(s.ID))
+	})
+}
+
+func TestRunSchedule(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		var event Event
+		var store Store
+		AddScheduleJob(&Schedule{ID: "test", Spec: "@daily", Channel: "C1", Job: func(ctx context.Context, e Event) {
+			event = e
+			store = StoreFromContext(ctx)
+		}})
+
+		assert.True(t, RunSchedule("test"))
+		assert.Equal(t, "schedule", event.Type())
+		assert.Equal(t, "test", event.String("schedule"))
+		assert.Equal(t, "C1", event.Channel())
+		assert.Equal(t, GetStore(), store)
+	})
+
+	testRun(t, "panic test", func(t *testing.T) {
+		AddScheduleJob(&Schedule{ID: "test", Spec: "@daily", Job: func(ctx context.Context, e Event) {
+			panic("test")
+		}})
+
+		assert.True(t, RunSchedule("test"))
+	})
+
+	testRun(t, "not found test", func(t *testing.T) {
+		assert.False(t, RunSchedule("test"))
+	})
+}
+
+func TestRunDueSchedules(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		called := []string{}
+		job := func(ctx context.Context, e Event) {
+			called = append(called, e.String("schedule"))
+		}
+		AddScheduleJob(&Schedule{ID: "due", Spec: "30 9 * * *", Job: job})
+		AddScheduleJob(&Schedule{ID: "not due", Spec: "31 9 * * *", Job: job})
+
+		count := RunDueSchedules(time.Date(2020, 1, 1, 9, 30, 12, 0, time.Local))
+		assert.Equal(t, 1, count)
+		assert.Equal(t, []string{"due"}, called)
+	})
+}
+
+func TestStartScheduler(t *testing.T) {
+	testRun := ToolsCreateTestRun(ToolsClearSchedule, ToolsClearSchedule)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		called := make(chan Event, 1)
+		AddSchedule("@every 1s", "C1", func(ctx context.Context, e Event) {
+			select {
+			case called <- e:
+			default:
+			}
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			StartScheduler(ctx)
+			close(done)
+		}()
+
+		select {
+		case e := <-called:
+			assert.Equal(t, "C1", e.Channel())
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "schedule is not run.")
+		}
+
+		cancel()
+		<-done
+	})
+}
@@ -0,0 +1,217 @@
+package slackbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SelfCheckResult of SelfCheck.
+type SelfCheckResult struct {
+	BotUserID string `json:"bot_user_id,omitempty"`
+	TeamID    string `json:"team_id,omitempty"`
+
+	// Scopes granted to the access token. nil if unknown.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// MissingScopes by the command name.
+	MissingScopes map[string][]string `json:"missing_scopes,omitempty"`
+
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+var (
+	requireScopes bool
+
+	// grantedScopes to the access token of Setup. nil if unknown.
+	grantedScopes map[string]bool
+
+	// selfCheckRunMu serializes SelfCheck, and selfCheckMu guards the result read while checking.
+	selfCheckRunMu  sync.Mutex
+	selfCheckMu     sync.Mutex
+	selfCheckResult *SelfCheckResult
+)
+
+// SetRequireScopes of the commands. If true, commands whose Scopes are not granted are not registered.
+// Otherwise they are only logged.
+func SetRequireScopes(require bool) {
+	requireScopes = require
+}
+
+// GetSelfCheckResult of the last SelfCheck. Returns nil if not checked yet.
+func GetSelfCheckResult() *SelfCheckResult {
+	selfCheckMu.Lock()
+	defer selfCheckMu.Unlock()
+	return selfCheckResult
+}
+
+// SelfCheck the access token of Setup by auth.test.
+// The bot user ID is resolved if not set or wrong, and the Scopes of the commands are compared with the granted scopes.
+// Nothing is checked without the access token, such as only for multiple workspaces.
+// Call it before handling events, since it updates the bot user ID and the commands.
+func SelfCheck() (*SelfCheckResult, error) {
+	return selfCheck(true)
+}
+
+// selfCheck updates the bot user ID, the granted scopes and the commands only if apply.
+// Otherwise it only reports the result, which is safe while handling events.
+func selfCheck(apply bool) (*SelfCheckResult, error) {
+	selfCheckRunMu.Lock()
+	defer selfCheckRunMu.Unlock()
+
+	r := &SelfCheckResult{CheckedAt: time.Now()}
+	if err := setupConfigFromEnv(true); err != nil {
+		r.Error = err.Error()
+		setSelfCheckResult(r)
+		return r, err
+	}
+	token := setupToken()
+	if token == "" {
+		setSelfCheckResult(r)
+		return r, nil
+	}
+
+	a, scopes, err := authTest(token)
+	if err != nil {
+		logError(nil, "self check error", "error", err)
+		r.Error = err.Error()
+		setSelfCheckResult(r)
+		return r, err
+	}
+	r.BotUserID, r.TeamID, r.Scopes = a.UserID, a.TeamID, scopes
+
+	// mentions are not detected with the wrong bot user ID
+	if slackBotUserID != a.UserID {
+		if slackBotUserID != "" {
+			logWarn(nil, "bot user ID mismatch", "configured", slackBotUserID, "actual", a.UserID)
+		}
+		if apply {
+			slackBotUserID = a.UserID
+		}
+	}
+
+	if scopes != nil {
+		granted := stringSet(scopes)
+		if granted == nil {
+			granted = map[string]bool{}
+		}
+		for _, key := range append([]string{}, commandKeys...) {
+			if missing := missingScopesIn(commands[key], granted); len(missing) > 0 {
+				if r.MissingScopes == nil {
+					r.MissingScopes = map[string][]string{}
+				}
+				r.MissingScopes[key] = missing
+				if apply {
+					refuseCommand(commands[key], missing)
+				}
+			}
+		}
+		if apply {
+			grantedScopes = granted
+		}
+	}
+
+	logInfo(nil, "self check", "bot_user_id", r.BotUserID, "team_id", r.TeamID, "scopes", strings.Join(scopes, ","))
+	setSelfCheckResult(r)
+	return r, nil
+}
+
+func setSelfCheckResult(r *SelfCheckResult) {
+	selfCheckMu.Lock()
+	defer selfCheckMu.Unlock()
+	selfCheckResult = r
+}
+
+// missingScopes of the command. Returns nil if the granted scopes are unknown.
+func missingScopes(c *Command) []string {
+	if grantedScopes == nil {
+		return nil
+	}
+	return missingScopesIn(c, grantedScopes)
+}
+
+// missingScopesIn the granted scopes.
+func missingScopesIn(c *Command, granted map[string]bool) []string {
+	missing := []string{}
+	for _, scope := range c.Scopes {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// refuseCommand with the missing scopes if required, otherwise logs them.
+// Returns true if refused.
+func refuseCommand(c *Command, missing []string) bool {
+	if !requireScopes {
+		logWarn(nil, "missing scopes", "command", c.Name, "scopes", strings.Join(missing, ","))
+		return false
+	}
+	logError(nil, "command refused for missing scopes", "command", c.Name, "scopes", strings.Join(missing, ","))
+	removeCommand(c.Name)
+	return true
+}
+
+// setupToken of the client created in Setup.
+func setupToken() string {
+	if c, ok := api.(*rotatingClient); ok {
+		_, token := c.current()
+		return token
+	}
+	return accessToken
+}
+
+// selfCheckTimeout of auth.test not to block the start.
+const selfCheckTimeout = 10 * time.Second
+
+// authTestResponse of auth.test.
+type authTestResponse struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error"`
+	UserID string `json:"user_id"`
+	TeamID string `json:"team_id"`
+}
+
+// authTest of the token. The granted scopes are returned from the X-OAuth-Scopes header, or nil if not present.
+func authTest(token string) (*authTestResponse, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, apiURL+"auth.test", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := instrumentHTTPClient(httpClient).Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	a := &authTestResponse{}
+	if err := json.NewDecoder(res.Body).Decode(a); err != nil {
+		return nil, nil, err
+	}
+	if !a.OK {
+		return nil, nil, errors.New("auth.test: " + a.Error)
+	}
+
+	var scopes []string
+	if header, ok := res.Header["X-Oauth-Scopes"]; ok {
+		scopes = []string{}
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return a, scopes, nil
+}
@@ -0,0 +1,211 @@
+package slackbot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peto-tn/slackbot-go/fakeslack"
+	"github.com/stretchr/testify/assert"
+)
+
+func ToolsClearSelfCheck() {
+	ToolsInitCommand()
+	SetRequireScopes(false)
+	grantedScopes = nil
+	selfCheckResult = nil
+	slackBotUserID = ""
+	accessToken = ""
+	api = nil
+}
+
+func TestSelfCheck(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		ToolsClearSelfCheck()
+		fake.Reset()
+	}
+	testRun := ToolsCreateTestRun(clear, ToolsClearSelfCheck)
+	addCommands := func() {
+		AddCommand(&Command{Name: "deploy", Scopes: []string{"chat:write"}, Execute: func(e Event, opt interface{}) {}})
+		AddCommand(&Command{Name: "upload", Scopes: []string{"files:write", "chat:write"}, Execute: func(e Event, opt interface{}) {}})
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		fake.SetScopes("chat:write", "commands")
+		Setup("", "", "xoxb-1")
+		addCommands()
+
+		r, err := SelfCheck()
+		assert.NoError(t, err)
+		assert.Equal(t, fakeslack.BotUserID, r.BotUserID)
+		assert.Equal(t, fakeslack.TeamID, r.TeamID)
+		assert.Equal(t, []string{"chat:write", "commands"}, r.Scopes)
+		assert.Equal(t, map[string][]string{"upload": {"files:write"}}, r.MissingScopes)
+		assert.Equal(t, r, GetSelfCheckResult())
+
+		// the bot user ID is resolved, and the command missing scopes is only logged
+		assert.Equal(t, fakeslack.BotUserID, slackBotUserID)
+		assert.Equal(t, "xoxb-1", fake.CallsFor("auth.test")[0].Param("token"))
+		_, ok := findCommand("upload")
+		assert.True(t, ok)
+	})
+
+	testRun(t, "require scopes test", func(t *testing.T) {
+		fake.SetScopes("chat:write")
+		SetRequireScopes(true)
+		Setup("", "", "xoxb-1")
+		addCommands()
+
+		_, err := SelfCheck()
+		assert.NoError(t, err)
+		_, ok := findCommand("upload")
+		assert.False(t, ok)
+		_, ok = findCommand("deploy")
+		assert.True(t, ok)
+
+		// commands added after the check are also refused
+		AddCommand(&Command{Name: "files", Scopes: []string{"files:read"}})
+		_, ok = findCommand("files")
+		assert.False(t, ok)
+	})
+
+	testRun(t, "wrong bot user ID test", func(t *testing.T) {
+		Setup("UWRONG", "", "xoxb-1")
+		addCommands()
+
+		r, err := SelfCheck()
+		assert.NoError(t, err)
+		assert.Equal(t, fakeslack.BotUserID, slackBotUserID)
+
+		// the granted scopes are unknown without the header
+		assert.Nil(t, r.Scopes)
+		assert.Nil(t, r.MissingScopes)
+	})
+
+	testRun(t, "no token test", func(t *testing.T) {
+		Setup("bot", "", "")
+
+		r, err := SelfCheck()
+		assert.NoError(t, err)
+		assert.Empty(t, r.BotUserID)
+		assert.Empty(t, fake.CallsFor("auth.test"))
+	})
+
+	testRun(t, "setup from env test", func(t *testing.T) {
+		botUserID, token := os.Getenv("SLACK_BOT_USER_ID"), os.Getenv("SLACK_ACCESS_TOKEN")
+		defer func() {
+			os.Setenv("SLACK_BOT_USER_ID", botUserID)
+			os.Setenv("SLACK_ACCESS_TOKEN", token)
+		}()
+		os.Setenv("SLACK_BOT_USER_ID", "")
+		os.Setenv("SLACK_ACCESS_TOKEN", "xoxb-env")
+
+		assert.NoError(t, setupFromEnv())
+		assert.Equal(t, fakeslack.BotUserID, slackBotUserID)
+	})
+
+	testRun(t, "setup from env with bot user ID test", func(t *testing.T) {
+		botUserID, token := os.Getenv("SLACK_BOT_USER_ID"), os.Getenv("SLACK_ACCESS_TOKEN")
+		defer func() {
+			os.Setenv("SLACK_BOT_USER_ID", botUserID)
+			os.Setenv("SLACK_ACCESS_TOKEN", token)
+		}()
+		os.Setenv("SLACK_BOT_USER_ID", fakeslack.BotUserID)
+		os.Setenv("SLACK_ACCESS_TOKEN", "xoxb-env")
+		fake.SetScopes("chat:write")
+		addCommands()
+
+		// the scopes are compared even if the bot user ID is set
+		assert.NoError(t, setupFromEnv())
+		assert.Equal(t, map[string][]string{"upload": {"files:write"}}, GetSelfCheckResult().MissingScopes)
+		assert.Len(t, fake.CallsFor("auth.test"), 1)
+
+		// once per process
+		assert.NoError(t, setupFromEnv())
+		assert.Len(t, fake.CallsFor("auth.test"), 1)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		fake.SetError("auth.test", "invalid_auth")
+		Setup("bot", "", "xoxb-1")
+
+		r, err := SelfCheck()
+		assert.EqualError(t, err, "auth.test: invalid_auth")
+		assert.Equal(t, "auth.test: invalid_auth", r.Error)
+		assert.Equal(t, "bot", slackBotUserID)
+	})
+}
+
+func TestServer_Readyz(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	clear := func() {
+		ToolsClearSelfCheck()
+		fake.Reset()
+		Setup("bot", "", "xoxb-1")
+	}
+	testRun := ToolsCreateTestRun(clear, ToolsClearSelfCheck)
+	readyz := func(s *Server) (int, readiness) {
+		atomic.StoreInt32(&s.ready, 1)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", ReadyzPath, nil))
+		body := readiness{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		fake.SetScopes("chat:write")
+		AddCommand(&Command{Name: "upload", Scopes: []string{"files:write"}})
+		SelfCheck()
+
+		code, body := readyz(NewServer(":0"))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok", body.Status)
+		assert.Equal(t, fakeslack.BotUserID, body.SelfCheck.BotUserID)
+		assert.Equal(t, []string{"files:write"}, body.SelfCheck.MissingScopes["upload"])
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		fake.SetError("auth.test", "invalid_auth")
+		SelfCheck()
+
+		code, body := readyz(NewServer(":0"))
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "error", body.Status)
+		assert.Equal(t, "auth.test: invalid_auth", body.SelfCheck.Error)
+
+		// retried after the interval, only reporting the result
+		fake.Reset()
+		fake.SetScopes("chat:write")
+		SetRequireScopes(true)
+		AddCommand(&Command{Name: "upload", Scopes: []string{"files:write"}})
+		selfCheckResult.CheckedAt = time.Now().Add(-selfCheckRetryInterval)
+		s := NewServer(":0")
+
+		// the last result is served while retried in background
+		code, _ = readyz(s)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		for i := 0; i < 50 && GetSelfCheckResult().Error != ""; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		code, body = readyz(s)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"files:write"}, body.SelfCheck.MissingScopes["upload"])
+		assert.Equal(t, "bot", slackBotUserID)
+		assert.Nil(t, grantedScopes)
+		_, ok := commands["upload"]
+		assert.True(t, ok)
+
+		code, _ = readyz(NewServer(":0").WithSelfCheck(false))
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
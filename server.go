@@ -1,13 +1,348 @@
 package slackbot
 
 import (
+	"context"
+	"encoding/json"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// Paths of the health checks of Server.
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+// Server is the http server of slackbot.
+// Slack requests are served at the pattern on a private ServeMux with the health checks and the metrics.
+type Server struct {
+	addr            string
+	pattern         string
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+	maxBodyBytes    int64
+	certFile        string
+	keyFile         string
+	scheduler       bool
+	selfCheck       bool
+
+	handlers []serverHandler
+	ready    int32
+
+	// checking is 1 while SelfCheck is retried in background.
+	checking int32
+}
+
+type serverHandler struct {
+	pattern string
+	handler http.Handler
+}
+
+// NewServer listening on addr with the default settings.
+func NewServer(addr string) *Server {
+	return &Server{
+		addr:            addr,
+		pattern:         "/",
+		readTimeout:     10 * time.Second,
+		writeTimeout:    30 * time.Second,
+		idleTimeout:     120 * time.Second,
+		shutdownTimeout: 30 * time.Second,
+		maxBodyBytes:    1 << 20,
+		scheduler:       true,
+		selfCheck:       true,
+	}
+}
+
+// WithPattern of the Slack requests. "/" is used by default.
+func (s *Server) WithPattern(pattern string) *Server {
+	s.pattern = pattern
+	return s
+}
+
+// WithTimeouts of reading a request, writing a response and keeping an idle connection.
+// Zero means no timeout.
+func (s *Server) WithTimeouts(read, write, idle time.Duration) *Server {
+	s.readTimeout = read
+	s.writeTimeout = write
+	s.idleTimeout = idle
+	return s
+}
+
+// WithShutdownTimeout of waiting for the in-flight requests and schedules on shutdown. 30 seconds by default.
+func (s *Server) WithShutdownTimeout(timeout time.Duration) *Server {
+	s.shutdownTimeout = timeout
+	return s
+}
+
+// WithMaxBodyBytes of a Slack request. 1MB by default, and zero means no limit.
+func (s *Server) WithMaxBodyBytes(n int64) *Server {
+	s.maxBodyBytes = n
+	return s
+}
+
+// WithTLS serves HTTPS with the certificate and the key files.
+func (s *Server) WithTLS(certFile, keyFile string) *Server {
+	s.certFile = certFile
+	s.keyFile = keyFile
+	return s
+}
+
+// WithScheduler runs the schedules in background if true. true by default.
+func (s *Server) WithScheduler(enabled bool) *Server {
+	s.scheduler = enabled
+	return s
+}
+
+// WithSelfCheck runs SelfCheck on start if true, and /readyz fails while it fails. true by default.
+func (s *Server) WithSelfCheck(enabled bool) *Server {
+	s.selfCheck = enabled
+	return s
+}
+
+// Handle registers the handler for the pattern on the ServeMux of the server.
+// The patterns of the Slack requests, the health checks and the metrics take precedence.
+func (s *Server) Handle(pattern string, handler http.Handler) *Server {
+	s.handlers = append(s.handlers, serverHandler{pattern: pattern, handler: handler})
+	return s
+}
+
+// Handler of the server, which serves the Slack requests, the health checks and the metrics.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	registered := map[string]bool{}
+	handle := func(pattern string, handler http.Handler) {
+		if !registered[pattern] {
+			registered[pattern] = true
+			mux.Handle(pattern, handler)
+		}
+	}
+
+	handle(s.pattern, http.HandlerFunc(s.serveSlack))
+	handle(HealthzPath, http.HandlerFunc(s.healthz))
+	handle(ReadyzPath, http.HandlerFunc(s.readyz))
+	handle(MetricsPath, MetricsHandler())
+	if oauthConfig != nil {
+		handle(InstallPath, InstallHandler())
+		handle(OAuthRedirectPath, OAuthRedirectHandler())
+	}
+	for _, h := range s.handlers {
+		handle(h.pattern, h.handler)
+	}
+	return mux
+}
+
+func (s *Server) serveSlack(w http.ResponseWriter, r *http.Request) {
+	if s.maxBodyBytes > 0 {
+		if r.ContentLength > s.maxBodyBytes {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+	}
+	OnCall(w, r)
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("ok"))
+}
+
+// selfCheckRetryInterval of SelfCheck failed, retried by /readyz.
+const selfCheckRetryInterval = 30 * time.Second
+
+// readiness of the server with the result of SelfCheck.
+type readiness struct {
+	Status    string           `json:"status"`
+	SelfCheck *SelfCheckResult `json:"self_check,omitempty"`
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.ready) == 0 {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	status := http.StatusOK
+	body := readiness{Status: "ok"}
+	if s.selfCheck {
+		result := GetSelfCheckResult()
+		if result != nil && result.Error != "" && time.Since(result.CheckedAt) >= selfCheckRetryInterval {
+			s.retrySelfCheck()
+		}
+		if result != nil && result.Error != "" {
+			status = http.StatusServiceUnavailable
+			body.Status = "error"
+		}
+		body.SelfCheck = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// retrySelfCheck in background not to delay the probe, which serves the last result until it finishes.
+// The result is only reported, not to update the commands while handling events.
+func (s *Server) retrySelfCheck() {
+	if !atomic.CompareAndSwapInt32(&s.checking, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&s.checking, 0)
+		selfCheck(false)
+	}()
+}
+
+// ListenAndServe until SIGTERM or SIGINT is received, then shuts down gracefully.
+func (s *Server) ListenAndServe() error {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			logInfo(nil, "shutdown", "signal", sig)
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	return s.Run(ctx)
+}
+
+// Run the server until ctx is done, then shuts down gracefully.
+// Returns ConfigError if the config of SLACKBOT_CONFIG is invalid.
+// The server stops accepting requests, and waits for the in-flight requests, schedules and tasks of MemoryDispatcher up to the shutdown timeout.
+func (s *Server) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve on the listener until ctx is done, then shuts down gracefully.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	// the config may set the workers, which start below
+	if err := setupConfigFromEnv(true); err != nil {
+		return err
+	}
+	if workerConfig.Concurrency > 0 {
+		d := NewMemoryDispatcher()
+		d.Workers = workerConfig.Concurrency
+		d.MaxQueue = workerConfig.MaxQueue
+		SetDispatcher(d)
+	}
+
+	srv := &http.Server{
+		Handler:      s.Handler(),
+		ReadTimeout:  s.readTimeout,
+		WriteTimeout: s.writeTimeout,
+		IdleTimeout:  s.idleTimeout,
+	}
+
+	// SelfCheck updates the commands before they are used in background and by requests
+	if s.selfCheck {
+		SelfCheck()
+	}
+
+	// the schedules and the workers of MemoryDispatcher run in background
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var background sync.WaitGroup
+	if s.scheduler {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			StartScheduler(backgroundCtx)
+		}()
+	}
+	if d, ok := dispatcher.(*MemoryDispatcher); ok {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			d.Start(backgroundCtx)
+		}()
+	}
+	backgroundDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(backgroundDone)
+	}()
+
+	errs := make(chan error, 1)
+	go func() {
+		if s.certFile != "" || s.keyFile != "" {
+			errs <- srv.ServeTLS(l, s.certFile, s.keyFile)
+		} else {
+			errs <- srv.Serve(l)
+		}
+	}()
+	atomic.StoreInt32(&s.ready, 1)
+
+	select {
+	case err := <-errs:
+		atomic.StoreInt32(&s.ready, 0)
+		stopBackground()
+		<-backgroundDone
+		return err
+	case <-ctx.Done():
+	}
+
+	atomic.StoreInt32(&s.ready, 0)
+	shutdownCtx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	// the in-flight requests may dispatch Tasks, which are run before the workers stop
+	err := srv.Shutdown(shutdownCtx)
+	d, _ := dispatcher.(*MemoryDispatcher)
+	if d != nil {
+		d.RunPending(shutdownCtx)
+	}
+	stopBackground()
+	select {
+	case <-backgroundDone:
+	case <-shutdownCtx.Done():
+		logWarn(nil, "shutdown timeout", "waiting", "schedules and tasks")
+	}
+	if d != nil && d.Len() > 0 {
+		logWarn(nil, "tasks dropped on shutdown", "count", d.Len())
+	}
+	if err := FlushCloudEvents(shutdownCtx); err != nil {
+		logWarn(nil, "cloudevents dropped on shutdown", "error", err)
+	}
+	if err == nil {
+		err = FlushMetrics()
+	}
+	return err
+}
+
 // ListenAndServe is start the http server. use net/http
+// Schedules are run in background, and the metrics are exposed at MetricsPath.
+// The handler is served for the other paths if not nil.
+//
+// Deprecated: Use NewServer, which returns the error.
 func ListenAndServe(pattern, addr string, handler http.Handler) {
-	http.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
-		OnCall(w, r)
-	})
-	http.ListenAndServe(addr, handler)
+	s := NewServer(addr).WithPattern(pattern)
+	if handler != nil {
+		s.Handle("/", handler)
+	}
+	if err := s.ListenAndServe(); err != nil {
+		logError(nil, "server error", "error", err)
+	}
 }
@@ -1,16 +1,314 @@
 package slackbot
 
 import (
+	"context"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestListenAndServe(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
 	go ListenAndServe("/", ":8585", nil)
 
-	resp, err := http.Get("http://localhost:8585")
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get("http://localhost:8585"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer resp.Body.Close()
 }
+
+func TestServer_Handler(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, nil)
+
+	serve := func(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		s := NewServer(":0").WithPattern("/slack")
+		h := s.Handler()
+
+		rec := serve(h, "POST", "/slack", `{"type":"url_verification", "challenge":"test"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "test", rec.Body.String())
+
+		rec = serve(h, "GET", HealthzPath, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ok", rec.Body.String())
+
+		rec = serve(h, "GET", MetricsPath, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(h, "GET", "/other", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	testRun(t, "readyz test", func(t *testing.T) {
+		s := NewServer(":0")
+		rec := serve(s.Handler(), "GET", ReadyzPath, "")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	testRun(t, "handle test", func(t *testing.T) {
+		other := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("other"))
+		})
+		h := NewServer(":0").Handle("/", other).Handle(HealthzPath, other).Handler()
+
+		// the Slack requests and the health checks take precedence
+		rec := serve(h, "POST", "/", `{"type":"url_verification", "challenge":"test"}`)
+		assert.Equal(t, "test", rec.Body.String())
+		rec = serve(h, "GET", HealthzPath, "")
+		assert.Equal(t, "ok", rec.Body.String())
+
+		h = NewServer(":0").WithPattern("/slack").Handle("/", other).Handler()
+		rec = serve(h, "GET", "/other", "")
+		assert.Equal(t, "other", rec.Body.String())
+	})
+
+	testRun(t, "oauth test", func(t *testing.T) {
+		SetOAuthConfig(OAuthConfig{ClientID: "client"})
+		defer func() {
+			oauthConfig = nil
+			ToolsClearInstallation()
+		}()
+		h := NewServer(":0").WithPattern("/slack/events").Handler()
+
+		rec := serve(h, "GET", InstallPath, "")
+		assert.Equal(t, http.StatusFound, rec.Code)
+		rec = serve(h, "GET", OAuthRedirectPath, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		h := NewServer(":0").WithMaxBodyBytes(10).Handler()
+
+		rec := serve(h, "POST", "/", `{"type":"url_verification", "challenge":"test"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+func TestServer_Serve(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, nil)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		})
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		s := NewServer(l.Addr().String()).WithScheduler(false).WithSelfCheck(false).Handle("/slow", slow)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- s.Serve(ctx, l)
+		}()
+
+		url := "http://" + l.Addr().String()
+		resp, err := http.Get(url + ReadyzPath)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+
+		bodies := make(chan string, 1)
+		go func() {
+			resp, err := http.Get(url + "/slow")
+			if err != nil {
+				bodies <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			bodies <- string(body)
+		}()
+		<-started
+
+		// the in-flight request is drained on shutdown
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case <-served:
+			t.Fatal("returned before the in-flight request finished")
+		default:
+		}
+		close(release)
+
+		assert.Equal(t, "done", <-bodies)
+		assert.NoError(t, <-served)
+	})
+
+	testRun(t, "dispatcher test", func(t *testing.T) {
+		ToolsInitCommand()
+		defer ToolsInitCommand()
+		ran := make(chan string, 1)
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			ran <- e.Text()
+		}})
+		d := NewMemoryDispatcher()
+		SetDispatcher(d)
+		defer SetDispatcher(nil)
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- NewServer(l.Addr().String()).WithScheduler(false).WithSelfCheck(false).Serve(ctx, l)
+		}()
+
+		// the workers of MemoryDispatcher run with the server
+		d.Dispatch(context.Background(), newTask(TaskAppMention, Event{"text": "test"}, nil))
+		select {
+		case text := <-ran:
+			assert.Equal(t, "test", text)
+		case <-time.After(time.Second):
+			t.Fatal("task is not run")
+		}
+		cancel()
+		assert.NoError(t, <-served)
+	})
+
+	testRun(t, "dispatcher shutdown test", func(t *testing.T) {
+		ToolsInitCommand()
+		defer ToolsInitCommand()
+		ran := make(chan string, 1)
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			ran <- e.Text()
+		}})
+		d := NewMemoryDispatcher()
+		SetDispatcher(d)
+		defer SetDispatcher(nil)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			d.Dispatch(context.Background(), newTask(TaskAppMention, Event{"text": "test"}, nil))
+		})
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- NewServer(l.Addr().String()).WithScheduler(false).WithSelfCheck(false).Handle("/slow", slow).Serve(ctx, l)
+		}()
+		go http.Get("http://" + l.Addr().String() + "/slow")
+		<-started
+
+		// the task dispatched by the in-flight request is run on shutdown
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		assert.NoError(t, <-served)
+		assert.Equal(t, 0, d.Len())
+		select {
+		case text := <-ran:
+			assert.Equal(t, "test", text)
+		default:
+			t.Fatal("task is not run")
+		}
+	})
+
+	testRun(t, "config test", func(t *testing.T) {
+		fake := ToolsStartFakeSlack()
+		defer ToolsStopFakeSlack(fake)
+		envs := map[string]string{}
+		for _, env := range configEnvs {
+			envs[env.name] = os.Getenv(env.name)
+			os.Unsetenv(env.name)
+		}
+		dir, _ := ioutil.TempDir("", "config")
+		defer func() {
+			for name, value := range envs {
+				os.Setenv(name, value)
+			}
+			os.RemoveAll(dir)
+			os.Unsetenv(ConfigEnv)
+			SetDispatcher(nil)
+			workerConfig = WorkerConfig{}
+			ToolsInitCommand()
+		}()
+		ToolsInitCommand()
+		AddCommand(&Command{Name: "test", Execute: func(e Event, opt interface{}) {
+			ReplyMessage(e, "done")
+		}})
+		api = nil
+		os.Setenv(ConfigEnv, ToolsWriteConfig(t, dir, "slackbot.yaml", `
+slack:
+  bot_user_id: UBOT
+  verification_token: token
+  access_token: xoxb-1
+worker:
+  concurrency: 2
+`))
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- NewServer(l.Addr().String()).WithScheduler(false).WithSelfCheck(false).Serve(ctx, l)
+		}()
+
+		// the workers of the dispatcher set by the config run the task
+		resp, err := http.Post("http://"+l.Addr().String(), "application/json",
+			strings.NewReader(`{"type":"event_callback", "token":"token", "event":{"type":"app_mention", "channel":"C1", "text":"<@UBOT> test"}}`))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		for i := 0; i < 100 && len(fake.Messages()) == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if assert.Len(t, fake.Messages(), 1) {
+			assert.Equal(t, "done", fake.Messages()[0].Text)
+		}
+		cancel()
+		assert.NoError(t, <-served)
+	})
+
+	testRun(t, "error test", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer l.Close()
+
+		err = NewServer(l.Addr().String()).Run(context.Background())
+		assert.Error(t, err)
+	})
+
+	testRun(t, "config error test", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "config")
+		defer os.RemoveAll(dir)
+		os.Setenv(ConfigEnv, ToolsWriteConfig(t, dir, "slackbot.yaml", "log:\n  level: verbose\n"))
+		defer os.Unsetenv(ConfigEnv)
+		api = nil
+
+		err := NewServer("127.0.0.1:0").Run(context.Background())
+		assert.IsType(t, &ConfigError{}, err)
+	})
+}
@@ -0,0 +1,252 @@
+package slackbot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session of multi-step conversation bound to channel, thread and user.
+type Session struct {
+	Channel         string            `json:"channel"`
+	ThreadTimestamp string            `json:"thread_ts"`
+	User            string            `json:"user"`
+	Step            string            `json:"step"`
+	Data            map[string]string `json:"data"`
+	ExpiresAt       time.Time         `json:"expires_at"`
+
+	next string
+}
+
+// Key of the Session in SessionStore.
+func (s *Session) Key() string {
+	return sessionKey(s.Channel, s.ThreadTimestamp, s.User)
+}
+
+// Next step of the Session.
+// The Session ends after the step if Next is not called.
+func (s *Session) Next(step string) {
+	s.next = step
+}
+
+// SessionStep handles a message in the thread of the Session.
+type SessionStep func(e Event, texts []string, s *Session)
+
+// SessionStore saves Sessions.
+type SessionStore interface {
+	Get(key string) (*Session, error)
+	Set(key string, s *Session) error
+	Delete(key string) error
+}
+
+var (
+	sessionSteps   = map[string]SessionStep{}
+	sessionStore   SessionStore
+	sessionTimeout = 10 * time.Minute
+
+	// sessionCancelWords end the Session when sent in the thread.
+	sessionCancelWords = []string{"cancel"}
+)
+
+// AddSessionStep for slackbot.
+func AddSessionStep(name string, step SessionStep) {
+	sessionSteps[name] = step
+}
+
+// ClearSessionStep all for slackbot.
+func ClearSessionStep() {
+	sessionSteps = map[string]SessionStep{}
+}
+
+// SetSessionStore for slackbot. Sessions are saved in Store by default.
+func SetSessionStore(store SessionStore) {
+	sessionStore = store
+}
+
+// SetSessionTimeout for slackbot.
+func SetSessionTimeout(timeout time.Duration) {
+	sessionTimeout = timeout
+}
+
+// StartSession in the thread of the Event. Next message of the user in the thread is handled by the step.
+func StartSession(e Event, step string, data map[string]string) (*Session, error) {
+	if _, ok := sessionSteps[step]; !ok {
+		return nil, errors.New("session step not found: " + step)
+	}
+	if data == nil {
+		data = map[string]string{}
+	}
+
+	s := &Session{
+		Channel:         e.Channel(),
+		ThreadTimestamp: e.ThreadTimestamp(),
+		User:            e.User(),
+		Step:            step,
+		Data:            data,
+		ExpiresAt:       time.Now().Add(sessionTimeout),
+	}
+	return s, getSessionStore().Set(s.Key(), s)
+}
+
+// CancelSession in the thread of the Event.
+func CancelSession(e Event) error {
+	return getSessionStore().Delete(sessionKey(e.Channel(), e.ThreadTimestamp(), e.User()))
+}
+
+// GetSession in the thread of the Event. Returns nil if no active Session.
+func GetSession(e Event) (*Session, error) {
+	key := sessionKey(e.Channel(), e.ThreadTimestamp(), e.User())
+	s, err := getSessionStore().Get(key)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if time.Now().After(s.ExpiresAt) {
+		return nil, getSessionStore().Delete(key)
+	}
+	return s, nil
+}
+
+// onSession handles the message by the active Session.
+func onSession(e Event, texts []string) bool {
+	s, err := GetSession(e)
+	if err != nil {
+		logError(e, "session error", "error", err)
+		return false
+	}
+	if s == nil {
+		return false
+	}
+
+	if len(texts) == 1 && containsString(sessionCancelWords, strings.ToLower(texts[0])) {
+		if err := CancelSession(e); err != nil {
+			logError(e, "session error", "error", err)
+		}
+		ReplyMessage(e, T(e, MessageSessionCanceled))
+		return true
+	}
+
+	step, ok := sessionSteps[s.Step]
+	if !ok {
+		logWarn(e, "session step not found", "step", s.Step)
+		CancelSession(e)
+		return false
+	}
+
+	s.next = ""
+	step(e, texts, s)
+
+	if s.next == "" {
+		err = getSessionStore().Delete(s.Key())
+	} else {
+		s.Step = s.next
+		s.ExpiresAt = time.Now().Add(sessionTimeout)
+		err = getSessionStore().Set(s.Key(), s)
+	}
+	if err != nil {
+		logError(e, "session error", "step", s.Step, "error", err)
+	}
+
+	return true
+}
+
+func getSessionStore() SessionStore {
+	if sessionStore == nil {
+		return NewStoreSessionStore(GetStore())
+	}
+	return sessionStore
+}
+
+func sessionKey(channel, threadTimestamp, user string) string {
+	return channel + ":" + threadTimestamp + ":" + user
+}
+
+// MemorySessionStore saves Sessions in memory.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string][]byte
+}
+
+// NewMemorySessionStore for slackbot.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string][]byte{}}
+}
+
+// Get Session by key. Returns nil if not found.
+func (m *MemorySessionStore) Get(key string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.sessions[key]
+	if !ok {
+		return nil, nil
+	}
+	s := &Session{}
+	return s, json.Unmarshal(data, s)
+}
+
+// Set Session by key.
+func (m *MemorySessionStore) Set(key string, s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[key] = data
+	return nil
+}
+
+// Delete Session by key.
+func (m *MemorySessionStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, key)
+	return nil
+}
+
+// StoreSessionStore saves Sessions in Store until they expire.
+type StoreSessionStore struct {
+	store Store
+}
+
+// NewStoreSessionStore for slackbot.
+func NewStoreSessionStore(store Store) *StoreSessionStore {
+	return &StoreSessionStore{store: store}
+}
+
+const sessionNamespace = "session"
+
+// Get Session by key. Returns nil if not found.
+func (m *StoreSessionStore) Get(key string) (*Session, error) {
+	data, err := m.store.Get(sessionNamespace, key)
+	if err == ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	return s, json.Unmarshal(data, s)
+}
+
+// Set Session by key.
+func (m *StoreSessionStore) Set(key string, s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(s.ExpiresAt)
+	if ttl <= 0 {
+		ttl = time.Nanosecond
+	}
+	return m.store.Set(sessionNamespace, key, data, ttl)
+}
+
+// Delete Session by key.
+func (m *StoreSessionStore) Delete(key string) error {
+	return m.store.Delete(sessionNamespace, key)
+}
@@ -0,0 +1,212 @@
+package slackbot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TestErrorSessionStore struct{}
+
+func (s *TestErrorSessionStore) Get(key string) (*Session, error) {
+	return nil, errors.New("error")
+}
+
+func (s *TestErrorSessionStore) Set(key string, session *Session) error {
+	return errors.New("error")
+}
+
+func (s *TestErrorSessionStore) Delete(key string) error {
+	return errors.New("error")
+}
+
+func TestStartSession(t *testing.T) {
+	clear := func() {
+		ClearSessionStep()
+		SetSessionStore(nil)
+		SetSessionTimeout(10 * time.Minute)
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	event := Event{"channel": "C1", "user": "U1", "event_ts": "1.0"}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		AddSessionStep("test", func(e Event, texts []string, s *Session) {})
+
+		s, err := StartSession(event, "test", map[string]string{"key": "value"})
+		assert.NoError(t, err)
+		assert.Equal(t, "C1:1.0:U1", s.Key())
+
+		s, err = GetSession(Event{"channel": "C1", "user": "U1", "thread_ts": "1.0"})
+		assert.NoError(t, err)
+		assert.Equal(t, "test", s.Step)
+		assert.Equal(t, "value", s.Data["key"])
+	})
+
+	testRun(t, "step not found test", func(t *testing.T) {
+		s, err := StartSession(event, "test", nil)
+		assert.Error(t, err)
+		assert.Nil(t, s)
+	})
+
+	testRun(t, "expired test", func(t *testing.T) {
+		AddSessionStep("test", func(e Event, texts []string, s *Session) {})
+		SetSessionTimeout(-time.Second)
+
+		StartSession(event, "test", nil)
+		s, err := GetSession(event)
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	testRun(t, "store error test", func(t *testing.T) {
+		AddSessionStep("test", func(e Event, texts []string, s *Session) {})
+		SetSessionStore(&TestErrorSessionStore{})
+
+		_, err := StartSession(event, "test", nil)
+		assert.Error(t, err)
+		_, err = GetSession(event)
+		assert.Error(t, err)
+	})
+}
+
+func TestCancelSession(t *testing.T) {
+	clear := func() {
+		ClearSessionStep()
+		SetSessionStore(nil)
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	event := Event{"channel": "C1", "user": "U1", "event_ts": "1.0"}
+
+	testRun(t, "normal test", func(t *testing.T) {
+		AddSessionStep("test", func(e Event, texts []string, s *Session) {})
+		StartSession(event, "test", nil)
+
+		err := CancelSession(event)
+		assert.NoError(t, err)
+
+		s, _ := GetSession(event)
+		assert.Nil(t, s)
+	})
+}
+
+func TestOnSession(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	slackBotUserID = "bot"
+	var steps []string
+	clear := func() {
+		fake.Reset()
+		Setup("", "", "")
+		ClearSessionStep()
+		SetSessionStore(nil)
+		steps = nil
+		AddSessionStep("first", func(e Event, texts []string, s *Session) {
+			steps = append(steps, "first:"+texts[0])
+			s.Data["first"] = texts[0]
+			s.Next("second")
+		})
+		AddSessionStep("second", func(e Event, texts []string, s *Session) {
+			steps = append(steps, "second:"+s.Data["first"]+":"+texts[0])
+		})
+	}
+	testRun := ToolsCreateTestRun(clear, clear)
+	start := Event{"channel": "C1", "user": "U1", "event_ts": "1.0"}
+	reply := func(user, text string) Event {
+		return Event{"channel": "C1", "user": user, "thread_ts": "1.0", "event_ts": "2.0", "text": text}
+	}
+
+	testRun(t, "multi step test", func(t *testing.T) {
+		StartSession(start, "first", nil)
+
+		onMessage(reply("U1", "production"))
+		onMessage(reply("U1", "<@bot> now"))
+		onMessage(reply("U1", "again"))
+
+		assert.Equal(t, []string{"first:production", "second:production:now"}, steps)
+	})
+
+	testRun(t, "other user test", func(t *testing.T) {
+		StartSession(start, "first", nil)
+
+		assert.False(t, onSession(reply("U2", "production"), []string{"production"}))
+		assert.Len(t, steps, 0)
+	})
+
+	testRun(t, "cancel test", func(t *testing.T) {
+		StartSession(start, "first", nil)
+
+		assert.True(t, onSession(reply("U1", "cancel"), []string{"cancel"}))
+		assert.False(t, onSession(reply("U1", "production"), []string{"production"}))
+		assert.Len(t, steps, 0)
+		assert.Equal(t, "Canceled.", fake.CallsFor("chat.postMessage")[0].Param("text"))
+	})
+
+	testRun(t, "step not found test", func(t *testing.T) {
+		StartSession(start, "first", nil)
+		ClearSessionStep()
+
+		assert.False(t, onSession(reply("U1", "production"), []string{"production"}))
+		s, _ := GetSession(start)
+		assert.Nil(t, s)
+	})
+
+	testRun(t, "store error test", func(t *testing.T) {
+		SetSessionStore(&TestErrorSessionStore{})
+
+		assert.False(t, onSession(reply("U1", "production"), []string{"production"}))
+	})
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	t.Parallel()
+	store := NewMemorySessionStore()
+
+	t.Run("normal test", func(t *testing.T) {
+		err := store.Set("key", &Session{Step: "test"})
+		assert.NoError(t, err)
+
+		s, err := store.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "test", s.Step)
+
+		err = store.Delete("key")
+		assert.NoError(t, err)
+
+		s, err = store.Get("key")
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+	})
+}
+
+func TestStoreSessionStore(t *testing.T) {
+	t.Parallel()
+	store := NewStoreSessionStore(NewMemoryStore())
+
+	t.Run("normal test", func(t *testing.T) {
+		err := store.Set("key", &Session{Step: "test", ExpiresAt: time.Now().Add(time.Minute)})
+		assert.NoError(t, err)
+
+		s, err := store.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "test", s.Step)
+
+		err = store.Delete("key")
+		assert.NoError(t, err)
+
+		s, err = store.Get("key")
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	t.Run("expired test", func(t *testing.T) {
+		store.Set("key", &Session{Step: "test", ExpiresAt: time.Now().Add(-time.Minute)})
+		time.Sleep(time.Millisecond)
+
+		s, err := store.Get("key")
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+	})
+}
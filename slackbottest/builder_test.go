@@ -0,0 +1,106 @@
+package slackbottest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func verify(t *testing.T, r *http.Request) []byte {
+	body, _ := ioutil.ReadAll(r.Body)
+	sv, err := slack.NewSecretsVerifier(r.Header, SigningSecret)
+	assert.NoError(t, err)
+	sv.Write(body)
+	assert.NoError(t, sv.Ensure())
+	return body
+}
+
+func TestMessage(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		r := Message("@bot test").User("U1").Channel("C1").Request()
+		body := verify(t, r)
+
+		p := map[string]interface{}{}
+		json.Unmarshal(body, &p)
+		assert.Equal(t, "event_callback", p["type"])
+		assert.Equal(t, VerificationToken, p["token"])
+
+		e := p["event"].(map[string]interface{})
+		assert.Equal(t, "message", e["type"])
+		assert.Equal(t, "<@"+BotUserID+"> test", e["text"])
+		assert.Equal(t, "U1", e["user"])
+		assert.Equal(t, "C1", e["channel"])
+	})
+
+	t.Run("retry test", func(t *testing.T) {
+		r := Message("test").Retry(1).Request()
+		assert.Equal(t, "1", r.Header.Get("X-Slack-Retry-Num"))
+	})
+
+	t.Run("unsigned test", func(t *testing.T) {
+		r := Message("test").SigningSecret("").Request()
+		assert.Equal(t, "", r.Header.Get("X-Slack-Signature"))
+	})
+}
+
+func TestMention(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		e := Mention("test").Event()
+		assert.Equal(t, "app_mention", e.Type())
+		assert.Equal(t, "<@"+BotUserID+"> test", e.Text())
+	})
+}
+
+func TestThreadReply(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		e := ThreadReply("1.0", "test").Event()
+		assert.Equal(t, "1.0", e.ThreadTimestamp())
+	})
+}
+
+func TestReaction(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		e := Reaction("eyes", "1.0").Channel("C1").Event()
+		assert.Equal(t, "reaction_added", e.Type())
+		assert.Equal(t, "eyes", e.Reaction())
+		assert.Equal(t, "C1", e.Item().Channel())
+		assert.Equal(t, "1.0", e.Item().String("ts"))
+	})
+}
+
+func TestSlashCommand(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		r := SlashCommand("/bot", "ping").User("U1").Channel("C1").Request()
+		body := verify(t, r)
+
+		form, _ := url.ParseQuery(string(body))
+		assert.Equal(t, "/bot", form.Get("command"))
+		assert.Equal(t, "ping", form.Get("text"))
+		assert.Equal(t, "U1", form.Get("user_id"))
+		assert.Equal(t, "C1", form.Get("channel_id"))
+		assert.Equal(t, VerificationToken, form.Get("token"))
+	})
+}
+
+func TestBlockAction(t *testing.T) {
+	t.Run("normal test", func(t *testing.T) {
+		r := BlockAction("confirm", "yes").User("U1").Channel("C1").Message("1.0").Request()
+		body := verify(t, r)
+
+		form, _ := url.ParseQuery(string(body))
+		p := map[string]interface{}{}
+		json.Unmarshal([]byte(form.Get("payload")), &p)
+		assert.Equal(t, "block_actions", p["type"])
+		assert.Equal(t, "U1", p["user"].(map[string]interface{})["id"])
+		assert.Equal(t, "1.0", p["message"].(map[string]interface{})["ts"])
+
+		action := p["actions"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "confirm", action["action_id"])
+		assert.Equal(t, "yes", action["value"])
+	})
+}
@@ -0,0 +1,189 @@
+package slackbottest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+	slackbot "github.com/peto-tn/slackbot-go"
+	"github.com/peto-tn/slackbot-go/fakeslack"
+)
+
+// Harness runs requests through the bot against a fake Slack Web API.
+// The bot is configured globally, so harnesses must not run in parallel.
+type Harness struct {
+	T       testing.TB
+	Slack   *fakeslack.Server
+	User    string
+	Channel string
+}
+
+// NewHarness sets up the bot with the fake Slack Web API and adds commands.
+func NewHarness(t testing.TB, commands ...*slackbot.Command) *Harness {
+	s := fakeslack.NewServer()
+	slackbot.SetAPIURL(s.URL())
+	slackbot.SetSigningSecret(SigningSecret)
+	slackbot.Setup(BotUserID, VerificationToken, AccessToken)
+	for _, c := range commands {
+		slackbot.AddCommand(c)
+	}
+
+	return &Harness{
+		T:       t,
+		Slack:   s,
+		User:    UserID,
+		Channel: ChannelID,
+	}
+}
+
+// Close the Harness and restore the Slack Web API.
+func (h *Harness) Close() {
+	h.Slack.Close()
+	slackbot.SetAPIURL(slack.APIURL)
+	slackbot.SetSigningSecret("")
+}
+
+// Say the message by the User in the Channel.
+func (h *Harness) Say(text string) *Result {
+	return h.Send(Message(text).User(h.User).Channel(h.Channel))
+}
+
+// SayInThread the message by the User in the thread of the Channel.
+func (h *Harness) SayInThread(threadTimestamp, text string) *Result {
+	return h.Send(ThreadReply(threadTimestamp, text).User(h.User).Channel(h.Channel))
+}
+
+// Send the request of the Builder to the bot.
+func (h *Harness) Send(b Builder) *Result {
+	before := len(h.Slack.Calls())
+
+	rec := httptest.NewRecorder()
+	slackbot.OnCall(rec, b.Request())
+
+	return &Result{
+		T:        h.T,
+		Response: rec,
+		Calls:    h.Slack.Calls()[before:],
+	}
+}
+
+// Result of the request sent by Harness.
+type Result struct {
+	T        testing.TB
+	Response *httptest.ResponseRecorder
+	Calls    []fakeslack.Call
+}
+
+// CallsFor the method in order.
+func (r *Result) CallsFor(method string) []fakeslack.Call {
+	calls := []fakeslack.Call{}
+	for _, c := range r.Calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Replies posted by the bot.
+func (r *Result) Replies() []string {
+	return texts(r.CallsFor("chat.postMessage"))
+}
+
+// Ephemerals posted by the bot.
+func (r *Result) Ephemerals() []string {
+	return texts(r.CallsFor("chat.postEphemeral"))
+}
+
+// ExpectStatus of the response.
+func (r *Result) ExpectStatus(code int) *Result {
+	r.T.Helper()
+	if r.Response.Code != code {
+		r.T.Errorf("expected status %d, but got %d", code, r.Response.Code)
+	}
+	return r
+}
+
+// ExpectReply posted by the bot.
+func (r *Result) ExpectReply(text string) *Result {
+	r.T.Helper()
+	if !containsText(r.Replies(), text, false) {
+		r.T.Errorf("expected reply %q, but got %q", text, r.Replies())
+	}
+	return r
+}
+
+// ExpectReplyContains the substring.
+func (r *Result) ExpectReplyContains(substr string) *Result {
+	r.T.Helper()
+	if !containsText(r.Replies(), substr, true) {
+		r.T.Errorf("expected reply containing %q, but got %q", substr, r.Replies())
+	}
+	return r
+}
+
+// ExpectThreadReply posted by the bot in the thread.
+func (r *Result) ExpectThreadReply(threadTimestamp, text string) *Result {
+	r.T.Helper()
+	for _, c := range r.CallsFor("chat.postMessage") {
+		if c.Param("thread_ts") == threadTimestamp && c.Param("text") == text {
+			return r
+		}
+	}
+	r.T.Errorf("expected reply %q in thread %s, but got %q", text, threadTimestamp, r.Replies())
+	return r
+}
+
+// ExpectEphemeral posted by the bot.
+func (r *Result) ExpectEphemeral(text string) *Result {
+	r.T.Helper()
+	if !containsText(r.Ephemerals(), text, false) {
+		r.T.Errorf("expected ephemeral %q, but got %q", text, r.Ephemerals())
+	}
+	return r
+}
+
+// ExpectEphemeralContains the substring.
+func (r *Result) ExpectEphemeralContains(substr string) *Result {
+	r.T.Helper()
+	if !containsText(r.Ephemerals(), substr, true) {
+		r.T.Errorf("expected ephemeral containing %q, but got %q", substr, r.Ephemerals())
+	}
+	return r
+}
+
+// ExpectNoReply from the bot.
+func (r *Result) ExpectNoReply() *Result {
+	r.T.Helper()
+	if replies := append(r.Replies(), r.Ephemerals()...); len(replies) != 0 {
+		r.T.Errorf("expected no reply, but got %q", replies)
+	}
+	return r
+}
+
+// ExpectCall of the Slack Web API method.
+func (r *Result) ExpectCall(method string) *Result {
+	r.T.Helper()
+	if len(r.CallsFor(method)) == 0 {
+		r.T.Errorf("expected call of %s, but not called", method)
+	}
+	return r
+}
+
+func texts(calls []fakeslack.Call) []string {
+	result := []string{}
+	for _, c := range calls {
+		result = append(result, c.Param("text"))
+	}
+	return result
+}
+
+func containsText(texts []string, target string, substring bool) bool {
+	for _, text := range texts {
+		if text == target || (substring && strings.Contains(text, target)) {
+			return true
+		}
+	}
+	return false
+}
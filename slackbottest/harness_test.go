@@ -0,0 +1,140 @@
+package slackbottest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type repeatOption struct {
+	Message string
+	Count   string `default:"1"`
+}
+
+var repeatCommand = &slackbot.Command{
+	Name: "repeat",
+	Execute: func(e slackbot.Event, opt interface{}) {
+		option := opt.(repeatOption)
+		count, _ := strconv.Atoi(option.Count)
+		slackbot.ReplyMessage(e, strings.Repeat(option.Message, count))
+	},
+	Option: repeatOption{},
+}
+
+type testReactionHandler struct {
+	reactions []string
+}
+
+func (h *testReactionHandler) OnReaction(e slackbot.Event) {
+	h.reactions = append(h.reactions, e.Reaction())
+}
+
+type testInteractionHandler struct {
+	types []string
+}
+
+func (h *testInteractionHandler) OnInteraction(p slackbot.Payload) {
+	h.types = append(h.types, p.Type())
+}
+
+func TestHarness_Say(t *testing.T) {
+	h := NewHarness(t, repeatCommand)
+	defer h.Close()
+
+	t.Run("mention test", func(t *testing.T) {
+		h.Say("@bot repeat hi 2").ExpectStatus(http.StatusOK).ExpectReply("hihi")
+	})
+
+	t.Run("message test", func(t *testing.T) {
+		r := h.Say("repeat hi")
+		r.ExpectReply("hi")
+		assert.Len(t, r.Calls, 1)
+	})
+
+	t.Run("ping test", func(t *testing.T) {
+		h.Say("@bot ping").ExpectReplyContains("pong")
+	})
+
+	t.Run("option error test", func(t *testing.T) {
+		h.Say("@bot repeat").ExpectReplyContains("option error.")
+	})
+
+	t.Run("no reply test", func(t *testing.T) {
+		h.Say("hello").ExpectNoReply()
+	})
+}
+
+func TestHarness_SayInThread(t *testing.T) {
+	h := NewHarness(t, repeatCommand)
+	defer h.Close()
+
+	t.Run("normal test", func(t *testing.T) {
+		h.SayInThread("1.0", "@bot repeat hi").ExpectThreadReply("1.0", "hi")
+	})
+}
+
+func TestHarness_Session(t *testing.T) {
+	h := NewHarness(t, &slackbot.Command{
+		Name: "deploy",
+		Execute: func(e slackbot.Event, opt interface{}) {
+			slackbot.ReplyMessage(e, "which environment?")
+			slackbot.StartSession(e, "deploy.environment", nil)
+		},
+	})
+	defer h.Close()
+	slackbot.AddSessionStep("deploy.environment", func(e slackbot.Event, texts []string, s *slackbot.Session) {
+		slackbot.ReplyMessage(e, "deploy to "+texts[0])
+	})
+	defer slackbot.ClearSessionStep()
+
+	t.Run("normal test", func(t *testing.T) {
+		b := Message("@bot deploy")
+		ts := b.Event().String("event_ts")
+		h.Send(b).ExpectThreadReply(ts, "which environment?")
+		h.SayInThread(ts, "production").ExpectThreadReply(ts, "deploy to production")
+		h.SayInThread(ts, "production").ExpectNoReply()
+	})
+}
+
+func TestHarness_Send(t *testing.T) {
+	h := NewHarness(t, repeatCommand)
+	defer h.Close()
+
+	t.Run("help test", func(t *testing.T) {
+		h.Send(Mention("help")).ExpectEphemeralContains("repeat")
+	})
+
+	t.Run("slash command test", func(t *testing.T) {
+		h.Send(SlashCommand("/bot", "repeat hi 3")).ExpectStatus(http.StatusOK).ExpectReply("hihihi")
+	})
+
+	t.Run("retry test", func(t *testing.T) {
+		h.Send(Message("repeat hi").Retry(1)).ExpectStatus(http.StatusOK).ExpectNoReply()
+	})
+
+	t.Run("invalid signature test", func(t *testing.T) {
+		h.Send(Message("repeat hi").SigningSecret("invalid")).ExpectStatus(http.StatusUnauthorized).ExpectNoReply()
+	})
+
+	t.Run("reaction test", func(t *testing.T) {
+		handler := &testReactionHandler{}
+		slackbot.SetReactionHandler(handler)
+		defer slackbot.SetReactionHandler(nil)
+
+		h.Send(Reaction("eyes", "1.0")).ExpectStatus(http.StatusOK)
+		assert.Equal(t, []string{"eyes"}, handler.reactions)
+	})
+
+	t.Run("interaction test", func(t *testing.T) {
+		handler := &testInteractionHandler{}
+		slackbot.SetInteractionHandler(handler)
+		defer slackbot.SetInteractionHandler(nil)
+
+		h.Send(BlockAction("confirm", "yes")).ExpectStatus(http.StatusOK)
+		assert.Equal(t, []string{"block_actions"}, handler.types)
+	})
+}
@@ -0,0 +1,98 @@
+package slackbottest
+
+import (
+	"testing"
+	"time"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// RunStoreTests checks the behavior of the slackbot.Store implementation.
+// The store must be empty.
+func RunStoreTests(t *testing.T, store slackbot.Store) {
+	t.Run("get set test", func(t *testing.T) {
+		_, err := store.Get("get", "key")
+		assert.Equal(t, slackbot.ErrNotFound, err)
+
+		assert.NoError(t, store.Set("get", "key", []byte("value"), 0))
+		value, err := store.Get("get", "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+
+		_, err = store.Get("other", "key")
+		assert.Equal(t, slackbot.ErrNotFound, err)
+	})
+
+	t.Run("delete test", func(t *testing.T) {
+		assert.NoError(t, store.Set("delete", "key", []byte("value"), 0))
+		assert.NoError(t, store.Delete("delete", "key"))
+		assert.NoError(t, store.Delete("delete", "key"))
+		assert.NoError(t, store.Delete("undefined", "key"))
+
+		_, err := store.Get("delete", "key")
+		assert.Equal(t, slackbot.ErrNotFound, err)
+	})
+
+	t.Run("list test", func(t *testing.T) {
+		keys, err := store.List("list")
+		assert.NoError(t, err)
+		assert.Len(t, keys, 0)
+
+		store.Set("list", "b", []byte("value"), 0)
+		store.Set("list", "a", []byte("value"), 0)
+		store.Set("list", "expired", []byte("value"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		keys, err = store.List("list")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, keys)
+	})
+
+	t.Run("ttl test", func(t *testing.T) {
+		store.Set("ttl", "key", []byte("value"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		_, err := store.Get("ttl", "key")
+		assert.Equal(t, slackbot.ErrNotFound, err)
+
+		store.Set("ttl", "key", []byte("value"), time.Hour)
+		value, err := store.Get("ttl", "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+	})
+
+	t.Run("compare and swap test", func(t *testing.T) {
+		swapped, err := store.CompareAndSwap("cas", "key", nil, []byte("1"), 0)
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+
+		swapped, err = store.CompareAndSwap("cas", "key", nil, []byte("2"), 0)
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+
+		swapped, err = store.CompareAndSwap("cas", "key", []byte("2"), []byte("3"), 0)
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+
+		swapped, err = store.CompareAndSwap("cas", "key", []byte("1"), []byte("2"), 0)
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+
+		value, _ := store.Get("cas", "key")
+		assert.Equal(t, []byte("2"), value)
+	})
+
+	t.Run("compare and swap expired test", func(t *testing.T) {
+		store.Set("cas-expired", "key", []byte("1"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		swapped, err := store.CompareAndSwap("cas-expired", "key", []byte("1"), []byte("2"), 0)
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+
+		swapped, err = store.CompareAndSwap("cas-expired", "key", nil, []byte("2"), time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+	})
+}
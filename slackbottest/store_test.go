@@ -0,0 +1,11 @@
+package slackbottest
+
+import (
+	"testing"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+)
+
+func TestRunStoreTests(t *testing.T) {
+	RunStoreTests(t, slackbot.NewMemoryStore())
+}
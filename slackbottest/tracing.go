@@ -0,0 +1,43 @@
+package slackbottest
+
+import (
+	slackbot "github.com/peto-tn/slackbot-go"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// SpanRecorder records the spans of slackbot in memory.
+type SpanRecorder struct {
+	*tracetest.SpanRecorder
+}
+
+// RecordSpans of slackbot in memory. Call Close to restore the TracerProvider.
+func RecordSpans() *SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	slackbot.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return &SpanRecorder{SpanRecorder: recorder}
+}
+
+// Names of the ended spans in order.
+func (r *SpanRecorder) Names() []string {
+	names := []string{}
+	for _, span := range r.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+// Span ended with the name. Returns nil if not found.
+func (r *SpanRecorder) Span(name string) sdktrace.ReadOnlySpan {
+	for _, span := range r.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+// Close the SpanRecorder and restore the TracerProvider.
+func (r *SpanRecorder) Close() {
+	slackbot.SetTracerProvider(nil)
+}
@@ -0,0 +1,45 @@
+package slackbottest
+
+import (
+	"context"
+	"testing"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRecordSpans(t *testing.T) {
+	spans := RecordSpans()
+	defer spans.Close()
+
+	var commandSpan trace.SpanContext
+
+	h := NewHarness(t, &slackbot.Command{
+		Name: "deploy",
+		ExecuteContext: func(ctx context.Context, e slackbot.Event, opt interface{}) {
+			commandSpan = trace.SpanContextFromContext(ctx)
+			slackbot.ReplyMessage(e, "deployed")
+		},
+	})
+	defer h.Close()
+
+	h.Say("@bot deploy").ExpectReply("deployed")
+
+	assert.Equal(t, []string{
+		"slackbot.parse_option",
+		"slack chat.postMessage",
+		"slackbot.command deploy",
+		"slackbot.event message",
+		"slackbot.request",
+	}, spans.Names())
+
+	request := spans.Span("slackbot.request")
+	command := spans.Span("slackbot.command deploy")
+	api := spans.Span("slack chat.postMessage")
+	assert.Equal(t, trace.SpanKindServer, request.SpanKind())
+	assert.Equal(t, trace.SpanKindClient, api.SpanKind())
+	assert.Equal(t, request.SpanContext().TraceID(), api.SpanContext().TraceID())
+	assert.Equal(t, command.SpanContext().SpanID(), api.Parent().SpanID())
+	assert.Equal(t, command.SpanContext(), commandSpan)
+}
@@ -0,0 +1,27 @@
+//go:build go1.21
+// +build go1.21
+
+package slackbot
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger writes the log by log/slog.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger of log/slog. The default logger is used if l is nil.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	l := s.l
+	if l == nil {
+		l = slog.Default()
+	}
+	l.Log(context.Background(), slog.Level(level), msg, keyvals...)
+}
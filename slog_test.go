@@ -0,0 +1,25 @@
+//go:build go1.21
+// +build go1.21
+
+package slackbot
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, ToolsResetLogger)
+
+	testRun(t, "normal test", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		SetLogger(NewSlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+		SetLogLevel(LogLevelDebug)
+
+		logDebug(Event{"channel": "C1"}, "command executed", "command", "ping", "token", "xoxb-1")
+		assert.Contains(t, buf.String(), `level=DEBUG msg="command executed" channel=C1 command=ping token=[REDACTED]`)
+	})
+}
@@ -0,0 +1,140 @@
+// Package sqlitestore is a slackbot.Store saved in a SQLite database.
+//
+// The database is opened by the caller with a SQLite driver of choice.
+//
+//	db, _ := sql.Open("sqlite3", "slackbot.db")
+//	store, _ := sqlitestore.New(db)
+//	slackbot.SetStore(store)
+package sqlitestore
+
+import (
+	"database/sql"
+	"time"
+
+	slackbot "github.com/peto-tn/slackbot-go"
+)
+
+const schema = `CREATE TABLE IF NOT EXISTS slackbot_store (
+	namespace  TEXT    NOT NULL,
+	key        TEXT    NOT NULL,
+	value      BLOB    NOT NULL,
+	expires_at INTEGER NOT NULL,
+	PRIMARY KEY (namespace, key)
+)`
+
+// live condition of the row at the time of the last parameter.
+const live = `(expires_at = 0 OR expires_at > ?)`
+
+// Store saves the bot state in a SQLite table.
+type Store struct {
+	db *sql.DB
+}
+
+// New Store with the opened SQLite database. The table is created if not exists.
+func New(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func now() int64 {
+	return time.Now().UnixNano()
+}
+
+func expiresAt(ttl time.Duration) int64 {
+	if ttl == 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).UnixNano()
+}
+
+// Get the value.
+func (s *Store) Get(namespace, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow(
+		`SELECT value FROM slackbot_store WHERE namespace = ? AND key = ? AND `+live,
+		namespace, key, now(),
+	).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, slackbot.ErrNotFound
+	}
+	return value, err
+}
+
+// Set the value.
+func (s *Store) Set(namespace, key string, value []byte, ttl time.Duration) error {
+	_, err := s.db.Exec(
+		`INSERT OR REPLACE INTO slackbot_store (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)`,
+		namespace, key, nonNil(value), expiresAt(ttl),
+	)
+	return err
+}
+
+// Delete the value.
+func (s *Store) Delete(namespace, key string) error {
+	_, err := s.db.Exec(
+		`DELETE FROM slackbot_store WHERE namespace = ? AND key = ?`,
+		namespace, key,
+	)
+	return err
+}
+
+// List keys in the namespace in order.
+func (s *Store) List(namespace string) ([]string, error) {
+	rows, err := s.db.Query(
+		`SELECT key FROM slackbot_store WHERE namespace = ? AND `+live+` ORDER BY key`,
+		namespace, now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// CompareAndSwap the value.
+func (s *Store) CompareAndSwap(namespace, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	var result sql.Result
+	var err error
+	if old == nil {
+		if _, err = s.db.Exec(
+			`DELETE FROM slackbot_store WHERE namespace = ? AND key = ? AND NOT `+live,
+			namespace, key, now(),
+		); err != nil {
+			return false, err
+		}
+		result, err = s.db.Exec(
+			`INSERT OR IGNORE INTO slackbot_store (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)`,
+			namespace, key, nonNil(value), expiresAt(ttl),
+		)
+	} else {
+		result, err = s.db.Exec(
+			`UPDATE slackbot_store SET value = ?, expires_at = ? WHERE namespace = ? AND key = ? AND value = ? AND `+live,
+			nonNil(value), expiresAt(ttl), namespace, key, old, now(),
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// nonNil value because NULL is not allowed.
+func nonNil(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
+}
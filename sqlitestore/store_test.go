@@ -0,0 +1,36 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/peto-tn/slackbot-go/slackbottest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	store, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slackbottest.RunStoreTests(t, store)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("error test", func(t *testing.T) {
+		db, _ := sql.Open("sqlite3", ":memory:")
+		db.Close()
+
+		_, err := New(db)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,157 @@
+package slackbot
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Store when the key is not found or expired.
+var ErrNotFound = errors.New("not found")
+
+// Store of the bot state. Keys are grouped by namespace.
+// A ttl of zero means the value never expires.
+type Store interface {
+	Get(namespace, key string) ([]byte, error)
+	Set(namespace, key string, value []byte, ttl time.Duration) error
+	Delete(namespace, key string) error
+	List(namespace string) ([]string, error)
+
+	// CompareAndSwap sets the value only if the current value equals old.
+	// A nil old means the key must not exist.
+	CompareAndSwap(namespace, key string, old, value []byte, ttl time.Duration) (bool, error)
+}
+
+var (
+	store   Store
+	storeMu sync.Mutex
+)
+
+// SetStore for slackbot. MemoryStore is used by default.
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+// GetStore of slackbot.
+func GetStore() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return store
+}
+
+// expiresAt of the ttl. Returns zero time if the ttl is zero.
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired() bool {
+	return !e.expiresAt.IsZero() && time.Now().After(e.expiresAt)
+}
+
+// MemoryStore saves the bot state in memory.
+type MemoryStore struct {
+	mu         sync.Mutex
+	namespaces map[string]map[string]memoryEntry
+}
+
+// NewMemoryStore for slackbot.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{namespaces: map[string]map[string]memoryEntry{}}
+}
+
+// get the entry. Expired entry is deleted.
+func (m *MemoryStore) get(namespace, key string) (memoryEntry, bool) {
+	entry, ok := m.namespaces[namespace][key]
+	if ok && entry.expired() {
+		delete(m.namespaces[namespace], key)
+		return memoryEntry{}, false
+	}
+	return entry, ok
+}
+
+func (m *MemoryStore) set(namespace, key string, value []byte, ttl time.Duration) {
+	if _, ok := m.namespaces[namespace]; !ok {
+		m.namespaces[namespace] = map[string]memoryEntry{}
+	}
+	m.namespaces[namespace][key] = memoryEntry{
+		value:     append([]byte{}, value...),
+		expiresAt: expiresAt(ttl),
+	}
+}
+
+// Get the value.
+func (m *MemoryStore) Get(namespace, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.get(namespace, key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, entry.value...), nil
+}
+
+// Set the value.
+func (m *MemoryStore) Set(namespace, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(namespace, key, value, ttl)
+	return nil
+}
+
+// Delete the value.
+func (m *MemoryStore) Delete(namespace, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.namespaces[namespace], key)
+	return nil
+}
+
+// List keys in the namespace in order.
+func (m *MemoryStore) List(namespace string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []string{}
+	for key := range m.namespaces[namespace] {
+		if _, ok := m.get(namespace, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// CompareAndSwap the value.
+func (m *MemoryStore) CompareAndSwap(namespace, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.get(namespace, key)
+	if old == nil && ok {
+		return false, nil
+	}
+	if old != nil && (!ok || !bytes.Equal(entry.value, old)) {
+		return false, nil
+	}
+
+	m.set(namespace, key, value, ttl)
+	return true, nil
+}
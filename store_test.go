@@ -0,0 +1,85 @@
+package slackbot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetStore(t *testing.T) {
+	testRun := ToolsCreateTestRun(nil, func() {
+		SetStore(nil)
+	})
+
+	testRun(t, "normal test", func(t *testing.T) {
+		s := NewMemoryStore()
+		SetStore(s)
+		assert.Equal(t, s, GetStore())
+	})
+
+	testRun(t, "default test", func(t *testing.T) {
+		SetStore(nil)
+		assert.NotNil(t, GetStore())
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryStore()
+
+	t.Run("get set test", func(t *testing.T) {
+		_, err := store.Get("test", "key")
+		assert.Equal(t, ErrNotFound, err)
+
+		value := []byte("value")
+		store.Set("test", "key", value, 0)
+		value[0] = 'V'
+
+		result, err := store.Get("test", "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), result)
+	})
+
+	t.Run("delete test", func(t *testing.T) {
+		store.Set("test", "delete", []byte("value"), 0)
+		store.Delete("test", "delete")
+
+		_, err := store.Get("test", "delete")
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("list test", func(t *testing.T) {
+		store.Set("list", "b", []byte("value"), 0)
+		store.Set("list", "a", []byte("value"), 0)
+		store.Set("list", "expired", []byte("value"), -time.Second)
+
+		keys, err := store.List("list")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, keys)
+	})
+
+	t.Run("ttl test", func(t *testing.T) {
+		store.Set("test", "ttl", []byte("value"), -time.Second)
+
+		_, err := store.Get("test", "ttl")
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("compare and swap test", func(t *testing.T) {
+		swapped, _ := store.CompareAndSwap("cas", "key", nil, []byte("1"), 0)
+		assert.True(t, swapped)
+
+		swapped, _ = store.CompareAndSwap("cas", "key", nil, []byte("2"), 0)
+		assert.False(t, swapped)
+
+		swapped, _ = store.CompareAndSwap("cas", "key", []byte("2"), []byte("3"), 0)
+		assert.False(t, swapped)
+
+		swapped, _ = store.CompareAndSwap("cas", "key", []byte("1"), []byte("2"), 0)
+		assert.True(t, swapped)
+
+		result, _ := store.Get("cas", "key")
+		assert.Equal(t, []byte("2"), result)
+	})
+}
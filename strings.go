@@ -1,6 +1,11 @@
 package slackbot
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/peto-tn/slackbot-go/mrkdwn"
+)
 
 func addBrackets(s string) string {
 	if s == "" {
@@ -9,13 +14,6 @@ func addBrackets(s string) string {
 	return "(" + s + ")"
 }
 
-func encloseString(s, character string) string {
-	if s == "" {
-		return s
-	}
-	return character + s + character
-}
-
 func encloseSubstring(s, target, character string) string {
 	if s == "" || target == "" {
 		return s
@@ -24,17 +22,24 @@ func encloseSubstring(s, target, character string) string {
 }
 
 func boldString(s string) string {
-	return encloseString(s, "*")
+	return mrkdwn.Bold(s)
 }
 
 func italicString(s string) string {
-	return encloseString(s, "_")
+	return mrkdwn.Italic(s)
 }
 
 func boldSubstring(s, target string) string {
 	return encloseSubstring(s, target, "*")
 }
 
+func truncateString(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 func selectString(condition bool, trueValue, falseValue string) string {
 	if condition {
 		return trueValue
@@ -42,6 +47,13 @@ func selectString(condition bool, trueValue, falseValue string) string {
 	return falseValue
 }
 
+func selectInt(condition bool, trueValue, falseValue int) int {
+	if condition {
+		return trueValue
+	}
+	return falseValue
+}
+
 func containsString(strs []string, target string) bool {
 	if target == "" {
 		return false
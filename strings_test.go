@@ -19,19 +19,6 @@ func TestAddBrackets(t *testing.T) {
 	})
 }
 
-func TestEncloseString(t *testing.T) {
-	t.Parallel()
-	t.Run("normal test", func(t *testing.T) {
-		result := encloseString("test", "*")
-		assert.Equal(t, "*test*", result)
-	})
-
-	t.Run("error test", func(t *testing.T) {
-		result := encloseString("", "*")
-		assert.Equal(t, "", result)
-	})
-}
-
 func TestEncloseSubstring(t *testing.T) {
 	t.Parallel()
 	t.Run("normal test", func(t *testing.T) {
@@ -72,6 +59,15 @@ func TestBoldSubstring(t *testing.T) {
 	})
 }
 
+func TestTruncateString(t *testing.T) {
+	t.Parallel()
+	t.Run("normal test", func(t *testing.T) {
+		assert.Equal(t, "test", truncateString("test", 4))
+		assert.Equal(t, "te", truncateString("test", 2))
+		assert.Equal(t, "あい", truncateString("あいう", 2))
+	})
+}
+
 func TestSelectString(t *testing.T) {
 	t.Parallel()
 	t.Run("true test", func(t *testing.T) {
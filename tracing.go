@@ -0,0 +1,75 @@
+package slackbot
+
+import (
+	"context"
+
+	"github.com/nlopes/slack"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName of the spans of slackbot.
+const tracerName = "github.com/peto-tn/slackbot-go"
+
+var (
+	tracerProvider trace.TracerProvider
+)
+
+// SetTracerProvider for slackbot. The global TracerProvider of OpenTelemetry is used by default.
+func SetTracerProvider(tp trace.TracerProvider) {
+	tracerProvider = tp
+}
+
+func tracer() trace.Tracer {
+	if tracerProvider == nil {
+		return otel.GetTracerProvider().Tracer(tracerName)
+	}
+	return tracerProvider.Tracer(tracerName)
+}
+
+func startSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// endSpan with the error if not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// contextClient is a Client accepting context, such as *slack.Client.
+// The context is used to join the spans of Slack Web API calls to the trace of the Event.
+type contextClient interface {
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error)
+	UpdateMessageContext(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+}
+
+func postMessage(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error) {
+	client := clientFor(ctx)
+	if c, ok := client.(contextClient); ok {
+		return c.PostMessageContext(ctx, channelID, options...)
+	}
+	return client.PostMessage(channelID, options...)
+}
+
+func postEphemeral(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error) {
+	client := clientFor(ctx)
+	if c, ok := client.(contextClient); ok {
+		return c.PostEphemeralContext(ctx, channelID, userID, options...)
+	}
+	return client.PostEphemeral(channelID, userID, options...)
+}
+
+func updateMessage(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	client := clientFor(ctx)
+	if c, ok := client.(contextClient); ok {
+		return c.UpdateMessageContext(ctx, channelID, timestamp, options...)
+	}
+	return client.UpdateMessage(channelID, timestamp, options...)
+}
@@ -0,0 +1,136 @@
+package slackbot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func ToolsRecordSpans() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestEvent_Context(t *testing.T) {
+	t.Parallel()
+
+	e := Event{"channel": "C1"}
+	assert.Equal(t, context.Background(), e.Context())
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	e.SetContext(ctx)
+	assert.Equal(t, ctx, e.Context())
+	assert.Equal(t, Event{"channel": "C1"}, e.withoutContext())
+
+	p := Payload{}
+	assert.Equal(t, context.Background(), p.Context())
+	p.SetContext(ctx)
+	assert.Equal(t, ctx, p.Context())
+}
+
+func TestTracing(t *testing.T) {
+	fake := ToolsStartFakeSlack()
+	defer ToolsStopFakeSlack(fake)
+
+	var recorder *tracetest.SpanRecorder
+	clear := func() {
+		recorder = ToolsRecordSpans()
+		ToolsInitCommand()
+		ToolsClearSchedule()
+		Setup("bot", "", "")
+	}
+	testRun := ToolsCreateTestRun(clear, func() {
+		SetTracerProvider(nil)
+		ToolsInitCommand()
+		ToolsClearSchedule()
+	})
+
+	testRun(t, "command test", func(t *testing.T) {
+		var ctx context.Context
+		AddCommand(&Command{Name: "test", ExecuteContext: func(c context.Context, e Event, opt interface{}) {
+			ctx = c
+			ReplyMessage(e, "ok")
+		}})
+
+		parent, span := startSpan(context.Background(), "parent", 0)
+		e := Event{"channel": "C1", "text": "test"}
+		e.SetContext(parent)
+		onMessage(e)
+		span.End()
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 4)
+		assert.Equal(t, "slackbot.parse_option", spans[0].Name())
+		assert.Equal(t, "slack chat.postMessage", spans[1].Name())
+		assert.Equal(t, "slackbot.command test", spans[2].Name())
+		assert.Equal(t, spans[3].SpanContext().SpanID(), spans[2].Parent().SpanID())
+		assert.Equal(t, spans[2].SpanContext().SpanID(), spans[1].Parent().SpanID())
+		assert.Equal(t, StoreFromContext(ctx), GetStore())
+
+		// context of the Event is restored
+		assert.Equal(t, parent, e.Context())
+	})
+
+	testRun(t, "token rotation test", func(t *testing.T) {
+		SetTokenRotation(TokenRotation{RefreshToken: "xoxe-1"})
+		defer func() {
+			tokenRotation = nil
+			SetStore(nil)
+		}()
+		Setup("bot", "", "xoxb-1")
+
+		parent, span := startSpan(context.Background(), "parent", 0)
+		e := Event{"channel": "C1", "user": "U1"}
+		e.SetContext(parent)
+		PostMessage(e, "test")
+		PostEphemeral(e, "test")
+		updateMessage(parent, "C1", "1.0")
+		span.End()
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 4)
+		for _, s := range spans[:3] {
+			assert.Equal(t, span.SpanContext().SpanID(), s.Parent().SpanID(), s.Name())
+		}
+	})
+
+	testRun(t, "option error test", func(t *testing.T) {
+		AddCommand(&Command{Name: "test", Option: struct {
+			Desc string `choice:"false,true"`
+		}{}, Execute: func(e Event, opt interface{}) {}})
+
+		onMessage(Event{"channel": "C1", "text": "test invalid"})
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "slackbot.parse_option", span.Name())
+		assert.Equal(t, codes.Error, span.Status().Code)
+	})
+
+	testRun(t, "slack api error test", func(t *testing.T) {
+		fake.SetError("chat.postMessage", "channel_not_found")
+		PostMessage(Event{"channel": "C1"}, "test")
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "slack chat.postMessage", span.Name())
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Equal(t, "channel_not_found", span.Status().Description)
+	})
+
+	testRun(t, "schedule test", func(t *testing.T) {
+		s, _ := AddSchedule("@daily", "C1", func(ctx context.Context, e Event) {
+			PostMessage(e, "test")
+		})
+		RunSchedule(s.ID)
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "slackbot.schedule "+s.ID, spans[1].Name())
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	})
+}